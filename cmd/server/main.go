@@ -1,14 +1,19 @@
 package main
 
 import (
+	"context"
 	"log"
 
 	"github.com/gemini-cli/portfolio-chat-ai-go/internal/chat"
+	"github.com/gemini-cli/portfolio-chat-ai-go/internal/usage"
 	"github.com/gemini-cli/portfolio-chat-ai-go/internal/user"
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/apperror"
 	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/bootstrap"
-	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/gemini"
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/jwtkeys"
 	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/middleware"
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/ratelimit"
 	"github.com/labstack/echo/v4"
+	echomiddleware "github.com/labstack/echo/v4/middleware"
 )
 
 // main adalah fungsi utama dan titik masuk dari seluruh aplikasi.
@@ -18,33 +23,65 @@ func main() {
 	// Menjadwalkan penutupan koneksi database saat fungsi main selesai.
 	defer app.Close()
 
-	// 2. Mengambil konfigurasi dan koneksi dari container `app`.
+	// 2. Mengambil konfigurasi dari container `app` dan menyiapkan penyimpanan sesuai STORAGE
+	//    (MongoDB, atau memori untuk development tanpa MongoDB).
 	cfg := app.Env
-	db := app.Mongo.Database(cfg.MongoDbName) // TODO: Nama DB seharusnya dari config.
+	repos, err := newRepositories(context.Background(), app)
+	if err != nil {
+		log.Fatalf("Gagal menyiapkan penyimpanan: %v", err)
+	}
 
 	// 3. Inisialisasi semua lapisan (dependency injection).
-	// Menggunakan implementasi repository MongoDB yang sesungguhnya.
-	userRepo := user.NewMongoUserRepository(db)
-	userUsecase := user.NewUserUsecase(userRepo, cfg.JWTSecret)
-	userHandler := user.NewUserHandler(userUsecase)
+	// Inisialisasi dependensi untuk domain Usage (pemakaian token AI).
+	usageUsecase := usage.NewUsageUsecase(repos.usage, cfg.ModelPrices)
+	usageHandler := usage.NewUsageHandler(usageUsecase)
 
-	// Inisialisasi klien Gemini.
-	geminiClient := gemini.NewClient(cfg.GeminiAPIKey)
+	limiter := ratelimit.NewLimiter(repos.rateLimit)
 
-	// Inisialisasi dependensi untuk domain Chat menggunakan Mongo.
-	chatMongo := chat.NewMongoChatRepository(db)
-	chatUsecase := chat.NewChatUsecase(chatMongo, geminiClient, cfg)
+	// Inisialisasi dependensi untuk domain Chat.
+	chatUsecase := chat.NewChatUsecase(repos.chat, app.LLM, usageUsecase, limiter, cfg)
 	chatHandler := chat.NewChatHandler(chatUsecase)
 
+	// Inisialisasi dependensi untuk domain User.
+	loginPolicy := user.LoginPolicy{
+		MaxAccountFailures: cfg.LoginMaxAccountFailures,
+		MaxIPFailures:      cfg.LoginMaxIPFailures,
+		FailureWindow:      cfg.LoginFailureWindow,
+		LockoutBase:        cfg.LoginLockoutBase,
+		LockoutMax:         cfg.LoginLockoutMax,
+	}
+	tokenPolicy := user.TokenPolicy{
+		AccessTTL:  cfg.JWTAccessTTL,
+		RefreshTTL: cfg.JWTRefreshTTL,
+	}
+	// Koneksi chat pengguna ditutup saat tokennya dicabut.
+	userUsecase := user.NewUserUsecase(repos.users, repos.loginAttempts, repos.tokens, chatUsecase, loginPolicy, tokenPolicy, app.JWTKeys)
+	userHandler := user.NewUserHandler(userUsecase)
+	// Membuat akun admin awal, karena pengguna baru hanya bisa dibuat oleh admin.
+	if cfg.AdminEmail != "" && cfg.AdminPassword != "" {
+		if err := userUsecase.EnsureAdmin(context.Background(), cfg.AdminEmail, cfg.AdminPassword); err != nil {
+			log.Fatalf("Gagal membuat admin awal: %v", err)
+		}
+	}
+
 	// Membuat instance middleware terpusat.
-	middlewares := middleware.NewMiddleware(cfg)
+	middlewares := middleware.NewMiddleware(cfg, app.JWTKeys, repos.tokens)
 
 	// 4. Membuat instance baru dari web server Echo.
+	// Setiap request diberi ID (header X-Request-Id) dan semua error dikirim dalam format JSON yang sama.
 	e := echo.New()
+	e.Use(echomiddleware.RequestID())
+	e.HTTPErrorHandler = apperror.HTTPErrorHandler
+	// IP client (c.RealIP) hanya dibaca dari X-Forwarded-For jika request datang dari proxy di TRUSTED_PROXIES.
+	ipExtractor, err := middleware.NewIPExtractor(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatalf("TRUSTED_PROXIES tidak valid: %v", err)
+	}
+	e.IPExtractor = ipExtractor
 
 	// 5. Mendaftarkan semua rute (endpoints) ke server Echo.
 	router := &Router{}
-	router.SetupRoutes(e, userHandler, chatHandler, middlewares)
+	router.SetupRoutes(e, userHandler, chatHandler, usageHandler, jwtkeys.JWKSHandler(app.JWTKeys), middlewares)
 
 	// 6. Menjalankan server.
 	log.Printf("Server berjalan di port %s", cfg.AppPort)
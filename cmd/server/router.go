@@ -3,6 +3,7 @@ package main
 
 import (
 	"github.com/gemini-cli/portfolio-chat-ai-go/internal/chat"
+	"github.com/gemini-cli/portfolio-chat-ai-go/internal/usage"
 	"github.com/gemini-cli/portfolio-chat-ai-go/internal/user"
 	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/middleware"
 	"github.com/labstack/echo/v4"
@@ -14,19 +15,32 @@ type Router struct{}
 
 // SetupRoutes mendefinisikan dan mengkonfigurasi semua rute (endpoints) aplikasi.
 // Fungsi ini menerima semua handler dan middleware yang dibutuhkan untuk mendaftarkan rute ke instance Echo.
-func (h *Router) SetupRoutes(e *echo.Echo, userHandler *user.UserHandler, chatHandler *chat.ChatHandler, m *middleware.Middleware) {
+func (h *Router) SetupRoutes(e *echo.Echo, userHandler *user.UserHandler, chatHandler *chat.ChatHandler, usageHandler *usage.UsageHandler, jwksHandler echo.HandlerFunc, m *middleware.Middleware) {
+	// Endpoint publik berisi public key JWT, agar layanan lain bisa memverifikasi token.
+	e.GET("/.well-known/jwks.json", jwksHandler)
+
 	// Endpoint publik untuk login, tidak memerlukan autentikasi.
 	e.POST("/v1/login", userHandler.Login)
-
-	// Grup rute yang diproteksi menggunakan Basic Auth.
-	// Hanya request dengan header Basic Auth yang valid yang bisa mengakses rute di dalam grup ini.
-	basicAuthGroup := e.Group("/v1", m.BasicAuth)
-	basicAuthGroup.POST("/users", userHandler.Create) // Endpoint untuk membuat user baru.
+	// Endpoint publik untuk menukar refresh token dengan pasangan token baru.
+	e.POST("/v1/token/refresh", userHandler.Refresh)
 
 	// Grup rute yang diproteksi menggunakan JWT Auth.
 	// Hanya request dengan header `Authorization: Bearer <token>` yang valid yang bisa mengakses rute di grup ini.
 	jwtGroup := e.Group("/v1", m.JWT)
-	jwtGroup.GET("/users/:id", userHandler.GetByID)  // Endpoint untuk mendapatkan data user.
-	jwtGroup.GET("/ws", chatHandler.HandleWebSocket) // Endpoint untuk koneksi WebSocket chat.
+	jwtGroup.GET("/users/:id", userHandler.GetByID)                 // Endpoint untuk mendapatkan data user.
+	jwtGroup.PATCH("/users/:id", userHandler.UpdateProfile)         // Endpoint untuk mengubah profil user.
+	jwtGroup.PUT("/users/:id/password", userHandler.ChangePassword) // Endpoint untuk mengganti password user.
+	jwtGroup.DELETE("/users/:id", userHandler.Delete)               // Endpoint untuk menghapus user (soft delete).
+	jwtGroup.GET("/ws", chatHandler.HandleWebSocket)                // Endpoint untuk koneksi WebSocket chat.
+	jwtGroup.POST("/logout", userHandler.Logout)                    // Endpoint untuk mencabut token yang sedang dipakai.
+
+	// Rute yang juga membutuhkan permission dari role pengguna (admin).
+	jwtGroup.POST("/users", userHandler.Create, m.CreateUser)                                  // Endpoint untuk membuat user baru.
+	jwtGroup.DELETE("/rooms/:roomId/messages/:id", chatHandler.DeleteMessage, m.ModerateRooms) // Moderasi: menghapus pesan di room.
+
+	// Grup rute admin: JWT yang valid dengan role yang memiliki permission terkait.
+	adminGroup := e.Group("/v1/admin", m.JWT)
+	adminGroup.GET("/usage", usageHandler.Report, m.ViewUsage) // Endpoint laporan pemakaian token dan perkiraan biaya.
+	adminGroup.GET("/users", userHandler.List, m.ManageUsers)  // Endpoint daftar user dengan pencarian email dan paginasi.
 
 }
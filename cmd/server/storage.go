@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gemini-cli/portfolio-chat-ai-go/internal/chat"
+	"github.com/gemini-cli/portfolio-chat-ai-go/internal/usage"
+	"github.com/gemini-cli/portfolio-chat-ai-go/internal/user"
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/bootstrap"
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/ratelimit"
+)
+
+// repositories menampung implementasi penyimpanan untuk semua domain, dipilih berdasarkan STORAGE.
+type repositories struct {
+	users         user.UserRepository
+	loginAttempts user.LoginAttemptRepository
+	tokens        user.TokenRepository // Juga dipakai middleware JWT untuk memeriksa token yang dicabut.
+	chat          chat.ChatRepository
+	usage         usage.UsageRepository
+	rateLimit     ratelimit.Store
+}
+
+// newRepositories membuat repository MongoDB (beserta index-nya) untuk STORAGE=mongo, atau repository
+// in-memory untuk STORAGE=memory.
+func newRepositories(ctx context.Context, app *bootstrap.Application) (*repositories, error) {
+	if app.Env.Storage == bootstrap.StorageMemory {
+		return &repositories{
+			users:         user.NewInMemoryUserRepository(),
+			loginAttempts: user.NewInMemoryLoginAttemptRepository(),
+			tokens:        user.NewInMemoryTokenRepository(),
+			chat:          chat.NewInMemoryChatRepository(),
+			usage:         usage.NewInMemoryUsageRepository(),
+			rateLimit:     ratelimit.NewMemoryStore(),
+		}, nil
+	}
+
+	cfg := app.Env
+	db := app.Mongo.Database(cfg.MongoDbName)
+
+	// Pemakaian token AI per model, pengguna dan room.
+	usageRepo := usage.NewMongoUsageRepository(db)
+	if err := usageRepo.EnsureIndexes(ctx); err != nil {
+		return nil, fmt.Errorf("gagal membuat index koleksi usage_counters: %w", err)
+	}
+
+	// Penghitung kuota disimpan di Mongo agar tetap berlaku setelah restart.
+	rateLimitStore := ratelimit.NewMongoStore(db)
+	if err := rateLimitStore.EnsureIndexes(ctx); err != nil {
+		return nil, fmt.Errorf("gagal membuat index koleksi rate_limits: %w", err)
+	}
+
+	chatRepo := chat.NewMongoChatRepository(db)
+	if err := chatRepo.EnsureIndexes(ctx); err != nil {
+		return nil, fmt.Errorf("gagal membuat index koleksi messages: %w", err)
+	}
+
+	userRepo := user.NewMongoUserRepository(db)
+	if err := userRepo.EnsureIndexes(ctx); err != nil {
+		return nil, fmt.Errorf("gagal membuat index koleksi users: %w", err)
+	}
+
+	// Catatan kegagalan login disimpan selama lockout terlama ditambah jendela kegagalan.
+	loginAttemptRepo := user.NewMongoLoginAttemptRepository(db)
+	if err := loginAttemptRepo.EnsureIndexes(ctx, cfg.LoginLockoutMax+cfg.LoginFailureWindow); err != nil {
+		return nil, fmt.Errorf("gagal membuat index koleksi login_attempts: %w", err)
+	}
+
+	// Refresh token dan daftar access token yang dicabut.
+	tokenRepo := user.NewMongoTokenRepository(db)
+	if err := tokenRepo.EnsureIndexes(ctx); err != nil {
+		return nil, fmt.Errorf("gagal membuat index koleksi refresh_tokens: %w", err)
+	}
+
+	return &repositories{
+		users:         userRepo,
+		loginAttempts: loginAttemptRepo,
+		tokens:        tokenRepo,
+		chat:          chatRepo,
+		usage:         usageRepo,
+		rateLimit:     rateLimitStore,
+	}, nil
+}
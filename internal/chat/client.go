@@ -0,0 +1,217 @@
+package chat
+
+import (
+	"errors"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Kebijakan untuk client yang lambat membaca (buffer pesan keluarnya penuh).
+const (
+	SlowConsumerDrop       = "drop"       // Pesan baru dibuang, koneksi tetap dibuka.
+	SlowConsumerDisconnect = "disconnect" // Koneksi ditutup.
+)
+
+// clientOptions menampung pengaturan per koneksi yang diambil dari config.
+type clientOptions struct {
+	bufferSize   int           // Ukuran buffer pesan keluar.
+	policy       string        // SlowConsumerDrop atau SlowConsumerDisconnect.
+	writeWait    time.Duration // Batas waktu untuk satu penulisan.
+	pingInterval time.Duration // Jeda pengiriman ping; 0 untuk menonaktifkan.
+}
+
+// Client merepresentasikan satu koneksi WebSocket yang bergabung ke sebuah room.
+// gorilla/websocket hanya mengizinkan satu penulis pada satu waktu, sehingga semua penulisan
+// ke koneksi dilakukan oleh satu goroutine (writePump) yang membaca dari channel `send`.
+type Client struct {
+	conn   *websocket.Conn
+	roomID string
+	userID string
+	// userName adalah nama tampilan pengguna, dipakai untuk mengisi template persona.
+	userName string
+	version  int // Versi protokol yang dipakai client.
+	opts     clientOptions
+
+	send chan []byte   // Buffer pesan keluar yang sudah di-encode.
+	done chan struct{} // Ditutup saat client dihentikan.
+
+	closeOnce   sync.Once
+	closeCode   int
+	closeReason string
+
+	// Selama client bergabung (joining), event room ditahan di held agar tidak mendahului event `history`.
+	// Dilindungi oleh heldMu.
+	heldMu  sync.Mutex
+	joining bool
+	held    []heldEvent
+}
+
+// heldEvent adalah event room yang ditahan selama client bergabung.
+type heldEvent struct {
+	id      string // ID event; untuk event `message` sama dengan ID pesan.
+	payload []byte
+}
+
+// newClient membuat Client baru dengan pengaturan dari opts.
+func newClient(conn *websocket.Conn, roomID, userID string, version int, opts clientOptions) *Client {
+	if opts.bufferSize <= 0 {
+		opts.bufferSize = 1
+	}
+	return &Client{
+		conn:    conn,
+		roomID:  roomID,
+		userID:  userID,
+		version: version,
+		opts:    opts,
+		send:    make(chan []byte, opts.bufferSize),
+		done:    make(chan struct{}),
+	}
+}
+
+// enqueue memasukkan pesan ke buffer tanpa pernah memblokir pemanggil.
+// Jika buffer penuh, kebijakan slow consumer diterapkan. Mengembalikan false jika pesan tidak terkirim.
+func (c *Client) enqueue(payload []byte) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+
+	select {
+	case c.send <- payload:
+		return true
+	default:
+	}
+	return c.overflow()
+}
+
+// overflow menerapkan kebijakan slow consumer saat buffer client penuh. Selalu mengembalikan false.
+func (c *Client) overflow() bool {
+	if c.opts.policy == SlowConsumerDrop {
+		log.Printf("dropping message for slow client %s in room %s", c.userID, c.roomID)
+		return false
+	}
+	log.Printf("disconnecting slow client %s in room %s", c.userID, c.roomID)
+	c.close(websocket.ClosePolicyViolation, "slow consumer")
+	return false
+}
+
+// startJoin mulai menahan event room sampai finishJoin dipanggil. Dipanggil sebelum client didaftarkan
+// ke room, sehingga tidak ada event yang terlewat selama riwayat diambil.
+func (c *Client) startJoin() {
+	c.heldMu.Lock()
+	c.joining = true
+	c.heldMu.Unlock()
+}
+
+// deliver memasukkan event room ke buffer client, atau menahannya jika client masih bergabung.
+// Event yang ditahan dibatasi sebesar buffer dan kebijakan slow consumer berlaku jika batas terlampaui.
+func (c *Client) deliver(id string, payload []byte) bool {
+	c.heldMu.Lock()
+	defer c.heldMu.Unlock()
+
+	if !c.joining {
+		return c.enqueue(payload)
+	}
+	if len(c.held) >= cap(c.send) {
+		return c.overflow()
+	}
+	c.held = append(c.held, heldEvent{id: id, payload: payload})
+	return true
+}
+
+// finishJoin meneruskan event yang ditahan ke buffer client, kecuali event `message` untuk pesan yang sudah
+// terkirim di riwayat, lalu berhenti menahan event. Urutan event tetap terjaga karena deliver menunggu heldMu.
+func (c *Client) finishJoin(history []*Message) {
+	c.heldMu.Lock()
+	defer c.heldMu.Unlock()
+
+	sent := make(map[string]bool, len(history))
+	for _, msg := range history {
+		sent[msg.ID] = true
+	}
+	for _, ev := range c.held {
+		if !sent[ev.id] {
+			c.enqueue(ev.payload)
+		}
+	}
+	c.held = nil
+	c.joining = false
+}
+
+// close menghentikan client dan meminta writePump mengirim close frame dengan kode dan alasan tertentu.
+// Aman dipanggil berkali-kali dan dari goroutine mana pun; hanya pemanggilan pertama yang berlaku.
+func (c *Client) close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		c.closeReason = reason
+		close(c.done)
+	})
+}
+
+// writePump adalah satu-satunya goroutine yang menulis pesan data ke koneksi WebSocket.
+// Selain meneruskan isi buffer `send`, goroutine ini mengirim ping secara berkala agar koneksi
+// yang mati bisa terdeteksi. Berjalan sampai client ditutup atau penulisan gagal, lalu menutup koneksi.
+func (c *Client) writePump() {
+	defer c.conn.Close()
+
+	var ping <-chan time.Time
+	if c.opts.pingInterval > 0 {
+		ticker := time.NewTicker(c.opts.pingInterval)
+		defer ticker.Stop()
+		ping = ticker.C
+	}
+
+	for {
+		select {
+		case payload := <-c.send:
+			c.conn.SetWriteDeadline(c.writeDeadline())
+			if err := c.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				log.Println("write error:", err)
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-ping:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, c.writeDeadline()); err != nil {
+				log.Println("ping error:", err)
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-c.done:
+			// CloseAbnormalClosure tidak boleh dikirim lewat jaringan; koneksi cukup ditutup.
+			if c.closeCode != websocket.CloseAbnormalClosure {
+				msg := websocket.FormatCloseMessage(c.closeCode, c.closeReason)
+				c.conn.WriteControl(websocket.CloseMessage, msg, c.writeDeadline())
+			}
+			return
+		}
+	}
+}
+
+// writeDeadline mengembalikan batas waktu untuk penulisan berikutnya.
+func (c *Client) writeDeadline() time.Time {
+	if c.opts.writeWait <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(c.opts.writeWait)
+}
+
+// closeForReadError menutup client dengan close code yang sesuai dengan penyebab gagalnya pembacaan.
+func (c *Client) closeForReadError(err error) {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, websocket.ErrReadLimit):
+		c.close(websocket.CloseMessageTooBig, "message too big")
+	case errors.As(err, &netErr) && netErr.Timeout():
+		// Tidak ada pong dalam batas waktu: peer kemungkinan sudah tidak ada.
+		c.close(websocket.CloseGoingAway, "pong timeout")
+	case websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway):
+		c.close(websocket.CloseNormalClosure, "")
+	default:
+		c.close(websocket.CloseAbnormalClosure, "")
+	}
+}
@@ -0,0 +1,50 @@
+package chat
+
+import (
+	"testing"
+)
+
+func TestClientHoldsRoomEventsWhileJoining(t *testing.T) {
+	client := newClient(nil, "room", "user", LatestProtocolVersion, clientOptions{bufferSize: 8, policy: SlowConsumerDrop})
+
+	client.startJoin()
+	client.deliver("m1", []byte("m1"))         // Sudah ada di riwayat.
+	client.deliver("typing", []byte("typing")) // Bukan pesan; tetap diteruskan.
+	client.deliver("m2", []byte("m2"))         // Disimpan setelah riwayat diambil.
+	if len(client.send) != 0 {
+		t.Fatalf("events were sent before the history: %d", len(client.send))
+	}
+
+	client.enqueue([]byte("history"))
+	client.finishJoin([]*Message{{ID: "m0"}, {ID: "m1"}})
+	client.deliver("m3", []byte("m3"))
+
+	want := []string{"history", "typing", "m2", "m3"}
+	if len(client.send) != len(want) {
+		t.Fatalf("buffered %d events, want %d", len(client.send), len(want))
+	}
+	for _, w := range want {
+		if got := string(<-client.send); got != w {
+			t.Errorf("got %q, want %q", got, w)
+		}
+	}
+}
+
+func TestClientHeldEventsFollowSlowConsumerPolicy(t *testing.T) {
+	client := newClient(nil, "room", "user", LatestProtocolVersion, clientOptions{bufferSize: 2, policy: SlowConsumerDisconnect})
+
+	client.startJoin()
+	for _, id := range []string{"a", "b"} {
+		if !client.deliver(id, []byte(id)) {
+			t.Fatalf("deliver(%s) rejected within the buffer size", id)
+		}
+	}
+	if client.deliver("c", []byte("c")) {
+		t.Fatal("deliver accepted more held events than the buffer size")
+	}
+	select {
+	case <-client.done:
+	default:
+		t.Fatal("client was not disconnected")
+	}
+}
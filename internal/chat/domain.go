@@ -5,29 +5,107 @@ import (
 	"context"
 	"time"
 
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/apperror"
 	"github.com/labstack/echo/v4"
 )
 
 // Message adalah struct entitas utama untuk sebuah pesan chat.
+// Tag `bson` digunakan oleh driver MongoDB untuk memetakan struct ke dokumen di koleksi `messages`.
 type Message struct {
-	ID        string    `json:"id"`
-	RoomID    string    `json:"room_id"`
-	UserID    string    `json:"user_id"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"created_at"`
-	status    string    `json:"status"` // "user" atau "system"
+	ID        string      `json:"id" bson:"_id"`
+	RoomID    string      `json:"room_id" bson:"room_id"`
+	UserID    string      `json:"user_id" bson:"user_id"`
+	Content   string      `json:"content" bson:"content"`
+	CreatedAt time.Time   `json:"created_at" bson:"created_at"`
+	Status    string      `json:"status" bson:"status"`                   // "user", "ai" atau "system"
+	Model     string      `json:"model,omitempty" bson:"model,omitempty"` // Model LLM yang menghasilkan pesan AI/sistem.
+	Usage     *TokenUsage `json:"usage,omitempty" bson:"usage,omitempty"` // Pemakaian token untuk pesan AI/sistem.
+}
+
+// TokenUsage adalah jumlah token yang dipakai untuk menghasilkan sebuah pesan AI.
+type TokenUsage struct {
+	PromptTokens    int `json:"prompt_tokens" bson:"prompt_tokens"`
+	CandidateTokens int `json:"candidate_tokens" bson:"candidate_tokens"`
+	TotalTokens     int `json:"total_tokens" bson:"total_tokens"`
+}
+
+// Nilai Status yang digunakan untuk membedakan asal sebuah pesan.
+const (
+	StatusUser   = "user"   // Pesan yang ditulis oleh pengguna.
+	StatusAI     = "ai"     // Balasan yang dihasilkan oleh AI.
+	StatusSystem = "system" // Pesan sistem dari AI, misalnya sambutan saat bergabung ke room.
+)
+
+// AIUserID adalah ID khusus untuk menandakan pesan dari AI.
+const AIUserID = "GEMINI"
+
+// JoinOptions menampung opsi yang dikirim client saat membuka koneksi WebSocket.
+type JoinOptions struct {
+	// HistoryLimit adalah jumlah pesan terakhir yang dikirim saat bergabung.
+	// nil berarti memakai nilai default dari config, 0 berarti tanpa riwayat.
+	HistoryLimit *int
+	// SinceID adalah ID pesan terakhir yang sudah dimiliki client (untuk reconnect).
+	// Jika diisi, hanya pesan setelah ID ini yang dikirim.
+	SinceID string
+	// ProtocolVersion adalah versi protokol yang dipakai client (lihat ProtocolLegacy dan ProtocolV1).
+	ProtocolVersion int
+}
+
+// Batas jumlah pesan yang diambil dalam satu halaman riwayat.
+const (
+	DefaultMessageLimit = 50
+	MaxMessageLimit     = 200
+)
+
+// ErrMessageNotFound dikembalikan jika pesan yang dirujuk (misal: sebagai cursor) tidak ditemukan.
+var ErrMessageNotFound = apperror.NotFound("message_not_found", "pesan tidak ditemukan")
+
+// MessageQuery menentukan halaman riwayat pesan yang diambil dari sebuah room (cursor-based pagination).
+// Cursor bisa berupa ID pesan atau timestamp. Jika cursor `After` diisi, halaman diambil maju dari
+// cursor tersebut (pesan terlama lebih dulu); selain itu diambil mundur dari pesan terbaru.
+// Hasil selalu diurutkan dari yang terlama berdasarkan `created_at`.
+type MessageQuery struct {
+	BeforeID string    // Hanya pesan sebelum pesan dengan ID ini.
+	AfterID  string    // Hanya pesan setelah pesan dengan ID ini.
+	Before   time.Time // Hanya pesan yang dibuat sebelum waktu ini.
+	After    time.Time // Hanya pesan yang dibuat setelah waktu ini.
+	Limit    int       // Jumlah maksimal pesan; 0 berarti DefaultMessageLimit.
+}
+
+// normalizedLimit mengembalikan Limit yang sudah disesuaikan dengan batas default dan maksimal.
+func (q MessageQuery) normalizedLimit() int {
+	switch {
+	case q.Limit <= 0:
+		return DefaultMessageLimit
+	case q.Limit > MaxMessageLimit:
+		return MaxMessageLimit
+	default:
+		return q.Limit
+	}
+}
+
+// forward menandakan apakah halaman diambil maju dari cursor `After`.
+func (q MessageQuery) forward() bool {
+	return q.AfterID != "" || !q.After.IsZero()
 }
 
 // ChatRepository mendefinisikan kontrak untuk lapisan persistensi chat.
 // Dependensi: lapisan Usecase bergantung pada interface ini.
 type ChatRepository interface {
 	CreateMessage(ctx context.Context, msg *Message) error
-	GetMessagesByRoom(ctx context.Context, roomID string) ([]*Message, error)
+	GetMessagesByRoom(ctx context.Context, roomID string, query MessageQuery) ([]*Message, error)
+	// DeleteMessage menghapus pesan dari room, atau mengembalikan ErrMessageNotFound jika tidak ada.
+	DeleteMessage(ctx context.Context, roomID, id string) error
+	// GetLatestMessageByStatus mengembalikan pesan terbaru di room dengan status tertentu,
+	// atau ErrMessageNotFound jika tidak ada.
+	GetLatestMessageByStatus(ctx context.Context, roomID, status string) (*Message, error)
 }
 
 // ChatUsecase mendefinisikan kontrak untuk lapisan logika bisnis chat.
 // Dependensi: lapisan Handler bergantung pada interface ini.
 type ChatUsecase interface {
 	// HandleStream adalah method utama yang menangani seluruh siklus hidup koneksi WebSocket.
-	HandleStream(ctx context.Context, roomID string, c echo.Context) error
+	HandleStream(ctx context.Context, roomID string, opts JoinOptions, c echo.Context) error
+	// DeleteMessage menghapus pesan dari room (moderasi) dan memberi tahu semua client di room tersebut.
+	DeleteMessage(ctx context.Context, roomID, messageID string) error
 }
@@ -2,6 +2,10 @@
 package chat
 
 import (
+	"net/http"
+	"strconv"
+
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/apperror"
 	"github.com/labstack/echo/v4"
 )
 
@@ -16,11 +20,44 @@ func NewChatHandler(chatUsecase ChatUsecase) *ChatHandler {
 	return &ChatHandler{chatUsecase: chatUsecase}
 }
 
-// HandleWebSocket menangani request untuk upgrade ke koneksi WebSocket (GET /v1/ws/:roomID).
+// HandleWebSocket menangani request untuk upgrade ke koneksi WebSocket (GET /v1/ws?roomId=...).
+// Query param opsional: `history` (jumlah pesan riwayat yang dikirim saat bergabung),
+// `since` (ID pesan terakhir yang dimiliki client, untuk reconnect) dan `v` (versi protokol).
 // Tugas utamanya adalah mengekstrak parameter dan meneruskan kontrol ke lapisan use case.
 func (h *ChatHandler) HandleWebSocket(c echo.Context) error {
 	// Mengambil ID room dari parameter URL.
 	roomID := c.QueryParam("roomId")
+
+	// Mengambil opsi riwayat dari query param.
+	opts := JoinOptions{SinceID: c.QueryParam("since")}
+	if raw := c.QueryParam("history"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 0 {
+			return apperror.Validation("invalid_query", "parameter history tidak valid")
+		}
+		opts.HistoryLimit = &limit
+	}
+
+	// Mengambil versi protokol; client lama yang tidak mengirim `v` memakai protokol legacy.
+	if raw := c.QueryParam("v"); raw != "" {
+		version, err := strconv.Atoi(raw)
+		if err != nil || version < ProtocolLegacy || version > LatestProtocolVersion {
+			return apperror.Validation("unsupported_protocol", "versi protokol tidak didukung")
+		}
+		opts.ProtocolVersion = version
+	}
+
 	// Memanggil use case untuk menangani seluruh logika streaming WebSocket.
-	return h.chatUsecase.HandleStream(c.Request().Context(), roomID, c)
+	return h.chatUsecase.HandleStream(c.Request().Context(), roomID, opts, c)
+}
+
+// DeleteMessage menangani request moderasi untuk menghapus pesan (DELETE /v1/rooms/:roomId/messages/:id).
+// Endpoint ini diproteksi oleh JWT Auth dan hanya untuk role yang boleh memoderasi room (admin).
+// Pesan yang tidak ada menghasilkan 404 Not Found (ErrMessageNotFound).
+func (h *ChatHandler) DeleteMessage(c echo.Context) error {
+	err := h.chatUsecase.DeleteMessage(c.Request().Context(), c.Param("roomId"), c.Param("id"))
+	if err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
 }
@@ -0,0 +1,29 @@
+package chat
+
+import (
+	"strings"
+	"time"
+)
+
+// Placeholder yang bisa dipakai di template persona (PROMPT_TEMA).
+const (
+	PlaceholderRoomID   = "{{room_id}}"   // ID room tempat percakapan berlangsung.
+	PlaceholderUserName = "{{user_name}}" // Nama tampilan pengguna yang sedang dibalas.
+	PlaceholderDate     = "{{date}}"      // Tanggal hari ini dengan format YYYY-MM-DD.
+)
+
+// personaDateLayout adalah format tanggal untuk PlaceholderDate.
+const personaDateLayout = "2006-01-02"
+
+// roomAudienceName mengisi PlaceholderUserName untuk pesan yang dibagikan ke seluruh room, seperti sambutan,
+// agar nama pengguna yang kebetulan memicunya tidak ikut tersimpan di riwayat room.
+const roomAudienceName = "semua peserta room"
+
+// renderPersona mengisi placeholder di template persona. Placeholder yang tidak dikenal dibiarkan apa adanya.
+func renderPersona(template, roomID, userName string, now time.Time) string {
+	return strings.NewReplacer(
+		PlaceholderRoomID, roomID,
+		PlaceholderUserName, userName,
+		PlaceholderDate, now.Format(personaDateLayout),
+	).Replace(template)
+}
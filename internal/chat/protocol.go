@@ -0,0 +1,274 @@
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/llm"
+	"github.com/google/uuid"
+)
+
+// Versi protokol WebSocket. Client memilih versi lewat query param `v` saat terhubung,
+// sehingga client lama dan baru bisa berada di room yang sama.
+const (
+	// ProtocolLegacy: frame masuk berupa teks mentah, frame keluar berupa payload JSON
+	// dengan field `type` (tanpa envelope). Dipakai jika client tidak mengirim `v`.
+	ProtocolLegacy = 0
+	// ProtocolV1: semua frame di kedua arah dibungkus dalam Envelope.
+	ProtocolV1 = 1
+
+	// LatestProtocolVersion adalah versi protokol tertinggi yang didukung server.
+	LatestProtocolVersion = ProtocolV1
+)
+
+// Jenis envelope yang dikirim client ke server.
+const (
+	TypeSendMessage = "send_message" // Mengirim pesan chat baru.
+	TypeTyping      = "typing"       // Memberi tahu room bahwa pengguna sedang (berhenti) mengetik.
+)
+
+// Jenis envelope yang dikirim server ke client.
+const (
+	TypeAck            = "ack"             // Konfirmasi bahwa send_message sudah disimpan.
+	TypeError          = "error"           // Frame dari client tidak valid atau gagal diproses.
+	TypeMessage        = "message"         // Pesan chat baru di room.
+	TypeMessageDeleted = "message_deleted" // Pesan dihapus oleh moderator.
+	TypeHistory        = "history"         // Riwayat pesan room, dikirim sekali saat bergabung.
+	TypeWelcome        = "welcome"         // Pesan sambutan AI untuk client yang baru bergabung.
+	TypePresence       = "presence"        // Pengguna bergabung atau meninggalkan room.
+	TypeAIChunk        = "ai_chunk"        // Potongan teks baru dari balasan AI.
+	TypeAIDone         = "ai_done"         // Balasan AI selesai dan sudah disimpan.
+	TypeAIError        = "ai_error"        // Balasan AI gagal dibuat.
+)
+
+// Kode error pada payload envelope `error`.
+const (
+	ErrCodeInvalidFrame       = "invalid_frame"       // Frame bukan JSON envelope yang valid.
+	ErrCodeUnsupportedVersion = "unsupported_version" // Versi protokol di envelope tidak didukung.
+	ErrCodeUnknownType        = "unknown_type"        // Jenis envelope tidak dikenal.
+	ErrCodeInvalidPayload     = "invalid_payload"     // Payload tidak sesuai dengan jenis envelope.
+	ErrCodeInternal           = "internal_error"      // Server gagal memproses frame.
+	ErrCodeRateLimited        = "rate_limited"        // Kuota pengguna atau room terlampaui; lihat retry_after.
+)
+
+// Envelope adalah bentuk setiap frame pada protokol v1, baik masuk maupun keluar.
+type Envelope struct {
+	V           int             `json:"v"`
+	Type        string          `json:"type"`
+	ID          string          `json:"id,omitempty"`            // ID unik frame (atau ID pesan untuk `message`).
+	ClientMsgID string          `json:"client_msg_id,omitempty"` // ID dari client, dikembalikan di `ack` dan `error`.
+	Payload     json.RawMessage `json:"payload,omitempty"`
+}
+
+// --- Payload untuk setiap jenis envelope ---
+
+// SendMessagePayload adalah payload `send_message`.
+type SendMessagePayload struct {
+	Content string `json:"content"`
+}
+
+// TypingPayload adalah payload `typing`. UserID hanya diisi server.
+type TypingPayload struct {
+	UserID   string `json:"user_id,omitempty"`
+	IsTyping bool   `json:"is_typing"`
+}
+
+// AckPayload adalah payload `ack`.
+type AckPayload struct {
+	MessageID string    `json:"message_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ErrorPayload adalah payload `error`.
+type ErrorPayload struct {
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	RetryAfter int    `json:"retry_after,omitempty"` // Detik sebelum client boleh mencoba lagi; hanya untuk rate_limited.
+}
+
+// MessageDeletedPayload adalah payload `message_deleted`.
+type MessageDeletedPayload struct {
+	MessageID string `json:"message_id"`
+}
+
+// HistoryPayload adalah payload `history`.
+type HistoryPayload struct {
+	Messages []*Message `json:"messages"` // Diurutkan dari yang terlama.
+}
+
+// WelcomePayload adalah payload `welcome`.
+type WelcomePayload struct {
+	Message *Message `json:"message"`
+}
+
+// Nilai Status pada PresencePayload.
+const (
+	PresenceOnline  = "online"
+	PresenceOffline = "offline"
+)
+
+// PresencePayload adalah payload `presence`.
+type PresencePayload struct {
+	UserID string `json:"user_id"`
+	Status string `json:"status"` // PresenceOnline atau PresenceOffline.
+}
+
+// AIChunkPayload adalah payload `ai_chunk`. Semua event untuk satu balasan AI membawa
+// MessageID yang sama, sehingga client bisa menyusun potongan teks menjadi satu pesan.
+type AIChunkPayload struct {
+	MessageID string `json:"message_id"`
+	Delta     string `json:"delta"`
+}
+
+// AIDonePayload adalah payload `ai_done`.
+type AIDonePayload struct {
+	MessageID string   `json:"message_id"`
+	Message   *Message `json:"message"`
+}
+
+// Kode error pada payload `ai_error`.
+const (
+	AIErrCodeUnavailable = "ai_unavailable" // Penyedia AI sedang tidak tersedia; client bisa mencoba lagi nanti.
+	AIErrCodeFailed      = "ai_failed"      // Balasan AI gagal dibuat atau disimpan.
+)
+
+// AIErrorPayload adalah payload `ai_error`. MessageID kosong jika kegagalan terjadi pada pesan sambutan.
+type AIErrorPayload struct {
+	MessageID string `json:"message_id"`
+	Code      string `json:"code"`
+	Error     string `json:"error"`
+}
+
+// newAIErrorEvent membuat Event `ai_error` dari error penyedia LLM.
+// Penyedia yang sedang tidak tersedia dibedakan agar pengguna tahu AI akan kembali.
+func newAIErrorEvent(messageID string, err error) Event {
+	if errors.Is(err, llm.ErrUnavailable) {
+		return newEvent(TypeAIError, AIErrorPayload{MessageID: messageID, Code: AIErrCodeUnavailable, Error: "AI sedang tidak tersedia, silakan coba lagi nanti"})
+	}
+	return newEvent(TypeAIError, AIErrorPayload{MessageID: messageID, Code: AIErrCodeFailed, Error: "gagal mendapatkan balasan AI"})
+}
+
+// Event adalah frame keluar sebelum di-encode sesuai versi protokol masing-masing client.
+type Event struct {
+	Type        string
+	ID          string
+	ClientMsgID string
+	Payload     interface{}
+}
+
+// newEvent membuat Event dengan ID unik.
+func newEvent(eventType string, payload interface{}) Event {
+	return Event{Type: eventType, ID: uuid.NewString(), Payload: payload}
+}
+
+// newErrorEvent membuat Event `error` sebagai balasan atas frame dari client.
+func newErrorEvent(clientMsgID, code, message string) Event {
+	ev := newEvent(TypeError, ErrorPayload{Code: code, Message: message})
+	ev.ClientMsgID = clientMsgID
+	return ev
+}
+
+// legacyTypes memetakan jenis event ke nama yang dipakai client lama, jika berbeda.
+var legacyTypes = map[string]string{
+	TypeTyping: "typing_indicator",
+}
+
+// encode mengubah Event menjadi frame JSON sesuai versi protokol client.
+func (ev Event) encode(version int) ([]byte, error) {
+	payload, err := json.Marshal(ev.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if version >= ProtocolV1 {
+		return json.Marshal(Envelope{
+			V:           ProtocolV1,
+			Type:        ev.Type,
+			ID:          ev.ID,
+			ClientMsgID: ev.ClientMsgID,
+			Payload:     payload,
+		})
+	}
+
+	// Client lama menerima pesan chat apa adanya, dan event lain sebagai objek datar dengan field `type`.
+	if ev.Type == TypeMessage {
+		return payload, nil
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil, err
+	}
+	fields["type"] = ev.Type
+	if legacy, ok := legacyTypes[ev.Type]; ok {
+		fields["type"] = legacy
+	}
+	return json.Marshal(fields)
+}
+
+// protocolError adalah kegagalan membaca frame dari client yang perlu dibalas dengan envelope `error`.
+type protocolError struct {
+	clientMsgID string
+	code        string
+	message     string
+}
+
+func (e *protocolError) Error() string {
+	return fmt.Sprintf("%s: %s", e.code, e.message)
+}
+
+// decodeFrame membaca frame dari client dan memvalidasinya sesuai versi protokol client.
+// Frame dari client lama (teks mentah) diperlakukan sebagai `send_message`.
+// Mengembalikan *protocolError jika frame tidak valid.
+func decodeFrame(version int, data []byte) (*Envelope, error) {
+	if version < ProtocolV1 {
+		payload, _ := json.Marshal(SendMessagePayload{Content: string(data)})
+		env := &Envelope{V: ProtocolLegacy, Type: TypeSendMessage, Payload: payload}
+		return env, validateEnvelope(env)
+	}
+
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, &protocolError{code: ErrCodeInvalidFrame, message: "frame harus berupa JSON envelope"}
+	}
+	if env.V < ProtocolV1 || env.V > LatestProtocolVersion {
+		return nil, &protocolError{
+			clientMsgID: env.ClientMsgID,
+			code:        ErrCodeUnsupportedVersion,
+			message:     fmt.Sprintf("versi protokol %d tidak didukung", env.V),
+		}
+	}
+	return &env, validateEnvelope(&env)
+}
+
+// validateEnvelope memeriksa jenis dan payload sebuah envelope dari client.
+func validateEnvelope(env *Envelope) error {
+	invalid := func(message string) error {
+		return &protocolError{clientMsgID: env.ClientMsgID, code: ErrCodeInvalidPayload, message: message}
+	}
+
+	switch env.Type {
+	case TypeSendMessage:
+		var p SendMessagePayload
+		if err := json.Unmarshal(env.Payload, &p); err != nil {
+			return invalid("payload send_message tidak valid")
+		}
+		if strings.TrimSpace(p.Content) == "" {
+			return invalid("content tidak boleh kosong")
+		}
+	case TypeTyping:
+		var p TypingPayload
+		if err := json.Unmarshal(env.Payload, &p); err != nil {
+			return invalid("payload typing tidak valid")
+		}
+	default:
+		return &protocolError{
+			clientMsgID: env.ClientMsgID,
+			code:        ErrCodeUnknownType,
+			message:     fmt.Sprintf("jenis frame %q tidak dikenal", env.Type),
+		}
+	}
+	return nil
+}
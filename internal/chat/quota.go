@@ -0,0 +1,131 @@
+package chat
+
+import (
+	"context"
+	"log"
+	"math"
+	"time"
+
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/ratelimit"
+)
+
+// Window kuota chat. Window harian dimulai tengah malam UTC.
+const (
+	quotaMinute = time.Minute
+	quotaDay    = 24 * time.Hour
+)
+
+// quota adalah satu batas yang diperiksa terhadap ratelimit.Limiter.
+type quota struct {
+	key     string
+	limit   int64
+	window  time.Duration
+	message string // Pesan untuk pengguna jika batas terlampaui.
+}
+
+// quotaError adalah kuota yang terlampaui. Dikirim ke client sebagai envelope `error` dengan kode rate_limited.
+type quotaError struct {
+	message    string
+	retryAfter time.Duration
+}
+
+// checkMessageQuota menghitung satu pesan untuk pengguna dan room, lalu menolaknya jika
+// batas pesan per menit terlampaui.
+func (uc *ChatUsecaseImpl) checkMessageQuota(ctx context.Context, roomID, userID string) *quotaError {
+	return uc.allowQuotas(ctx, 1, []quota{
+		{"chat:messages:user:" + userID, uc.cfg.QuotaUserMessagesPerMinute, quotaMinute, "terlalu banyak pesan, tunggu sebentar sebelum mengirim lagi"},
+		{"chat:messages:room:" + roomID, uc.cfg.QuotaRoomMessagesPerMinute, quotaMinute, "room ini sedang terlalu ramai, tunggu sebentar sebelum mengirim lagi"},
+	})
+}
+
+// checkTypingQuota menghitung satu frame `typing` dari pengguna. Frame yang melampaui batas dibuang
+// tanpa balasan, karena indikator mengetik hanya bersifat sementara.
+func (uc *ChatUsecaseImpl) checkTypingQuota(ctx context.Context, userID string) *quotaError {
+	return uc.allowQuotas(ctx, 1, []quota{
+		{"chat:typing:user:" + userID, uc.cfg.QuotaUserTypingPerMinute, quotaMinute, "terlalu banyak indikator mengetik"},
+	})
+}
+
+// checkAIQuota memeriksa kuota token harian lalu menghitung satu panggilan AI untuk pengguna dan room.
+// Token baru diketahui setelah balasan selesai, sehingga hanya diperiksa apakah kuotanya belum habis.
+func (uc *ChatUsecaseImpl) checkAIQuota(ctx context.Context, roomID, userID string) *quotaError {
+	if uc.limiter == nil {
+		return nil
+	}
+	tokenQuotas := []quota{
+		{"chat:tokens:user:" + userID, uc.cfg.QuotaUserTokensPerDay, quotaDay, "kuota token AI harianmu sudah habis"},
+		{"chat:tokens:room:" + roomID, uc.cfg.QuotaRoomTokensPerDay, quotaDay, "kuota token AI harian room ini sudah habis"},
+	}
+	for _, q := range tokenQuotas {
+		res, err := uc.limiter.Peek(ctx, q.key, q.limit, q.window)
+		if err != nil {
+			// Penyimpanan kuota bermasalah: lebih baik chat tetap berjalan.
+			log.Printf("failed to check quota %s: %v", q.key, err)
+			continue
+		}
+		if !res.Allowed {
+			return &quotaError{message: q.message, retryAfter: res.RetryAfter}
+		}
+	}
+
+	return uc.allowQuotas(ctx, 1, []quota{
+		{"chat:ai_calls:user:" + userID, uc.cfg.QuotaUserAICallsPerDay, quotaDay, "batas harian balasan AI untukmu sudah tercapai"},
+		{"chat:ai_calls:room:" + roomID, uc.cfg.QuotaRoomAICallsPerDay, quotaDay, "batas harian balasan AI untuk room ini sudah tercapai"},
+	})
+}
+
+// consumeTokenQuota menambahkan token yang dipakai sebuah balasan AI ke kuota harian pengguna dan room.
+func (uc *ChatUsecaseImpl) consumeTokenQuota(ctx context.Context, roomID, userID string, tokens int) {
+	if tokens <= 0 || uc.limiter == nil {
+		return
+	}
+	// Token sudah terpakai, sehingga selalu dihitung ke kedua kuota walaupun salah satunya terlampaui.
+	for _, q := range []quota{
+		{key: "chat:tokens:user:" + userID, limit: uc.cfg.QuotaUserTokensPerDay, window: quotaDay},
+		{key: "chat:tokens:room:" + roomID, limit: uc.cfg.QuotaRoomTokensPerDay, window: quotaDay},
+	} {
+		if _, err := uc.limiter.Allow(ctx, q.key, q.limit, q.window, int64(tokens)); err != nil {
+			log.Printf("failed to consume quota %s: %v", q.key, err)
+		}
+	}
+}
+
+// allowQuotas menambahkan cost ke setiap kuota dan mengembalikan kuota pertama yang terlampaui.
+// Jika ada yang terlampaui, cost dikembalikan ke kuota yang sudah menerimanya, sehingga pengguna tidak
+// kehilangan kuota pribadinya karena room yang penuh (dan sebaliknya).
+// Kegagalan penyimpanan kuota hanya dicatat di log (fail open).
+func (uc *ChatUsecaseImpl) allowQuotas(ctx context.Context, cost int64, quotas []quota) *quotaError {
+	if uc.limiter == nil {
+		return nil
+	}
+	charged := make([]ratelimit.Result, 0, len(quotas))
+	for i, q := range quotas {
+		res, err := uc.limiter.Allow(ctx, q.key, q.limit, q.window, cost)
+		if err != nil {
+			log.Printf("failed to check quota %s: %v", q.key, err)
+			charged = append(charged, ratelimit.Result{}) // Tidak ada yang perlu dikembalikan.
+			continue
+		}
+		if !res.Allowed {
+			for j, prev := range charged {
+				if err := uc.limiter.Refund(ctx, quotas[j].key, quotas[j].window, prev, cost); err != nil {
+					log.Printf("failed to refund quota %s: %v", quotas[j].key, err)
+				}
+			}
+			return &quotaError{message: quotas[i].message, retryAfter: res.RetryAfter}
+		}
+		charged = append(charged, res)
+	}
+	return nil
+}
+
+// newRateLimitedEvent membuat Event `error` dengan kode rate_limited dan waktu tunggu dalam detik (dibulatkan ke atas).
+func newRateLimitedEvent(clientMsgID string, qerr *quotaError) Event {
+	ev := newEvent(TypeError, ErrorPayload{
+		Code:       ErrCodeRateLimited,
+		Message:    qerr.message,
+		RetryAfter: int(math.Ceil(qerr.retryAfter.Seconds())),
+	})
+	ev.ClientMsgID = clientMsgID
+	return ev
+}
@@ -0,0 +1,132 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/config"
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/llm"
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/ratelimit"
+)
+
+// newQuotaUsecase membuat ChatUsecaseImpl dengan kuota dari cfg yang disimpan di memori.
+func newQuotaUsecase(cfg *config.Config) (*ChatUsecaseImpl, *ratelimit.Limiter) {
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore())
+	return NewChatUsecase(NewInMemoryChatRepository(), llm.NewFakeProvider(nil, 0, 0), nil, limiter, cfg), limiter
+}
+
+// rateLimitedPayload mengembalikan payload dari envelope `error` rate_limited, atau menggagalkan test.
+func rateLimitedPayload(t *testing.T, env Envelope, clientMsgID string) ErrorPayload {
+	t.Helper()
+	var payload ErrorPayload
+	json.Unmarshal(env.Payload, &payload)
+	if env.Type != TypeError || payload.Code != ErrCodeRateLimited || env.ClientMsgID != clientMsgID {
+		t.Fatalf("event = %s %+v (client_msg_id %q), want rate_limited for %s", env.Type, payload, env.ClientMsgID, clientMsgID)
+	}
+	return payload
+}
+
+// repliesTo mengembalikan envelope yang menjawab frame dengan clientMsgID. Event lain, misal balasan AI
+// yang di-stream di goroutine terpisah, diabaikan.
+func repliesTo(events []Envelope, clientMsgID string) []Envelope {
+	var replies []Envelope
+	for _, env := range events {
+		if env.ClientMsgID == clientMsgID {
+			replies = append(replies, env)
+		}
+	}
+	return replies
+}
+
+// assertRetryAfter memastikan client diminta menunggu sampai window berikutnya, paling lama satu window.
+func assertRetryAfter(t *testing.T, payload ErrorPayload, windowSeconds int) {
+	t.Helper()
+	if payload.RetryAfter < 1 || payload.RetryAfter > windowSeconds {
+		t.Errorf("retry_after = %d, want between 1 and %d seconds", payload.RetryAfter, windowSeconds)
+	}
+}
+
+func TestMessageQuotaRejectsWithRetryAfter(t *testing.T) {
+	ctx := context.Background()
+	uc, _ := newQuotaUsecase(&config.Config{QuotaUserMessagesPerMinute: 1})
+	client := joinTestClient(uc, "room", "user-1")
+
+	uc.handleSendMessage(ctx, client, "c1", "pertama")
+	if got := eventTypes(repliesTo(drainEvents(client), "c1")); !reflect.DeepEqual(got, []string{TypeAck}) {
+		t.Fatalf("first message replies = %v, want ack", got)
+	}
+
+	uc.handleSendMessage(ctx, client, "c2", "kedua")
+	events := repliesTo(drainEvents(client), "c2")
+	if len(events) != 1 {
+		t.Fatalf("second message replies = %v, want only rate_limited", eventTypes(events))
+	}
+	assertRetryAfter(t, rateLimitedPayload(t, events[0], "c2"), 60)
+
+	messages, err := uc.chatRepo.GetMessagesByRoom(ctx, "room", MessageQuery{})
+	if err != nil || len(messages) != 1 {
+		t.Errorf("stored %d messages (err %v), want only the first", len(messages), err)
+	}
+}
+
+func TestMessageQuotaRejectionDoesNotSpendOtherQuotas(t *testing.T) {
+	ctx := context.Background()
+	uc, limiter := newQuotaUsecase(&config.Config{QuotaUserMessagesPerMinute: 5, QuotaRoomMessagesPerMinute: 1})
+	first := joinTestClient(uc, "room", "user-1")
+	second := joinTestClient(uc, "room", "user-2")
+
+	uc.handleSendMessage(ctx, first, "c1", "halo")
+	// Room sudah penuh: pesan user-2 ditolak oleh kuota room.
+	uc.handleSendMessage(ctx, second, "c2", "halo juga")
+	rejected := repliesTo(drainEvents(second), "c2")
+	if len(rejected) != 1 {
+		t.Fatalf("user-2 replies = %v, want only rate_limited", eventTypes(rejected))
+	}
+	assertRetryAfter(t, rateLimitedPayload(t, rejected[0], "c2"), 60)
+
+	// Kuota pribadi user-2 dikembalikan karena pesannya tidak terkirim.
+	res, err := limiter.Peek(ctx, "chat:messages:user:user-2", 5, quotaMinute)
+	if err != nil || res.Count != 0 {
+		t.Errorf("user-2 message quota = %d (err %v), want 0 after the rejection", res.Count, err)
+	}
+}
+
+func TestAIQuotaDeliversMessageWithoutReply(t *testing.T) {
+	ctx := context.Background()
+	uc, _ := newQuotaUsecase(&config.Config{QuotaUserTokensPerDay: 10})
+	client := joinTestClient(uc, "room", "user-1")
+	// Balasan sebelumnya sudah memakai seluruh kuota token harian.
+	uc.consumeTokenQuota(ctx, "room", "user-1", 10)
+
+	uc.handleSendMessage(ctx, client, "c1", "halo")
+	events := drainEvents(client)
+	if got, want := eventTypes(events), []string{TypeAck, TypeMessage, TypeError}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+	assertRetryAfter(t, rateLimitedPayload(t, events[2], "c1"), 24*60*60)
+}
+
+func TestTypingQuotaDropsFrames(t *testing.T) {
+	ctx := context.Background()
+	uc, _ := newQuotaUsecase(&config.Config{QuotaUserTypingPerMinute: 2})
+	typist := joinTestClient(uc, "room", "user-1")
+	watcher := joinTestClient(uc, "room", "user-2")
+	drainEvents(typist)
+
+	frame := &Envelope{Type: TypeTyping, Payload: json.RawMessage(`{"is_typing":true}`)}
+	for i := 0; i < 5; i++ {
+		uc.handleFrame(ctx, typist, frame)
+	}
+
+	if got, want := eventTypes(drainEvents(watcher)), []string{TypeTyping, TypeTyping}; !reflect.DeepEqual(got, want) {
+		t.Errorf("watcher events = %v, want %v", got, want)
+	}
+	// Frame yang dibuang tidak dibalas dengan error.
+	for _, env := range drainEvents(typist) {
+		if env.Type == TypeError {
+			t.Errorf("typist got an error event for a dropped typing frame")
+		}
+	}
+}
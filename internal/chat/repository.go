@@ -0,0 +1,163 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// InMemoryChatRepository adalah implementasi ChatRepository yang menyimpan pesan di memori.
+// Berguna untuk development dan pengujian tanpa MongoDB. Perilakunya mengikuti MongoChatRepository;
+// keduanya dijalankan dengan suite yang sama di repotest.RunChatRepository.
+type InMemoryChatRepository struct {
+	mu       sync.RWMutex
+	messages map[string][]*Message // Pesan per roomID, selalu terurut berdasarkan (created_at, id).
+}
+
+// NewInMemoryChatRepository membuat instance baru dari InMemoryChatRepository.
+func NewInMemoryChatRepository() *InMemoryChatRepository {
+	return &InMemoryChatRepository{
+		messages: make(map[string][]*Message),
+	}
+}
+
+// CreateMessage menyimpan pesan baru. ID pesan harus unik di dalam room.
+func (r *InMemoryChatRepository) CreateMessage(ctx context.Context, msg *Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	room := r.messages[msg.RoomID]
+	for _, existing := range room {
+		if existing.ID == msg.ID {
+			return fmt.Errorf("message with id %s already exists", msg.ID)
+		}
+	}
+
+	stored := *msg
+	room = append(room, &stored)
+	sort.SliceStable(room, func(i, j int) bool {
+		return messageLess(room[i], room[j])
+	})
+	r.messages[msg.RoomID] = room
+	return nil
+}
+
+// DeleteMessage menghapus satu pesan dari sebuah room, atau mengembalikan ErrMessageNotFound.
+func (r *InMemoryChatRepository) DeleteMessage(ctx context.Context, roomID, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	room := r.messages[roomID]
+	for i, msg := range room {
+		if msg.ID == id {
+			r.messages[roomID] = append(room[:i:i], room[i+1:]...)
+			return nil
+		}
+	}
+	return ErrMessageNotFound
+}
+
+// GetMessagesByRoom mengambil satu halaman pesan dari sebuah room sesuai cursor di MessageQuery.
+func (r *InMemoryChatRepository) GetMessagesByRoom(ctx context.Context, roomID string, query MessageQuery) ([]*Message, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	room := r.messages[roomID]
+
+	before, err := findCursor(room, query.BeforeID, query.Before)
+	if err != nil {
+		return nil, err
+	}
+	after, err := findCursor(room, query.AfterID, query.After)
+	if err != nil {
+		return nil, err
+	}
+
+	// Kumpulkan pesan yang berada di antara kedua cursor.
+	var matched []*Message
+	for _, msg := range room {
+		if before != nil && before.compare(msg) <= 0 {
+			continue
+		}
+		if after != nil && after.compare(msg) >= 0 {
+			continue
+		}
+		matched = append(matched, msg)
+	}
+
+	// Ambil dari awal untuk halaman maju, dari akhir untuk halaman mundur.
+	limit := query.normalizedLimit()
+	if len(matched) > limit {
+		if query.forward() {
+			matched = matched[:limit]
+		} else {
+			matched = matched[len(matched)-limit:]
+		}
+	}
+
+	messages := make([]*Message, 0, len(matched))
+	for _, msg := range matched {
+		copied := *msg
+		messages = append(messages, &copied)
+	}
+	return messages, nil
+}
+
+// GetLatestMessageByStatus mengembalikan pesan terbaru di room dengan status tertentu.
+func (r *InMemoryChatRepository) GetLatestMessageByStatus(ctx context.Context, roomID, status string) (*Message, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	room := r.messages[roomID]
+	for i := len(room) - 1; i >= 0; i-- {
+		if room[i].Status == status {
+			copied := *room[i]
+			return &copied, nil
+		}
+	}
+	return nil, ErrMessageNotFound
+}
+
+// findCursor mencari posisi cursor berdasarkan ID pesan atau timestamp.
+func findCursor(room []*Message, id string, at time.Time) (*messageCursor, error) {
+	if id == "" {
+		if at.IsZero() {
+			return nil, nil
+		}
+		return &messageCursor{createdAt: at}, nil
+	}
+	for _, msg := range room {
+		if msg.ID == id {
+			return &messageCursor{createdAt: msg.CreatedAt, id: msg.ID}, nil
+		}
+	}
+	return nil, ErrMessageNotFound
+}
+
+// compare membandingkan posisi cursor dengan sebuah pesan: negatif jika pesan berada sesudah cursor,
+// positif jika sebelum, dan 0 jika sama. Cursor tanpa ID hanya membandingkan timestamp,
+// sama seperti filter yang dibangun oleh messageCursor.filter.
+func (c *messageCursor) compare(msg *Message) int {
+	switch {
+	case c.createdAt.Before(msg.CreatedAt):
+		return -1
+	case c.createdAt.After(msg.CreatedAt):
+		return 1
+	case c.id == "" || c.id == msg.ID:
+		return 0
+	case c.id < msg.ID:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// messageLess mengurutkan pesan berdasarkan (created_at, id), sama seperti index di MongoDB.
+func messageLess(a, b *Message) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID < b.ID
+}
@@ -0,0 +1,28 @@
+package chat_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gemini-cli/portfolio-chat-ai-go/internal/chat"
+	"github.com/gemini-cli/portfolio-chat-ai-go/internal/repotest"
+)
+
+// TestChatRepositoryContract menjalankan suite kontrak terhadap InMemoryChatRepository dan,
+// jika MONGO_URI diisi, terhadap MongoChatRepository.
+func TestChatRepositoryContract(t *testing.T) {
+	t.Run("memory", func(t *testing.T) {
+		repotest.RunChatRepository(t, func(t *testing.T) chat.ChatRepository {
+			return chat.NewInMemoryChatRepository()
+		})
+	})
+	t.Run("mongo", func(t *testing.T) {
+		repotest.RunChatRepository(t, func(t *testing.T) chat.ChatRepository {
+			repo := chat.NewMongoChatRepository(repotest.MongoDatabase(t))
+			if err := repo.EnsureIndexes(context.Background()); err != nil {
+				t.Fatalf("EnsureIndexes: %v", err)
+			}
+			return repo
+		})
+	})
+}
@@ -2,15 +2,19 @@ package chat
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// MongoChatRepository adalah implementasi dari ChatRepository yang menggunakan MongoDB sebagai penyimpanannya.
+// Dependensi: bergantung pada koneksi database MongoDB (*mongo.Database).
 type MongoChatRepository struct {
-	// Tambahkan field yang diperlukan untuk koneksi MongoDB, misalnya:
-	db         *mongo.Database
-	collection string // Nama koleksi untuk menyimpan pesan, misalnya "messages"
+	db         *mongo.Database // Koneksi ke database spesifik di MongoDB.
+	collection string          // Nama koleksi untuk menyimpan pesan, yaitu "messages".
 }
 
 // NewMongoChatRepository membuat instance baru dari MongoChatRepository.
@@ -21,31 +25,143 @@ func NewMongoChatRepository(db *mongo.Database) *MongoChatRepository {
 	}
 }
 
+// EnsureIndexes membuat index yang dibutuhkan oleh koleksi `messages`.
+// Index gabungan `room_id, created_at, _id` dipakai oleh GetMessagesByRoom untuk filter dan pengurutan,
+// sehingga pengurutan (created_at, _id) tidak perlu dilakukan di memori. Aman dipanggil berulang kali saat startup.
+func (r *MongoChatRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.db.Collection(r.collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "room_id", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}},
+		Options: options.Index().SetName("room_id_created_at_id"),
+	})
+	return err
+}
+
 // CreateMessage menyimpan pesan baru ke dalam koleksi `messages` di MongoDB.
 func (r *MongoChatRepository) CreateMessage(ctx context.Context, msg *Message) error {
-	// Implementasi penyimpanan pesan ke MongoDB.
-	// Contoh (pseudo-code):
-	fmt.Printf("Simulasi menyimpan pesan ke MongoDB: %+v\n", msg)
-	collection := r.db.Collection(r.collection)
-	_, err := collection.InsertOne(ctx, msg)
+	_, err := r.db.Collection(r.collection).InsertOne(ctx, msg)
 	return err
 }
 
-func (r *MongoChatRepository) GetMessagesByRoom(ctx context.Context, roomID string) ([]*Message, error) {
-	// Implementasi pengambilan pesan berdasarkan roomID dari MongoDB.
-	// Contoh (pseudo-code):
-	/*
-		collection := r.db.Collection("messages")
-		cursor, err := collection.Find(ctx, bson.M{"room_id": roomID})
-		if err != nil {
-			return nil, err
-		}
-		var messages []*Message
-		if err := cursor.All(ctx, &messages); err != nil {
-			return nil, err
+// DeleteMessage menghapus satu pesan dari sebuah room, atau mengembalikan ErrMessageNotFound.
+func (r *MongoChatRepository) DeleteMessage(ctx context.Context, roomID, id string) error {
+	result, err := r.db.Collection(r.collection).DeleteOne(ctx, bson.M{"_id": id, "room_id": roomID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrMessageNotFound
+	}
+	return nil
+}
+
+// GetMessagesByRoom mengambil satu halaman pesan dari sebuah room sesuai cursor di MessageQuery.
+// Hasil selalu diurutkan dari yang terlama berdasarkan `created_at`.
+func (r *MongoChatRepository) GetMessagesByRoom(ctx context.Context, roomID string, query MessageQuery) ([]*Message, error) {
+	collection := r.db.Collection(r.collection)
+
+	// 1. Bangun filter dasar berdasarkan room, lalu tambahkan batas dari cursor `before` dan `after`.
+	conditions := bson.A{bson.M{"room_id": roomID}}
+
+	before, err := r.resolveCursor(ctx, roomID, query.BeforeID, query.Before)
+	if err != nil {
+		return nil, err
+	}
+	if before != nil {
+		conditions = append(conditions, before.filter("$lt"))
+	}
+
+	after, err := r.resolveCursor(ctx, roomID, query.AfterID, query.After)
+	if err != nil {
+		return nil, err
+	}
+	if after != nil {
+		conditions = append(conditions, after.filter("$gt"))
+	}
+
+	// 2. Halaman maju diambil dari yang terlama, halaman mundur dari yang terbaru.
+	//    `_id` dipakai sebagai pengurut kedua agar urutan stabil untuk timestamp yang sama.
+	direction := -1
+	if query.forward() {
+		direction = 1
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: direction}, {Key: "_id", Value: direction}}).
+		SetLimit(int64(query.normalizedLimit()))
+
+	cursor, err := collection.Find(ctx, bson.M{"$and": conditions}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []*Message{}
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, err
+	}
+
+	// 3. Kembalikan hasil halaman mundur ke urutan kronologis.
+	if direction < 0 {
+		reverseMessages(messages)
+	}
+
+	return messages, nil
+}
+
+// GetLatestMessageByStatus mengembalikan pesan terbaru di room dengan status tertentu.
+func (r *MongoChatRepository) GetLatestMessageByStatus(ctx context.Context, roomID, status string) (*Message, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+
+	var msg Message
+	err := r.db.Collection(r.collection).FindOne(ctx, bson.M{"room_id": roomID, "status": status}, opts).Decode(&msg)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// messageCursor adalah posisi sebuah pesan di dalam urutan (created_at, _id).
+type messageCursor struct {
+	createdAt time.Time
+	id        string
+}
+
+// filter mengembalikan filter MongoDB untuk pesan yang berada sebelum (`$lt`) atau sesudah (`$gt`) cursor.
+func (c *messageCursor) filter(op string) bson.M {
+	if c.id == "" {
+		return bson.M{"created_at": bson.M{op: c.createdAt}}
+	}
+	return bson.M{"$or": bson.A{
+		bson.M{"created_at": bson.M{op: c.createdAt}},
+		bson.M{"created_at": c.createdAt, "_id": bson.M{op: c.id}},
+	}}
+}
+
+// resolveCursor mengubah cursor berupa ID pesan atau timestamp menjadi messageCursor.
+// ID pesan diutamakan jika keduanya diisi. Mengembalikan nil jika cursor tidak diisi.
+func (r *MongoChatRepository) resolveCursor(ctx context.Context, roomID, id string, at time.Time) (*messageCursor, error) {
+	if id == "" {
+		if at.IsZero() {
+			return nil, nil
 		}
-		return messages, nil
-	*/
-	fmt.Println("Simulasi mengambil pesan dari MongoDB untuk roomID:", roomID)
-	return []*Message{}, nil
+		return &messageCursor{createdAt: at}, nil
+	}
+
+	var msg Message
+	err := r.db.Collection(r.collection).FindOne(ctx, bson.M{"_id": id, "room_id": roomID}).Decode(&msg)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &messageCursor{createdAt: msg.CreatedAt, id: msg.ID}, nil
+}
+
+// reverseMessages membalik urutan slice pesan secara in-place.
+func reverseMessages(messages []*Message) {
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
 }
@@ -2,18 +2,23 @@ package chat
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"sync"
 	"time"
 
+	"github.com/gemini-cli/portfolio-chat-ai-go/internal/usage"
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/auth"
 	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/config"
-	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/gemini"
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/llm"
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/ratelimit"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/labstack/echo/v4"
+	"golang.org/x/sync/singleflight"
 )
 
 // upgrader adalah instance dari gorilla/websocket yang menangani proses upgrade koneksi HTTP ke WebSocket.
@@ -28,207 +33,575 @@ var (
 )
 
 // ChatUsecaseImpl adalah implementasi dari ChatUsecase yang menangani logika real-time chat.
-// Dependensi: bergantung pada ChatRepository untuk menyimpan pesan.
+// Dependensi: bergantung pada ChatRepository untuk menyimpan pesan, llm.Provider untuk balasan AI
+// usage.UsageUsecase untuk mencatat pemakaian token, dan ratelimit.Limiter untuk kuota pengguna dan room.
 type ChatUsecaseImpl struct {
-	chatMongo    *MongoChatRepository
-	geminiClient *gemini.Client
-	cfg          *config.Config
-	mu           sync.RWMutex
-	// rooms adalah map untuk menampung koneksi WebSocket yang aktif untuk setiap room.
-	// Kunci luar adalah roomID, kunci dalam adalah pointer ke koneksi WebSocket.
-	rooms map[string]map[*websocket.Conn]bool
+	chatRepo ChatRepository
+	llm      llm.Provider
+	usage    usage.UsageUsecase
+	limiter  *ratelimit.Limiter // Boleh nil untuk menonaktifkan kuota.
+	cfg      *config.Config
+	mu       sync.RWMutex
+	// rooms adalah map untuk menampung client WebSocket yang aktif untuk setiap room.
+	// Kunci luar adalah roomID, kunci dalam adalah pointer ke Client.
+	rooms map[string]map[*Client]bool
+
+	// welcomes adalah cache pesan sambutan per roomID, dilindungi oleh welcomeMu.
+	welcomeMu sync.RWMutex
+	welcomes  map[string]*Message
+	// welcomeGroup memastikan hanya ada satu pembuatan sambutan per room pada satu waktu.
+	welcomeGroup singleflight.Group
 }
 
 // NewChatUsecase membuat instance baru dari ChatUsecaseImpl.
-func NewChatUsecase(chatMongo *MongoChatRepository, geminiClient *gemini.Client, cfg *config.Config) *ChatUsecaseImpl {
+func NewChatUsecase(chatRepo ChatRepository, llmProvider llm.Provider, usageUsecase usage.UsageUsecase, limiter *ratelimit.Limiter, cfg *config.Config) *ChatUsecaseImpl {
 	return &ChatUsecaseImpl{
-		chatMongo:    chatMongo,
-		geminiClient: geminiClient,
-		cfg:          cfg,
-		rooms:        make(map[string]map[*websocket.Conn]bool),
+		chatRepo: chatRepo,
+		llm:      llmProvider,
+		usage:    usageUsecase,
+		limiter:  limiter,
+		cfg:      cfg,
+		rooms:    make(map[string]map[*Client]bool),
+		welcomes: make(map[string]*Message),
 	}
 }
 
 // HandleStream adalah method utama yang menangani siklus hidup koneksi WebSocket.
-func (uc *ChatUsecaseImpl) HandleStream(ctx context.Context, roomID string, c echo.Context) error {
+func (uc *ChatUsecaseImpl) HandleStream(ctx context.Context, roomID string, opts JoinOptions, c echo.Context) error {
 
 	// 1. Upgrade koneksi HTTP ke koneksi WebSocket.
 	ws, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
 	if err != nil {
 		return err
 	}
-	defer ws.Close()
 
-	// 2. Tambahkan koneksi baru ini ke dalam daftar koneksi aktif untuk room ini.
-	uc.addConnection(roomID, ws)
+	// Mengambil pengguna dari token JWT yang sudah divalidasi oleh middleware.
+	principal, err := auth.PrincipalFromContext(c)
+	userID, userName := "", ""
+	if err == nil {
+		userID, userName = principal.UserID, principal.DisplayName()
+	}
+
+	// 2. Buat client dengan goroutine penulis sendiri. Sejak titik ini, semua penulisan ke koneksi
+	//    harus melalui client agar tidak ada dua goroutine yang menulis bersamaan.
+	client := newClient(ws, roomID, userID, opts.ProtocolVersion, clientOptions{
+		bufferSize:   uc.cfg.WSSendBufferSize,
+		policy:       uc.cfg.WSSlowConsumerPolicy,
+		writeWait:    uc.cfg.WSWriteWait,
+		pingInterval: uc.cfg.WSPingInterval,
+	})
+	client.userName = userName
+	go client.writePump()
+	// Pastikan client dihentikan saat fungsi ini berakhir (koneksi terputus).
+	defer client.close(websocket.CloseNormalClosure, "")
+
+	if err != nil {
+		// Jika token tidak ditemukan atau tipe-nya salah, ini adalah error internal.
+		log.Println(err)
+		client.close(websocket.CloseInternalServerErr, "internal server error")
+		return nil
+	}
 
-	// Pastikan koneksi dihapus saat fungsi ini berakhir (koneksi terputus).
-	defer uc.removeConnection(roomID, ws)
+	// 3. Tambahkan client ini ke dalam daftar koneksi aktif untuk room ini. Client didaftarkan sebelum
+	//    riwayat diambil agar pesan yang disiarkan di antaranya tidak terlewat; event room ditahan
+	//    sampai riwayat terkirim, dan pesan yang sudah ada di riwayat tidak dikirim dua kali.
+	client.startJoin()
+	uc.addConnection(client)
 
-	// 3. Masuk ke loop tak terbatas untuk membaca pesan dari client.
+	// Pastikan koneksi dihapus saat fungsi ini berakhir.
+	defer uc.removeConnection(client)
+
+	history, err := uc.sendHistory(ctx, client, opts)
+	if err != nil {
+		log.Println("failed to send room history:", err)
+	}
+	client.finishJoin(history)
+
+	// Pesan sambutan dibuat di background agar client langsung bisa mengirim dan menerima pesan.
+	go func() {
+		if err := uc.sendWelcome(context.Background(), client); err != nil {
+			log.Println("failed to send welcome message:", err)
+			// Beri tahu pengguna bahwa AI tidak bisa menyapa, alih-alih diam saja.
+			uc.sendEvent(client, newAIErrorEvent("", err))
+		}
+	}()
+
+	// Batasi ukuran pesan masuk dan anggap koneksi mati jika tidak ada pong dalam WSPongWait.
+	ws.SetReadLimit(uc.cfg.WSMaxMessageSize)
+	if uc.cfg.WSPongWait > 0 {
+		ws.SetReadDeadline(time.Now().Add(uc.cfg.WSPongWait))
+		ws.SetPongHandler(func(string) error {
+			return ws.SetReadDeadline(time.Now().Add(uc.cfg.WSPongWait))
+		})
+	}
+
+	// Tutup koneksi jika client tidak mengirim pesan apa pun selama WSIdleTimeout.
+	idle := func() {}
+	if uc.cfg.WSIdleTimeout > 0 {
+		idleTimer := time.AfterFunc(uc.cfg.WSIdleTimeout, func() {
+			client.close(websocket.CloseGoingAway, "idle timeout")
+		})
+		defer idleTimer.Stop()
+		idle = func() { idleTimer.Reset(uc.cfg.WSIdleTimeout) }
+	}
+
+	// 4. Masuk ke loop tak terbatas untuk membaca pesan dari client.
 	for {
 		_, msgBytes, err := ws.ReadMessage()
 		if err != nil {
-			// Jika ada error saat membaca (misal: client disconnect), hentikan loop.
+			// Jika ada error saat membaca (misal: client disconnect, pesan terlalu besar atau
+			// pong tidak diterima), tutup dengan close code yang sesuai lalu hentikan loop.
 			log.Println("read error:", err)
+			client.closeForReadError(err)
 			break
 		}
+		idle()
 
-		// Mengambil token JWT dari context, yang seharusnya sudah divalidasi oleh middleware.
-		userToken, ok := c.Get("user").(*jwt.Token)
-		if !ok {
-			// Jika token tidak ditemukan atau tipe-nya salah, ini adalah error internal.
-			log.Println("failed to get user token from context")
-			// Sebaiknya kirim pesan error ke client dan tutup koneksi.
-			ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "internal server error"))
-			break
-		}
-		// Mengekstrak claims dari token.
-		claims, ok := userToken.Claims.(jwt.MapClaims)
-		if !ok {
-			log.Println("failed to cast claims from token")
-			ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "internal server error"))
-			break
+		// 5. Validasi frame sesuai versi protokol client, lalu proses sesuai jenisnya.
+		env, err := decodeFrame(client.version, msgBytes)
+		if err != nil {
+			uc.replyError(client, err)
+			continue
 		}
+		uc.handleFrame(ctx, client, env)
+	}
 
-		// Mengambil userID dari claims.
-		userID, ok := claims["sub"].(string)
-		if !ok {
-			log.Println("failed to get userID from claims")
-			ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "internal server error"))
-			break
-		}
+	return nil
+}
 
-		// 4. Buat entitas Message baru untuk pesan pengguna.
-		newMessage := &Message{
-			ID:        uuid.NewString(),
-			RoomID:    roomID,
-			UserID:    userID,
-			Content:   string(msgBytes),
-			CreatedAt: time.Now(),
-		}
-		// 5. Simpan pesan pengguna ke database.
-		if err := uc.chatMongo.CreateMessage(ctx, newMessage); err != nil {
-			log.Println("write error:", err)
-			continue
+// handleFrame memproses satu envelope yang sudah divalidasi dari client.
+func (uc *ChatUsecaseImpl) handleFrame(ctx context.Context, client *Client, env *Envelope) {
+	switch env.Type {
+	case TypeSendMessage:
+		var p SendMessagePayload
+		json.Unmarshal(env.Payload, &p) // Payload sudah divalidasi oleh decodeFrame.
+		uc.handleSendMessage(ctx, client, env.ClientMsgID, p.Content)
+	case TypeTyping:
+		var p TypingPayload
+		json.Unmarshal(env.Payload, &p)
+		if uc.checkTypingQuota(ctx, client.userID) != nil {
+			return
 		}
+		uc.broadcastEvent(client.roomID, newEvent(TypeTyping, TypingPayload{UserID: client.userID, IsTyping: p.IsTyping}))
+	}
+}
+
+// handleSendMessage menyimpan pesan baru dari pengguna, mengirim `ack` ke pengirim,
+// menyiarkannya ke room, lalu meminta balasan AI. Pesan yang melampaui kuota ditolak dengan `rate_limited`.
+func (uc *ChatUsecaseImpl) handleSendMessage(ctx context.Context, client *Client, clientMsgID, content string) {
+	// Tolak pesan jika pengguna atau room sudah melampaui batas pesan per menit.
+	if qerr := uc.checkMessageQuota(ctx, client.roomID, client.userID); qerr != nil {
+		uc.sendEvent(client, newRateLimitedEvent(clientMsgID, qerr))
+		return
+	}
+
+	// 1. Buat entitas Message baru untuk pesan pengguna.
+	newMessage := &Message{
+		ID:        uuid.NewString(),
+		RoomID:    client.roomID,
+		UserID:    client.userID,
+		Content:   content,
+		CreatedAt: time.Now(),
+		Status:    StatusUser,
+	}
+
+	// 2. Simpan pesan pengguna ke database.
+	if err := uc.chatRepo.CreateMessage(ctx, newMessage); err != nil {
+		log.Println("write error:", err)
+		uc.sendEvent(client, newErrorEvent(clientMsgID, ErrCodeInternal, "gagal menyimpan pesan"))
+		return
+	}
+
+	// 3. Konfirmasi ke pengirim, lalu siarkan pesan ke semua client.
+	ack := newEvent(TypeAck, AckPayload{MessageID: newMessage.ID, CreatedAt: newMessage.CreatedAt})
+	ack.ClientMsgID = clientMsgID
+	uc.sendEvent(client, ack)
+	uc.broadcast(client.roomID, newMessage)
+
+	// 4. Pesan tetap tersimpan walaupun kuota AI habis, tetapi AI tidak membalas.
+	if qerr := uc.checkAIQuota(ctx, client.roomID, client.userID); qerr != nil {
+		uc.sendEvent(client, newRateLimitedEvent(clientMsgID, qerr))
+		return
+	}
 
-		// 6. Siarkan pesan pengguna ke semua client.
-		uc.broadcast(roomID, newMessage)
+	// 5. Stream balasan AI ke room dalam sebuah goroutine.
+	go uc.streamAIReply(client.roomID, client.userName, newMessage)
+}
 
-		// 7. Panggil Gemini API untuk mendapatkan balasan dalam sebuah goroutine.
-		go func() {
-			// Kirim indikator "mulai mengetik".
-			uc.broadcastEvent(roomID, map[string]interface{}{"type": "typing_indicator", "is_typing": true, "user_id": "GEMINI"})
-			// Pastikan indikator "berhenti mengetik" dikirim saat goroutine selesai.
-			defer uc.broadcastEvent(roomID, map[string]interface{}{"type": "typing_indicator", "is_typing": false, "user_id": "GEMINI"})
+// replyError membalas frame yang tidak valid dengan envelope `error`.
+func (uc *ChatUsecaseImpl) replyError(client *Client, err error) {
+	var perr *protocolError
+	if !errors.As(err, &perr) {
+		perr = &protocolError{code: ErrCodeInternal, message: "frame gagal diproses"}
+	}
+	uc.sendEvent(client, newErrorEvent(perr.clientMsgID, perr.code, perr.message))
+}
+
+// persona mengembalikan instruksi sistem untuk AI dari template PROMPT_TEMA.
+func (uc *ChatUsecaseImpl) persona(roomID, userName string) string {
+	return renderPersona(uc.cfg.PromptTema, roomID, userName, time.Now())
+}
+
+// sendWelcome mengirim pesan sambutan room ke client yang baru terhubung.
+func (uc *ChatUsecaseImpl) sendWelcome(ctx context.Context, client *Client) error {
+	welcome, err := uc.welcomeMessage(ctx, client.roomID, client.userID)
+	if err != nil {
+		return err
+	}
+	return uc.sendEvent(client, newEvent(TypeWelcome, WelcomePayload{Message: welcome}))
+}
+
+// welcomeMessage mengembalikan pesan sambutan untuk sebuah room. Pesan diambil dari cache, lalu dari
+// penyimpanan, dan baru dibuat dengan LLM (lalu disimpan) jika room belum pernah memilikinya.
+// Pemanggilan bersamaan untuk room yang sama hanya menghasilkan satu panggilan ke LLM.
+// Sambutan dibagikan ke semua pengguna room, sehingga persona diisi tanpa nama pengguna tertentu;
+// userID hanya dipakai untuk mencatat pemakaian token pada pengguna yang memicunya.
+func (uc *ChatUsecaseImpl) welcomeMessage(ctx context.Context, roomID, userID string) (*Message, error) {
+	uc.welcomeMu.RLock()
+	cached, ok := uc.welcomes[roomID]
+	uc.welcomeMu.RUnlock()
+	if ok {
+		return cached, nil
+	}
+
+	v, err, _ := uc.welcomeGroup.Do(roomID, func() (interface{}, error) {
+		// 1. Cek penyimpanan, mungkin sambutan sudah dibuat sebelum server di-restart.
+		welcome, err := uc.chatRepo.GetLatestMessageByStatus(ctx, roomID, StatusSystem)
+		if err != nil && !errors.Is(err, ErrMessageNotFound) {
+			return nil, err
+		}
 
-			aiResponse, err := uc.geminiClient.GenerateContent(context.Background(), newMessage.Content)
+		// 2. Jika belum ada, buat sambutan baru dengan LLM dan simpan sebagai pesan sistem.
+		if welcome == nil {
+			req := llm.UserPrompt(uc.cfg.WelcomePrompt)
+			req.System = uc.persona(roomID, roomAudienceName)
+			aiResponse, err := uc.llm.Generate(ctx, req)
 			if err != nil {
-				log.Printf("failed to get response from gemini: %v", err)
-				return
+				return nil, fmt.Errorf("failed to generate welcome message: %w", err)
 			}
 
-			// 8. Buat entitas Message untuk balasan AI.
-			aiMessage := &Message{
+			welcome = &Message{
 				ID:        uuid.NewString(),
 				RoomID:    roomID,
-				UserID:    "GEMINI", // ID khusus untuk menandakan pesan dari AI.
-				Content:   aiResponse,
+				UserID:    AIUserID,
+				Content:   aiResponse.Text,
 				CreatedAt: time.Now(),
+				Status:    StatusSystem,
+				Model:     aiResponse.Model,
+				Usage:     newTokenUsage(aiResponse.Usage),
 			}
-
-			// 9. Simpan balasan AI ke database.
-			if err := uc.chatMongo.CreateMessage(context.Background(), aiMessage); err != nil {
-				log.Println("write error for ai message:", err)
-				return
+			uc.recordUsage(ctx, userID, welcome)
+			if err := uc.chatRepo.CreateMessage(ctx, welcome); err != nil {
+				return nil, fmt.Errorf("failed to store welcome message: %w", err)
 			}
+		}
+
+		// 3. Simpan ke cache agar client berikutnya tidak perlu menunggu.
+		uc.welcomeMu.Lock()
+		uc.welcomes[roomID] = welcome
+		uc.welcomeMu.Unlock()
 
-			// 10. Siarkan balasan AI ke semua client.
-			uc.broadcast(roomID, aiMessage)
-		}()
+		return welcome, nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return v.(*Message), nil
+}
 
-	return nil
+// sendHistory mengirim pesan-pesan terakhir room ke client yang baru terhubung sebagai satu event `history`.
+// Jumlah pesan diambil dari opts.HistoryLimit atau ChatHistoryLimit di config. Jika opts.SinceID diisi,
+// hanya pesan setelah ID tersebut yang dikirim; ID yang tidak dikenal diperlakukan seperti koneksi baru.
+// HistoryLimit 0 yang diminta client berarti tanpa riwayat, juga saat reconnect.
+// Pesan yang terkirim dikembalikan agar tidak dikirim ulang dari event yang ditahan selama bergabung.
+func (uc *ChatUsecaseImpl) sendHistory(ctx context.Context, client *Client, opts JoinOptions) ([]*Message, error) {
+	roomID := client.roomID
+	if opts.HistoryLimit != nil && *opts.HistoryLimit <= 0 {
+		return nil, nil
+	}
+	limit := uc.cfg.ChatHistoryLimit
+	if opts.HistoryLimit != nil {
+		limit = *opts.HistoryLimit
+	}
+	if limit <= 0 && opts.SinceID == "" {
+		return nil, nil
+	}
+
+	query := MessageQuery{Limit: limit}
+	if opts.SinceID != "" {
+		query.AfterID = opts.SinceID
+		// Client yang reconnect menerima semua yang terlewat, kecuali dibatasi secara eksplisit.
+		if opts.HistoryLimit == nil {
+			query.Limit = MaxMessageLimit
+		}
+	}
+
+	messages, err := uc.chatRepo.GetMessagesByRoom(ctx, roomID, query)
+	if errors.Is(err, ErrMessageNotFound) && opts.SinceID != "" {
+		if limit <= 0 {
+			return nil, nil
+		}
+		messages, err = uc.chatRepo.GetMessagesByRoom(ctx, roomID, MessageQuery{Limit: limit})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.sendEvent(client, newEvent(TypeHistory, HistoryPayload{Messages: messages})); err != nil {
+		return nil, err
+	}
+	return messages, nil
 }
 
-// addConnection secara aman (thread-safe) menambahkan koneksi baru ke map `rooms`.
-func (uc *ChatUsecaseImpl) addConnection(roomID string, ws *websocket.Conn) {
-	uc.mu.Lock()
-	defer uc.mu.Unlock()
+// streamAIReply meminta balasan dari LLM secara streaming dan meneruskan setiap potongan teks
+// ke room sebagai event `ai_chunk`. Setelah stream selesai, teks lengkap disimpan sebagai satu Message
+// dan event `ai_done` dikirim. Jika terjadi kegagalan, event `ai_error` dikirim.
+func (uc *ChatUsecaseImpl) streamAIReply(roomID, userName string, userMessage *Message) {
+	// Kirim indikator "mulai mengetik".
+	uc.broadcastEvent(roomID, newEvent(TypeTyping, TypingPayload{UserID: AIUserID, IsTyping: true}))
+	// Pastikan indikator "berhenti mengetik" dikirim saat goroutine selesai.
+	defer uc.broadcastEvent(roomID, newEvent(TypeTyping, TypingPayload{UserID: AIUserID, IsTyping: false}))
+
+	// ID pesan AI dibuat di awal agar semua event streaming merujuk ke pesan yang sama.
+	aiMessageID := uuid.NewString()
 
-	aiResponse, err := uc.geminiClient.GenerateContent(context.Background(), uc.cfg.PromptTema)
+	// Susun percakapan dari riwayat room agar AI memahami konteks pertanyaan lanjutan.
+	req, err := uc.buildConversation(context.Background(), roomID, userMessage)
 	if err != nil {
-		log.Printf("failed to get response from gemini: %v", err)
+		// Riwayat gagal dimuat: tetap balas, hanya berdasarkan pesan terakhir.
+		log.Printf("failed to load room history for ai: %v", err)
+		req = llm.UserPrompt(userMessage.Content)
+	}
+	// Persona selalu dikirim sebagai instruksi sistem agar AI tetap pada perannya di setiap balasan.
+	req.System = uc.persona(roomID, userName)
+
+	aiResponse, err := uc.llm.Stream(context.Background(), req, func(text string) error {
+		uc.broadcastEvent(roomID, newEvent(TypeAIChunk, AIChunkPayload{MessageID: aiMessageID, Delta: text}))
+		return nil
+	})
+	if err != nil {
+		log.Printf("failed to get response from llm: %v", err)
+		uc.broadcastEvent(roomID, newAIErrorEvent(aiMessageID, err))
 		return
 	}
 
-	subs := aiResponse
-	maxLen := 500
-	runes := []rune(subs)
-	if len(runes) > maxLen {
-		subs = string(runes[:maxLen]) + "..."
+	// 8. Buat entitas Message untuk balasan AI dengan teks lengkap.
+	aiMessage := &Message{
+		ID:        aiMessageID,
+		RoomID:    roomID,
+		UserID:    AIUserID,
+		Content:   aiResponse.Text,
+		CreatedAt: time.Now(),
+		Status:    StatusAI,
+		Model:     aiResponse.Model,
+		Usage:     newTokenUsage(aiResponse.Usage),
+	}
+	// Token sudah terpakai walaupun penyimpanan pesan gagal, jadi pemakaian dicatat lebih dulu.
+	uc.recordUsage(context.Background(), userMessage.UserID, aiMessage)
+	if aiMessage.Usage != nil {
+		uc.consumeTokenQuota(context.Background(), roomID, userMessage.UserID, aiMessage.Usage.TotalTokens)
 	}
 
-	// LOG RESPONSE
-	fmt.Println("Gemini Response:", subs)
+	// 9. Simpan balasan AI ke database.
+	if err := uc.chatRepo.CreateMessage(context.Background(), aiMessage); err != nil {
+		log.Println("write error for ai message:", err)
+		uc.broadcastEvent(roomID, newEvent(TypeAIError, AIErrorPayload{MessageID: aiMessageID, Code: AIErrCodeFailed, Error: "gagal menyimpan balasan AI"}))
+		return
+	}
+
+	// 10. Beri tahu semua client bahwa balasan AI sudah lengkap.
+	uc.broadcastEvent(roomID, newEvent(TypeAIDone, AIDonePayload{MessageID: aiMessageID, Message: aiMessage}))
+}
 
-	if _, ok := uc.rooms[roomID]; !ok {
-		uc.rooms[roomID] = make(map[*websocket.Conn]bool)
+// recordUsage menambahkan pemakaian token sebuah pesan AI ke penghitung pengguna dan room.
+// Kegagalan hanya dicatat di log agar tidak mengganggu chat.
+func (uc *ChatUsecaseImpl) recordUsage(ctx context.Context, userID string, msg *Message) {
+	if uc.usage == nil || msg.Usage == nil {
+		return
+	}
+	err := uc.usage.Record(ctx, usage.Record{
+		UserID:          userID,
+		RoomID:          msg.RoomID,
+		Model:           msg.Model,
+		PromptTokens:    msg.Usage.PromptTokens,
+		CandidateTokens: msg.Usage.CandidateTokens,
+		TotalTokens:     msg.Usage.TotalTokens,
+		At:              msg.CreatedAt,
+	})
+	if err != nil {
+		log.Println("failed to record ai usage:", err)
 	}
-	uc.rooms[roomID][ws] = true
 }
 
-// removeConnection secara aman (thread-safe) menghapus koneksi dari map `rooms`.
-func (uc *ChatUsecaseImpl) removeConnection(roomID string, ws *websocket.Conn) {
-	uc.mu.Lock()
-	defer uc.mu.Unlock()
+// newTokenUsage mengubah llm.Usage menjadi TokenUsage, atau nil jika penyedia tidak melaporkan pemakaian.
+func newTokenUsage(u llm.Usage) *TokenUsage {
+	if u == (llm.Usage{}) {
+		return nil
+	}
+	return &TokenUsage{PromptTokens: u.PromptTokens, CandidateTokens: u.CandidateTokens, TotalTokens: u.TotalTokens}
+}
 
-	if _, ok := uc.rooms[roomID]; ok {
-		delete(uc.rooms[roomID], ws)
-		if len(uc.rooms[roomID]) == 0 {
-			delete(uc.rooms, roomID)
+// buildConversation menyusun riwayat room menjadi percakapan untuk LLM.
+// Pesan pengguna menjadi role `user`, balasan AI menjadi role `assistant`. Riwayat dipotong dari yang
+// terlama sesuai batas AIHistoryMaxMessages dan AIHistoryMaxTokens di config.
+func (uc *ChatUsecaseImpl) buildConversation(ctx context.Context, roomID string, userMessage *Message) (llm.Request, error) {
+	// Ambil pesan terbaru secukupnya; batas token diterapkan setelahnya.
+	limit := uc.cfg.AIHistoryMaxMessages
+	if limit <= 0 || limit > MaxMessageLimit {
+		limit = MaxMessageLimit
+	}
+	history, err := uc.chatRepo.GetMessagesByRoom(ctx, roomID, MessageQuery{Limit: limit})
+	if err != nil {
+		return llm.Request{}, err
+	}
+
+	// Pastikan pesan terbaru ikut terkirim walaupun belum terbaca dari penyimpanan.
+	if len(history) == 0 || history[len(history)-1].ID != userMessage.ID {
+		history = append(history, userMessage)
+	}
+
+	// 1. Ambil pesan dari yang terbaru ke yang terlama sampai salah satu batas terlampaui.
+	//    Pesan terbaru selalu disertakan.
+	var selected []*Message
+	tokens := 0
+	for i := len(history) - 1; i >= 0; i-- {
+		msg := history[i]
+		if msg.Status != StatusUser && msg.Status != StatusAI {
+			continue
+		}
+		msgTokens := llm.EstimateTokens(msg.Content)
+		if len(selected) > 0 {
+			if uc.cfg.AIHistoryMaxMessages > 0 && len(selected) >= uc.cfg.AIHistoryMaxMessages {
+				break
+			}
+			if uc.cfg.AIHistoryMaxTokens > 0 && tokens+msgTokens > uc.cfg.AIHistoryMaxTokens {
+				break
+			}
 		}
+		selected = append(selected, msg)
+		tokens += msgTokens
 	}
+
+	// 2. Susun ulang dari yang terlama.
+	var req llm.Request
+	for i := len(selected) - 1; i >= 0; i-- {
+		role := llm.RoleUser
+		if selected[i].Status == StatusAI {
+			role = llm.RoleAssistant
+		}
+		// Percakapan harus dimulai dari giliran pengguna.
+		if len(req.Messages) == 0 && role != llm.RoleUser {
+			continue
+		}
+		req.Messages = append(req.Messages, llm.Message{Role: role, Content: selected[i].Content})
+	}
+
+	return req, nil
 }
 
-// broadcast mengirimkan pesan ke semua koneksi yang aktif di sebuah room.
-func (uc *ChatUsecaseImpl) broadcast(roomID string, msg *Message) {
-	uc.mu.RLock()
-	defer uc.mu.RUnlock()
+// addConnection secara aman (thread-safe) menambahkan client baru ke map `rooms`,
+// lalu memberi tahu room bahwa pengguna tersebut online.
+func (uc *ChatUsecaseImpl) addConnection(client *Client) {
+	uc.mu.Lock()
+	if _, ok := uc.rooms[client.roomID]; !ok {
+		uc.rooms[client.roomID] = make(map[*Client]bool)
+	}
+	uc.rooms[client.roomID][client] = true
+	uc.mu.Unlock()
+
+	uc.broadcastEvent(client.roomID, newEvent(TypePresence, PresencePayload{UserID: client.userID, Status: PresenceOnline}))
+}
 
-	for conn := range uc.rooms[roomID] {
-		if err := conn.WriteJSON(msg); err != nil {
-			log.Println("write error:", err)
-			uc.removeConnection(roomID, conn)
+// removeConnection secara aman (thread-safe) menghapus client dari map `rooms`,
+// lalu memberi tahu room bahwa pengguna tersebut offline.
+func (uc *ChatUsecaseImpl) removeConnection(client *Client) {
+	uc.mu.Lock()
+	if _, ok := uc.rooms[client.roomID]; ok {
+		delete(uc.rooms[client.roomID], client)
+		if len(uc.rooms[client.roomID]) == 0 {
+			delete(uc.rooms, client.roomID)
 		}
 	}
+	uc.mu.Unlock()
+
+	uc.broadcastEvent(client.roomID, newEvent(TypePresence, PresencePayload{UserID: client.userID, Status: PresenceOffline}))
 }
 
-// broadcastEvent mengirimkan event generic (dalam format JSON) ke semua koneksi di sebuah room.
-func (uc *ChatUsecaseImpl) broadcastEvent(roomID string, event interface{}) {
+// DeleteMessage menghapus pesan dari room sebagai tindakan moderasi, lalu menyiarkan `message_deleted`
+// agar client menghapus pesan tersebut dari tampilannya. Sambutan room yang dihapus akan dibuat ulang.
+func (uc *ChatUsecaseImpl) DeleteMessage(ctx context.Context, roomID, messageID string) error {
+	if err := uc.chatRepo.DeleteMessage(ctx, roomID, messageID); err != nil {
+		return err
+	}
+
+	uc.welcomeMu.Lock()
+	if welcome, ok := uc.welcomes[roomID]; ok && welcome.ID == messageID {
+		delete(uc.welcomes, roomID)
+	}
+	uc.welcomeMu.Unlock()
+
+	uc.broadcastEvent(roomID, newEvent(TypeMessageDeleted, MessageDeletedPayload{MessageID: messageID}))
+	return nil
+}
+
+// CloseUserConnections menutup semua koneksi WebSocket milik seorang pengguna di semua room,
+// misalnya setelah tokennya dicabut. Koneksi dihapus dari room oleh goroutine pembacanya sendiri.
+func (uc *ChatUsecaseImpl) CloseUserConnections(userID, reason string) {
 	uc.mu.RLock()
 	defer uc.mu.RUnlock()
 
-	for conn := range uc.rooms[roomID] {
-		if err := conn.WriteJSON(event); err != nil {
-			log.Println("write error on event broadcast:", err)
+	for _, clients := range uc.rooms {
+		for client := range clients {
+			if client.userID == userID {
+				client.close(websocket.ClosePolicyViolation, reason)
+			}
 		}
 	}
 }
 
-func (uc *ChatUsecaseImpl) TypingIndicator(roomID string, userID string) {
+// sendEvent mengirimkan event hanya ke satu client, di-encode sesuai versi protokol client tersebut.
+func (uc *ChatUsecaseImpl) sendEvent(client *Client, event Event) error {
+	payload, err := event.encode(client.version)
+	if err != nil {
+		return err
+	}
+	if !client.enqueue(payload) {
+		return errors.New("client is not accepting messages")
+	}
+	return nil
+}
+
+// broadcast mengirimkan pesan ke semua koneksi yang aktif di sebuah room.
+func (uc *ChatUsecaseImpl) broadcast(roomID string, msg *Message) {
+	ev := newEvent(TypeMessage, msg)
+	ev.ID = msg.ID
+	uc.broadcastEvent(roomID, ev)
+}
+
+// broadcastEvent mengirimkan event ke semua koneksi di sebuah room.
+// Event di-encode sekali per versi protokol lalu dimasukkan ke buffer setiap client tanpa memblokir;
+// client yang lambat ditangani sesuai kebijakan slow consumer dan dihapus oleh goroutine pembacanya sendiri.
+func (uc *ChatUsecaseImpl) broadcastEvent(roomID string, event Event) {
 	uc.mu.RLock()
 	defer uc.mu.RUnlock()
 
-	indicator := map[string]string{
-		"user_id": userID,
-		"status":  "typing",
-	}
-	for conn := range uc.rooms[roomID] {
-		if err := conn.WriteJSON(indicator); err != nil {
-			log.Println("write error:", err)
-			uc.removeConnection(roomID, conn)
+	encoded := make(map[int][]byte)
+	for client := range uc.rooms[roomID] {
+		payload, ok := encoded[client.version]
+		if !ok {
+			var err error
+			payload, err = event.encode(client.version)
+			if err != nil {
+				log.Println("failed to encode event:", err)
+				return
+			}
+			encoded[client.version] = payload
 		}
+		client.deliver(event.ID, payload)
 	}
 }
+
+// TypingIndicator mengirimkan status "sedang mengetik" dari seorang pengguna ke semua koneksi di room.
+func (uc *ChatUsecaseImpl) TypingIndicator(roomID string, userID string) {
+	uc.broadcastEvent(roomID, newEvent(TypeTyping, TypingPayload{UserID: userID, IsTyping: true}))
+}
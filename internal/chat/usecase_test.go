@@ -0,0 +1,545 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/auth"
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/config"
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/llm"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+)
+
+// recordingProvider mencatat instruksi sistem dari setiap Generate dan permintaan lengkap dari setiap Stream.
+type recordingProvider struct {
+	*llm.FakeProvider
+	mu       sync.Mutex
+	systems  []string
+	streamed []llm.Request
+}
+
+func (p *recordingProvider) Generate(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	p.mu.Lock()
+	p.systems = append(p.systems, req.System)
+	p.mu.Unlock()
+	return p.FakeProvider.Generate(ctx, req)
+}
+
+func (p *recordingProvider) Stream(ctx context.Context, req llm.Request, onChunk func(text string) error) (*llm.Response, error) {
+	p.mu.Lock()
+	p.streamed = append(p.streamed, req)
+	p.mu.Unlock()
+	return p.FakeProvider.Stream(ctx, req, onChunk)
+}
+
+func TestWelcomeMessageIsNotPersonalized(t *testing.T) {
+	provider := &recordingProvider{FakeProvider: llm.NewFakeProvider([]string{"Selamat datang!"}, 0, 0)}
+	cfg := &config.Config{PromptTema: "Kamu menyapa {{user_name}} di room {{room_id}}.", WelcomePrompt: "Sapa room ini."}
+	uc := NewChatUsecase(NewInMemoryChatRepository(), provider, nil, nil, cfg)
+
+	first, err := uc.welcomeMessage(context.Background(), "room-1", "user-1")
+	if err != nil {
+		t.Fatalf("welcomeMessage: %v", err)
+	}
+	second, err := uc.welcomeMessage(context.Background(), "room-1", "user-2")
+	if err != nil {
+		t.Fatalf("welcomeMessage: %v", err)
+	}
+	if first.ID != second.ID {
+		t.Error("each joiner got a different welcome message")
+	}
+
+	if len(provider.systems) != 1 {
+		t.Fatalf("llm called %d times, want 1", len(provider.systems))
+	}
+	if want := "Kamu menyapa " + roomAudienceName + " di room room-1."; provider.systems[0] != want {
+		t.Errorf("system = %q, want %q", provider.systems[0], want)
+	}
+	if strings.Contains(provider.systems[0], "{{user_name}}") {
+		t.Error("placeholder was not rendered")
+	}
+}
+
+// newStreamServer menjalankan HandleStream di server uji. Pengguna diambil dari query param `user`,
+// menggantikan middleware JWT.
+func newStreamServer(t *testing.T, uc *ChatUsecaseImpl) string {
+	t.Helper()
+	e := echo.New()
+	h := NewChatHandler(uc)
+	e.GET("/v1/ws", h.HandleWebSocket, func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims := &auth.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: c.QueryParam("user")}}
+			c.Set(auth.ContextKey, &jwt.Token{Claims: claims, Valid: true})
+			return next(c)
+		}
+	})
+	srv := httptest.NewServer(e)
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/ws"
+}
+
+// dialRoom menghubungkan client uji ke sebuah room dan menunggu event `history`, yang menandakan
+// client sudah terdaftar di room.
+func dialRoom(baseURL, roomID, userID string) (*websocket.Conn, error) {
+	url := fmt.Sprintf("%s?roomId=%s&user=%s&history=1&v=%d", baseURL, roomID, userID, LatestProtocolVersion)
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var env Envelope
+		if err := conn.ReadJSON(&env); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if env.Type == TypeHistory {
+			return conn, nil
+		}
+	}
+}
+
+// roomSize mengembalikan jumlah koneksi yang terdaftar di sebuah room.
+func (uc *ChatUsecaseImpl) roomSize(roomID string) int {
+	uc.mu.RLock()
+	defer uc.mu.RUnlock()
+	return len(uc.rooms[roomID])
+}
+
+func TestBroadcastWhileClientsJoinAndLeave(t *testing.T) {
+	const (
+		stableClients = 8
+		churnClients  = 8
+		churnRounds   = 15
+		messages      = 200
+	)
+
+	cfg := &config.Config{
+		PromptTema:           "persona",
+		WSSendBufferSize:     4096,
+		WSSlowConsumerPolicy: SlowConsumerDisconnect,
+		WSMaxMessageSize:     8192,
+	}
+	uc := NewChatUsecase(NewInMemoryChatRepository(), llm.NewFakeProvider([]string{"halo"}, 0, 0), nil, nil, cfg)
+	baseURL := newStreamServer(t, uc)
+
+	// 1. Hubungkan client tetap; semuanya harus menerima setiap pesan yang disiarkan.
+	stable := make([]*websocket.Conn, stableClients)
+	for i := range stable {
+		conn, err := dialRoom(baseURL, "room", fmt.Sprintf("stable-%d", i))
+		if err != nil {
+			t.Fatalf("dial stable client: %v", err)
+		}
+		defer conn.Close()
+		stable[i] = conn
+	}
+
+	received := make([][]string, stableClients)
+	var readers sync.WaitGroup
+	for i, conn := range stable {
+		readers.Add(1)
+		go func(i int, conn *websocket.Conn) {
+			defer readers.Done()
+			conn.SetReadDeadline(time.Now().Add(20 * time.Second))
+			for len(received[i]) < messages {
+				var env Envelope
+				if err := conn.ReadJSON(&env); err != nil {
+					t.Errorf("stable client %d: %v", i, err)
+					return
+				}
+				if env.Type == TypeMessage {
+					received[i] = append(received[i], env.ID)
+				}
+			}
+		}(i, conn)
+	}
+
+	// 2. Client lain bergabung dan keluar berulang kali selama penyiaran berlangsung.
+	var churn sync.WaitGroup
+	for i := 0; i < churnClients; i++ {
+		churn.Add(1)
+		go func(i int) {
+			defer churn.Done()
+			for round := 0; round < churnRounds; round++ {
+				conn, err := dialRoom(baseURL, "room", fmt.Sprintf("churn-%d", i))
+				if err != nil {
+					t.Errorf("dial churn client: %v", err)
+					return
+				}
+				conn.Close()
+			}
+		}(i)
+	}
+
+	// 3. Siarkan pesan, bersamaan dengan event typing dari goroutine lain.
+	stopTyping := make(chan struct{})
+	typingDone := make(chan struct{})
+	go func() {
+		defer close(typingDone)
+		for {
+			select {
+			case <-stopTyping:
+				return
+			default:
+				uc.TypingIndicator("room", "someone")
+			}
+		}
+	}()
+	want := make([]string, messages)
+	for i := range want {
+		want[i] = fmt.Sprintf("m%03d", i)
+		uc.broadcast("room", &Message{ID: want[i], RoomID: "room", Content: want[i]})
+	}
+	close(stopTyping)
+	<-typingDone
+
+	churn.Wait()
+	readers.Wait()
+	for i, got := range received {
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("stable client %d received %d messages out of order or with gaps", i, len(got))
+		}
+	}
+
+	// 4. Setelah semua koneksi ditutup, room harus kosong.
+	for _, conn := range stable {
+		conn.Close()
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for uc.roomSize("room") > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d connections still registered after all clients left", uc.roomSize("room"))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestBroadcastSlowConsumerPolicy(t *testing.T) {
+	tests := []struct {
+		policy         string
+		wantDisconnect bool
+	}{
+		{policy: SlowConsumerDrop, wantDisconnect: false},
+		{policy: SlowConsumerDisconnect, wantDisconnect: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			uc := NewChatUsecase(NewInMemoryChatRepository(), nil, nil, nil, &config.Config{})
+
+			// Client tanpa writePump tidak pernah mengosongkan buffer-nya, sehingga bisa dibuat penuh.
+			fast := newClient(nil, "room", "fast", LatestProtocolVersion, clientOptions{bufferSize: 16, policy: tt.policy})
+			slow := newClient(nil, "room", "slow", LatestProtocolVersion, clientOptions{bufferSize: 1, policy: tt.policy})
+			uc.addConnection(fast)
+			uc.addConnection(slow) // Event presence miliknya sendiri memenuhi buffer slow.
+
+			for i := 0; i < 3; i++ {
+				uc.broadcast("room", &Message{ID: fmt.Sprintf("m%d", i), RoomID: "room"})
+			}
+
+			// Client yang lambat tidak boleh mengganggu client lain.
+			if got := len(fast.send); got != 5 {
+				t.Errorf("fast client buffered %d events, want 5 (2 presence, 3 messages)", got)
+			}
+			if got := len(slow.send); got != 1 {
+				t.Errorf("slow client buffered %d events, want 1", got)
+			}
+
+			select {
+			case <-slow.done:
+				if !tt.wantDisconnect {
+					t.Fatal("slow client was disconnected under the drop policy")
+				}
+				if slow.closeCode != websocket.ClosePolicyViolation {
+					t.Errorf("close code = %d, want %d", slow.closeCode, websocket.ClosePolicyViolation)
+				}
+			default:
+				if tt.wantDisconnect {
+					t.Fatal("slow client was not disconnected")
+				}
+			}
+
+			// Client yang diputus tidak menerima event lagi.
+			if tt.wantDisconnect && slow.enqueue([]byte("late")) {
+				t.Error("disconnected client accepted an event")
+			}
+		})
+	}
+}
+
+func TestSendHistoryLimits(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryChatRepository()
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 60; i++ {
+		msg := &Message{ID: fmt.Sprintf("m%02d", i), RoomID: "room", CreatedAt: base.Add(time.Duration(i) * time.Second)}
+		if err := repo.CreateMessage(ctx, msg); err != nil {
+			t.Fatalf("CreateMessage: %v", err)
+		}
+	}
+	uc := NewChatUsecase(repo, llm.NewFakeProvider(nil, 0, 0), nil, nil, &config.Config{ChatHistoryLimit: 10})
+	limit := func(n int) *int { return &n }
+
+	tests := []struct {
+		name      string
+		opts      JoinOptions
+		wantFirst string // ID pesan pertama di riwayat; kosong berarti tidak ada event `history`.
+		wantCount int
+	}{
+		{name: "config default", opts: JoinOptions{}, wantFirst: "m50", wantCount: 10},
+		{name: "explicit limit", opts: JoinOptions{HistoryLimit: limit(3)}, wantFirst: "m57", wantCount: 3},
+		{name: "explicit zero", opts: JoinOptions{HistoryLimit: limit(0)}},
+		{name: "explicit zero with since", opts: JoinOptions{HistoryLimit: limit(0), SinceID: "m10"}},
+		{name: "explicit zero with unknown since", opts: JoinOptions{HistoryLimit: limit(0), SinceID: "x"}},
+		{name: "since sends everything missed", opts: JoinOptions{SinceID: "m30"}, wantFirst: "m31", wantCount: 29},
+		{name: "since with limit", opts: JoinOptions{HistoryLimit: limit(2), SinceID: "m30"}, wantFirst: "m31", wantCount: 2},
+		{name: "unknown since acts like a new connection", opts: JoinOptions{SinceID: "x"}, wantFirst: "m50", wantCount: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newClient(nil, "room", "user", LatestProtocolVersion, clientOptions{bufferSize: 4, policy: SlowConsumerDrop})
+			messages, err := uc.sendHistory(ctx, client, tt.opts)
+			if err != nil {
+				t.Fatalf("sendHistory: %v", err)
+			}
+			if len(messages) != tt.wantCount {
+				t.Fatalf("sent %d messages, want %d", len(messages), tt.wantCount)
+			}
+			if tt.wantFirst == "" {
+				if len(client.send) != 0 {
+					t.Errorf("history event sent, want none")
+				}
+				return
+			}
+			if len(client.send) != 1 {
+				t.Fatalf("queued %d events, want 1 history event", len(client.send))
+			}
+			if messages[0].ID != tt.wantFirst {
+				t.Errorf("first message = %s, want %s", messages[0].ID, tt.wantFirst)
+			}
+		})
+	}
+}
+
+// joinTestClient mendaftarkan client tanpa koneksi WebSocket ke sebuah room. Event untuk client tersebut
+// bisa dibaca dengan drainEvents.
+func joinTestClient(uc *ChatUsecaseImpl, roomID, userID string) *Client {
+	client := newClient(nil, roomID, userID, LatestProtocolVersion, clientOptions{bufferSize: 256, policy: SlowConsumerDrop})
+	uc.addConnection(client)
+	drainEvents(client) // Buang event presence milik client sendiri.
+	return client
+}
+
+// drainEvents mengambil semua envelope yang sedang mengantre untuk client.
+func drainEvents(client *Client) []Envelope {
+	var events []Envelope
+	for {
+		select {
+		case payload := <-client.send:
+			var env Envelope
+			json.Unmarshal(payload, &env)
+			events = append(events, env)
+		default:
+			return events
+		}
+	}
+}
+
+// eventTypes mengembalikan jenis setiap envelope sesuai urutannya.
+func eventTypes(events []Envelope) []string {
+	types := make([]string, 0, len(events))
+	for _, env := range events {
+		types = append(types, env.Type)
+	}
+	return types
+}
+
+func TestStreamAIReplySendsChunksThenDone(t *testing.T) {
+	repo := NewInMemoryChatRepository()
+	uc := NewChatUsecase(repo, llm.NewFakeProvider([]string{"Halo apa kabar"}, 0, 0), nil, nil, &config.Config{PromptTema: "persona"})
+	client := joinTestClient(uc, "room", "user-1")
+
+	userMessage := &Message{ID: "m1", RoomID: "room", UserID: "user-1", Content: "hai", Status: StatusUser, CreatedAt: time.Now()}
+	uc.streamAIReply("room", "Budi", userMessage)
+
+	events := drainEvents(client)
+	want := []string{TypeTyping, TypeAIChunk, TypeAIChunk, TypeAIChunk, TypeAIDone, TypeTyping}
+	if got := eventTypes(events); !reflect.DeepEqual(got, want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+
+	var text strings.Builder
+	var messageID string
+	for _, env := range events[1:4] {
+		var chunk AIChunkPayload
+		json.Unmarshal(env.Payload, &chunk)
+		if messageID != "" && chunk.MessageID != messageID {
+			t.Errorf("chunk message id = %s, want %s", chunk.MessageID, messageID)
+		}
+		messageID = chunk.MessageID
+		text.WriteString(chunk.Delta)
+	}
+	if text.String() != "Halo apa kabar" {
+		t.Errorf("chunks = %q, want the full reply", text.String())
+	}
+
+	var done AIDonePayload
+	json.Unmarshal(events[4].Payload, &done)
+	if done.MessageID != messageID || done.Message.Content != "Halo apa kabar" || done.Message.Model != llm.FakeModel {
+		t.Errorf("ai_done = %+v (message %+v), want message %s with the full reply from %s", done, done.Message, messageID, llm.FakeModel)
+	}
+
+	stored, err := repo.GetLatestMessageByStatus(context.Background(), "room", StatusAI)
+	if err != nil {
+		t.Fatalf("ai reply was not stored: %v", err)
+	}
+	if stored.ID != messageID || stored.Usage == nil {
+		t.Errorf("stored = %+v, want message %s with usage", stored, messageID)
+	}
+}
+
+func TestStreamAIReplyReportsErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode string
+	}{
+		{name: "provider failure", err: llm.ErrFakeFailure, wantCode: AIErrCodeFailed},
+		{name: "provider unavailable", err: fmt.Errorf("%w: 503", llm.ErrUnavailable), wantCode: AIErrCodeUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := llm.NewFakeProvider([]string{"tidak terkirim"}, 0, 1)
+			provider.Err = tt.err
+			repo := NewInMemoryChatRepository()
+			uc := NewChatUsecase(repo, provider, nil, nil, &config.Config{PromptTema: "persona"})
+			client := joinTestClient(uc, "room", "user-1")
+
+			uc.streamAIReply("room", "Budi", &Message{ID: "m1", RoomID: "room", Content: "hai", Status: StatusUser})
+
+			events := drainEvents(client)
+			if got, want := eventTypes(events), []string{TypeTyping, TypeAIError, TypeTyping}; !reflect.DeepEqual(got, want) {
+				t.Fatalf("events = %v, want %v", got, want)
+			}
+			var payload AIErrorPayload
+			json.Unmarshal(events[1].Payload, &payload)
+			if payload.Code != tt.wantCode || payload.MessageID == "" {
+				t.Errorf("ai_error = %+v, want code %s with a message id", payload, tt.wantCode)
+			}
+			if _, err := repo.GetLatestMessageByStatus(context.Background(), "room", StatusAI); err == nil {
+				t.Error("a failed reply was stored")
+			}
+		})
+	}
+}
+
+func TestStreamAIReplySendsConversationFromHistory(t *testing.T) {
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	history := []*Message{
+		{ID: "m1", Content: "siapa kamu?", Status: StatusUser},
+		{ID: "m2", Content: "aku asisten", Status: StatusAI},
+		{ID: "m3", Content: "selamat datang", Status: StatusSystem}, // Sambutan tidak ikut dikirim.
+		{ID: "m4", Content: "bisa bantu?", Status: StatusUser},
+	}
+
+	tests := []struct {
+		name        string
+		maxMessages int
+		want        []llm.Message
+	}{
+		{
+			name: "whole history",
+			want: []llm.Message{
+				{Role: llm.RoleUser, Content: "siapa kamu?"},
+				{Role: llm.RoleAssistant, Content: "aku asisten"},
+				{Role: llm.RoleUser, Content: "bisa bantu?"},
+			},
+		},
+		{
+			// Dua pesan terakhir diawali balasan AI, yang dibuang agar percakapan dimulai dari pengguna.
+			name:        "trimmed to start with the user",
+			maxMessages: 2,
+			want:        []llm.Message{{Role: llm.RoleUser, Content: "bisa bantu?"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := NewInMemoryChatRepository()
+			for i, msg := range history {
+				msg := *msg
+				msg.RoomID, msg.CreatedAt = "room", base.Add(time.Duration(i)*time.Second)
+				if err := repo.CreateMessage(context.Background(), &msg); err != nil {
+					t.Fatalf("CreateMessage: %v", err)
+				}
+			}
+			provider := &recordingProvider{FakeProvider: llm.NewFakeProvider([]string{"tentu"}, 0, 0)}
+			cfg := &config.Config{PromptTema: "Kamu membantu {{user_name}}.", AIHistoryMaxMessages: tt.maxMessages}
+			uc := NewChatUsecase(repo, provider, nil, nil, cfg)
+
+			last, err := repo.GetMessagesByRoom(context.Background(), "room", MessageQuery{Limit: 1})
+			if err != nil {
+				t.Fatalf("GetMessagesByRoom: %v", err)
+			}
+			uc.streamAIReply("room", "Budi", last[0])
+
+			if len(provider.streamed) != 1 {
+				t.Fatalf("llm streamed %d times, want 1", len(provider.streamed))
+			}
+			req := provider.streamed[0]
+			if !reflect.DeepEqual(req.Messages, tt.want) {
+				t.Errorf("messages = %+v, want %+v", req.Messages, tt.want)
+			}
+			if req.System != "Kamu membantu Budi." {
+				t.Errorf("system = %q, want the rendered persona", req.System)
+			}
+		})
+	}
+}
+
+// namedProvider adalah FakeProvider yang melaporkan nama model lain, untuk membedakan penyedia dalam test.
+type namedProvider struct {
+	*llm.FakeProvider
+	model string
+}
+
+func (p namedProvider) Stream(ctx context.Context, req llm.Request, onChunk func(text string) error) (*llm.Response, error) {
+	resp, err := p.FakeProvider.Stream(ctx, req, onChunk)
+	if resp != nil {
+		resp.Model = p.model
+	}
+	return resp, err
+}
+
+func TestStreamAIReplyRecordsFallbackModel(t *testing.T) {
+	primary := llm.NewFakeProvider(nil, 0, 1)
+	primary.Err = fmt.Errorf("%w: 503", llm.ErrUnavailable)
+	backup := namedProvider{FakeProvider: llm.NewFakeProvider([]string{"dari cadangan"}, 0, 0), model: "cadangan"}
+	repo := NewInMemoryChatRepository()
+	uc := NewChatUsecase(repo, llm.NewFallbackProvider([]llm.Provider{primary, backup}, nil), nil, nil, &config.Config{PromptTema: "persona"})
+	client := joinTestClient(uc, "room", "user-1")
+
+	uc.streamAIReply("room", "Budi", &Message{ID: "m1", RoomID: "room", Content: "hai", Status: StatusUser})
+
+	events := drainEvents(client)
+	var done AIDonePayload
+	for _, env := range events {
+		if env.Type == TypeAIDone {
+			json.Unmarshal(env.Payload, &done)
+		}
+	}
+	if done.Message == nil || done.Message.Model != "cadangan" {
+		t.Fatalf("ai_done message = %+v, want the backup model (events %v)", done.Message, eventTypes(events))
+	}
+	stored, err := repo.GetLatestMessageByStatus(context.Background(), "room", StatusAI)
+	if err != nil || stored.Model != "cadangan" {
+		t.Errorf("stored = %+v (err %v), want the backup model", stored, err)
+	}
+}
@@ -0,0 +1,178 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gemini-cli/portfolio-chat-ai-go/internal/chat"
+)
+
+// RunChatRepository menguji kontrak chat.ChatRepository. newRepo dipanggil sekali per subtest
+// dan harus mengembalikan repository yang kosong.
+func RunChatRepository(t *testing.T, newRepo func(t *testing.T) chat.ChatRepository) {
+	ctx := context.Background()
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(i int) time.Time { return base.Add(time.Duration(i) * time.Second) }
+
+	t.Run("message cursors", func(t *testing.T) {
+		repo := newRepo(t)
+		// "b" dan "c" dibuat pada waktu yang sama; urutannya ditentukan oleh ID.
+		// Pesan disimpan tidak berurutan untuk memastikan repository mengurutkannya sendiri.
+		for _, msg := range []*chat.Message{
+			{ID: "c", CreatedAt: at(1)},
+			{ID: "a", CreatedAt: at(0)},
+			{ID: "e", CreatedAt: at(3)},
+			{ID: "b", CreatedAt: at(1)},
+			{ID: "d", CreatedAt: at(2)},
+			{ID: "x1", RoomID: "other", CreatedAt: at(2)}, // Pesan room lain tidak ikut.
+		} {
+			if msg.RoomID == "" {
+				msg.RoomID = "room"
+			}
+			if err := repo.CreateMessage(ctx, msg); err != nil {
+				t.Fatalf("CreateMessage(%s): %v", msg.ID, err)
+			}
+		}
+
+		tests := []struct {
+			name    string
+			query   chat.MessageQuery
+			want    []string
+			wantErr error
+		}{
+			{name: "no cursor", query: chat.MessageQuery{}, want: []string{"a", "b", "c", "d", "e"}},
+			{name: "no cursor takes newest", query: chat.MessageQuery{Limit: 2}, want: []string{"d", "e"}},
+			{name: "before id", query: chat.MessageQuery{BeforeID: "d"}, want: []string{"a", "b", "c"}},
+			{name: "before id takes closest", query: chat.MessageQuery{BeforeID: "d", Limit: 2}, want: []string{"b", "c"}},
+			{name: "before id on tie", query: chat.MessageQuery{BeforeID: "c"}, want: []string{"a", "b"}},
+			{name: "after id", query: chat.MessageQuery{AfterID: "c"}, want: []string{"d", "e"}},
+			{name: "after id on tie", query: chat.MessageQuery{AfterID: "b"}, want: []string{"c", "d", "e"}},
+			{name: "after id takes closest", query: chat.MessageQuery{AfterID: "a", Limit: 2}, want: []string{"b", "c"}},
+			{name: "after and before id", query: chat.MessageQuery{AfterID: "a", BeforeID: "e"}, want: []string{"b", "c", "d"}},
+			{name: "before time excludes tie", query: chat.MessageQuery{Before: at(1)}, want: []string{"a"}},
+			{name: "after time excludes tie", query: chat.MessageQuery{After: at(1)}, want: []string{"d", "e"}},
+			{name: "after last", query: chat.MessageQuery{AfterID: "e"}, want: []string{}},
+			{name: "unknown before id", query: chat.MessageQuery{BeforeID: "y"}, wantErr: chat.ErrMessageNotFound},
+			{name: "unknown after id", query: chat.MessageQuery{AfterID: "y"}, wantErr: chat.ErrMessageNotFound},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				messages, err := repo.GetMessagesByRoom(ctx, "room", tt.query)
+				if tt.wantErr != nil {
+					if !errors.Is(err, tt.wantErr) {
+						t.Fatalf("err = %v, want %v", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("GetMessagesByRoom: %v", err)
+				}
+				if got := messageIDs(messages); !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("message limit clamp", func(t *testing.T) {
+		repo := newRepo(t)
+		total := chat.MaxMessageLimit + 50
+		for i := 0; i < total; i++ {
+			msg := &chat.Message{ID: fmt.Sprintf("m%04d", i), RoomID: "room", CreatedAt: at(i)}
+			if err := repo.CreateMessage(ctx, msg); err != nil {
+				t.Fatalf("CreateMessage: %v", err)
+			}
+		}
+
+		tests := []struct {
+			name  string
+			limit int
+			want  int
+		}{
+			{name: "zero uses default", limit: 0, want: chat.DefaultMessageLimit},
+			{name: "negative uses default", limit: -5, want: chat.DefaultMessageLimit},
+			{name: "within range", limit: 10, want: 10},
+			{name: "at max", limit: chat.MaxMessageLimit, want: chat.MaxMessageLimit},
+			{name: "above max is clamped", limit: chat.MaxMessageLimit + 1, want: chat.MaxMessageLimit},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				messages, err := repo.GetMessagesByRoom(ctx, "room", chat.MessageQuery{Limit: tt.limit})
+				if err != nil {
+					t.Fatalf("GetMessagesByRoom: %v", err)
+				}
+				if len(messages) != tt.want {
+					t.Fatalf("len = %d, want %d", len(messages), tt.want)
+				}
+				// Tanpa cursor, halaman berisi pesan terbaru.
+				if last := messages[len(messages)-1].ID; last != fmt.Sprintf("m%04d", total-1) {
+					t.Errorf("last message = %s, want the newest", last)
+				}
+			})
+		}
+	})
+
+	t.Run("delete message", func(t *testing.T) {
+		repo := newRepo(t)
+		for i, id := range []string{"a", "b"} {
+			if err := repo.CreateMessage(ctx, &chat.Message{ID: id, RoomID: "room", CreatedAt: at(i)}); err != nil {
+				t.Fatalf("CreateMessage: %v", err)
+			}
+		}
+		if err := repo.DeleteMessage(ctx, "room", "a"); err != nil {
+			t.Fatalf("DeleteMessage: %v", err)
+		}
+		if err := repo.DeleteMessage(ctx, "room", "a"); !errors.Is(err, chat.ErrMessageNotFound) {
+			t.Errorf("second DeleteMessage: err = %v, want ErrMessageNotFound", err)
+		}
+		if err := repo.DeleteMessage(ctx, "other", "b"); !errors.Is(err, chat.ErrMessageNotFound) {
+			t.Errorf("DeleteMessage(other room): err = %v, want ErrMessageNotFound", err)
+		}
+
+		messages, err := repo.GetMessagesByRoom(ctx, "room", chat.MessageQuery{})
+		if err != nil {
+			t.Fatalf("GetMessagesByRoom: %v", err)
+		}
+		if got := messageIDs(messages); !reflect.DeepEqual(got, []string{"b"}) {
+			t.Errorf("got %v, want [b]", got)
+		}
+	})
+
+	t.Run("latest message by status", func(t *testing.T) {
+		repo := newRepo(t)
+		for _, msg := range []*chat.Message{
+			{ID: "a", Status: chat.StatusAI, CreatedAt: at(0)},
+			{ID: "c", Status: chat.StatusUser, CreatedAt: at(2)},
+			{ID: "b", Status: chat.StatusAI, CreatedAt: at(1)},
+		} {
+			msg.RoomID = "room"
+			if err := repo.CreateMessage(ctx, msg); err != nil {
+				t.Fatalf("CreateMessage: %v", err)
+			}
+		}
+
+		latest, err := repo.GetLatestMessageByStatus(ctx, "room", chat.StatusAI)
+		if err != nil {
+			t.Fatalf("GetLatestMessageByStatus: %v", err)
+		}
+		if latest.ID != "b" {
+			t.Errorf("latest = %s, want b", latest.ID)
+		}
+		if _, err := repo.GetLatestMessageByStatus(ctx, "room", chat.StatusSystem); !errors.Is(err, chat.ErrMessageNotFound) {
+			t.Errorf("GetLatestMessageByStatus(system): err = %v, want ErrMessageNotFound", err)
+		}
+	})
+}
+
+// messageIDs mengembalikan ID dari daftar pesan sesuai urutannya.
+func messageIDs(messages []*chat.Message) []string {
+	ids := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		ids = append(ids, msg.ID)
+	}
+	return ids
+}
@@ -0,0 +1,45 @@
+// Package repotest berisi suite uji kontrak untuk repository domain. Satu suite dijalankan terhadap
+// implementasi in-memory maupun MongoDB, sehingga perbedaan perilaku di antara keduanya (urutan, error,
+// operasi atomik) ketahuan oleh test, bukan baru di production.
+package repotest
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/database"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MongoDatabase mengembalikan database MongoDB kosong yang hanya dipakai oleh satu test dan dihapus
+// setelah test selesai. Test dilewati jika MONGO_URI tidak diisi.
+func MongoDatabase(t *testing.T) *mongo.Database {
+	t.Helper()
+	uri := os.Getenv("MONGO_URI")
+	if uri == "" {
+		t.Skip("MONGO_URI is not set")
+	}
+
+	ctx := context.Background()
+	client, err := database.ConnectMongo(ctx, uri)
+	if err != nil {
+		t.Fatalf("failed to connect to MongoDB: %v", err)
+	}
+	db := client.Database("repotest_" + uuid.NewString()[:8])
+	t.Cleanup(func() {
+		if err := db.Drop(ctx); err != nil {
+			t.Errorf("failed to drop test database: %v", err)
+		}
+		database.DisconnectMongo(ctx, client)
+	})
+	return db
+}
+
+// now mengembalikan waktu saat ini dengan presisi milidetik, sama seperti yang disimpan MongoDB,
+// agar waktu yang dibaca kembali bisa dibandingkan dengan yang disimpan.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
@@ -0,0 +1,572 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gemini-cli/portfolio-chat-ai-go/internal/user"
+)
+
+// RunUserRepository menguji kontrak user.UserRepository. newRepo dipanggil sekali per subtest
+// dan harus mengembalikan repository yang kosong.
+func RunUserRepository(t *testing.T, newRepo func(t *testing.T) user.UserRepository) {
+	ctx := context.Background()
+
+	t.Run("create and get", func(t *testing.T) {
+		repo := newRepo(t)
+		created := newUser("u1", "budi@example.com", now())
+		created.Name = "Budi"
+		if err := repo.Create(ctx, created); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+
+		byID, err := repo.GetByID(ctx, "u1")
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		assertSameUser(t, byID, created)
+		byEmail, err := repo.GetByEmail(ctx, "budi@example.com")
+		if err != nil {
+			t.Fatalf("GetByEmail: %v", err)
+		}
+		assertSameUser(t, byEmail, created)
+
+		if _, err := repo.GetByID(ctx, "missing"); !errors.Is(err, user.ErrUserNotFound) {
+			t.Errorf("GetByID(missing): err = %v, want ErrUserNotFound", err)
+		}
+		if _, err := repo.GetByEmail(ctx, "missing@example.com"); !errors.Is(err, user.ErrUserNotFound) {
+			t.Errorf("GetByEmail(missing): err = %v, want ErrUserNotFound", err)
+		}
+	})
+
+	t.Run("unique email", func(t *testing.T) {
+		repo := newRepo(t)
+		if err := repo.Create(ctx, newUser("u1", "budi@example.com", now())); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if err := repo.Create(ctx, newUser("u2", "budi@example.com", now())); !errors.Is(err, user.ErrEmailTaken) {
+			t.Fatalf("Create(duplicate): err = %v, want ErrEmailTaken", err)
+		}
+
+		// Email pengguna yang sudah dihapus tetap tidak bisa dipakai lagi.
+		if err := repo.SoftDelete(ctx, "u1", now()); err != nil {
+			t.Fatalf("SoftDelete: %v", err)
+		}
+		if err := repo.Create(ctx, newUser("u3", "budi@example.com", now())); !errors.Is(err, user.ErrEmailTaken) {
+			t.Fatalf("Create(email of deleted user): err = %v, want ErrEmailTaken", err)
+		}
+	})
+
+	t.Run("update profile and password", func(t *testing.T) {
+		repo := newRepo(t)
+		created := newUser("u1", "budi@example.com", now())
+		if err := repo.Create(ctx, created); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+
+		// Perubahan password lalu profil dari salinan lama: keduanya harus tetap tersimpan.
+		want := *created
+		want.PasswordHash = "hash-baru"
+		want.UpdatedAt = created.UpdatedAt.Add(time.Minute)
+		if err := repo.UpdatePassword(ctx, "u1", want.PasswordHash, want.UpdatedAt); err != nil {
+			t.Fatalf("UpdatePassword: %v", err)
+		}
+		got, err := repo.GetByID(ctx, "u1")
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		assertSameUser(t, got, &want)
+
+		want.Name = "Budi Santoso"
+		want.UpdatedAt = created.UpdatedAt.Add(2 * time.Minute)
+		if err := repo.UpdateProfile(ctx, "u1", want.Name, want.UpdatedAt); err != nil {
+			t.Fatalf("UpdateProfile: %v", err)
+		}
+		got, err = repo.GetByID(ctx, "u1")
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		assertSameUser(t, got, &want)
+
+		if err := repo.UpdateProfile(ctx, "missing", "x", now()); !errors.Is(err, user.ErrUserNotFound) {
+			t.Errorf("UpdateProfile(missing): err = %v, want ErrUserNotFound", err)
+		}
+		if err := repo.UpdatePassword(ctx, "missing", "x", now()); !errors.Is(err, user.ErrUserNotFound) {
+			t.Errorf("UpdatePassword(missing): err = %v, want ErrUserNotFound", err)
+		}
+	})
+
+	t.Run("soft delete hides the user", func(t *testing.T) {
+		repo := newRepo(t)
+		for _, u := range []*user.User{newUser("u1", "budi@example.com", now()), newUser("u2", "sari@example.com", now())} {
+			if err := repo.Create(ctx, u); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+		if err := repo.SoftDelete(ctx, "u1", now()); err != nil {
+			t.Fatalf("SoftDelete: %v", err)
+		}
+
+		if _, err := repo.GetByID(ctx, "u1"); !errors.Is(err, user.ErrUserNotFound) {
+			t.Errorf("GetByID: err = %v, want ErrUserNotFound", err)
+		}
+		if _, err := repo.GetByEmail(ctx, "budi@example.com"); !errors.Is(err, user.ErrUserNotFound) {
+			t.Errorf("GetByEmail: err = %v, want ErrUserNotFound", err)
+		}
+		if err := repo.UpdateProfile(ctx, "u1", "x", now()); !errors.Is(err, user.ErrUserNotFound) {
+			t.Errorf("UpdateProfile: err = %v, want ErrUserNotFound", err)
+		}
+		if err := repo.UpdatePassword(ctx, "u1", "x", now()); !errors.Is(err, user.ErrUserNotFound) {
+			t.Errorf("UpdatePassword: err = %v, want ErrUserNotFound", err)
+		}
+		if err := repo.SoftDelete(ctx, "u1", now()); !errors.Is(err, user.ErrUserNotFound) {
+			t.Errorf("second SoftDelete: err = %v, want ErrUserNotFound", err)
+		}
+
+		page, err := repo.List(ctx, user.UserQuery{})
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if got := userIDs(page.Users); page.Total != 1 || !reflect.DeepEqual(got, []string{"u2"}) {
+			t.Errorf("List = %v (total %d), want [u2] (total 1)", got, page.Total)
+		}
+	})
+
+	t.Run("list sort and pagination", func(t *testing.T) {
+		repo := newRepo(t)
+		base := now()
+		// "u2" dan "u3" dibuat pada waktu yang sama; urutannya ditentukan oleh ID.
+		for _, u := range []*user.User{
+			newUser("u3", "citra@example.com", base.Add(time.Second)),
+			newUser("u1", "dewi@example.com", base),
+			newUser("u4", "andi@example.com", base.Add(2*time.Second)),
+			newUser("u2", "budi@example.com", base.Add(time.Second)),
+			newUser("u5", "admin@contoh.com", base.Add(3*time.Second)),
+		} {
+			if err := repo.Create(ctx, u); err != nil {
+				t.Fatalf("Create(%s): %v", u.ID, err)
+			}
+		}
+
+		tests := []struct {
+			name      string
+			query     user.UserQuery
+			want      []string
+			wantTotal int64
+		}{
+			{name: "default sort by created_at", query: user.UserQuery{}, want: []string{"u1", "u2", "u3", "u4", "u5"}, wantTotal: 5},
+			{name: "created_at descending", query: user.UserQuery{Descending: true}, want: []string{"u5", "u4", "u3", "u2", "u1"}, wantTotal: 5},
+			{name: "sort by email", query: user.UserQuery{SortBy: user.SortByEmail}, want: []string{"u5", "u4", "u2", "u3", "u1"}, wantTotal: 5},
+			{name: "email prefix", query: user.UserQuery{EmailPrefix: "a", SortBy: user.SortByEmail}, want: []string{"u5", "u4"}, wantTotal: 2},
+			{name: "prefix is not a pattern", query: user.UserQuery{EmailPrefix: "."}, want: []string{}, wantTotal: 0},
+			{name: "limit", query: user.UserQuery{Limit: 2}, want: []string{"u1", "u2"}, wantTotal: 5},
+			{name: "limit and offset", query: user.UserQuery{Limit: 2, Offset: 2}, want: []string{"u3", "u4"}, wantTotal: 5},
+			{name: "offset descending", query: user.UserQuery{Descending: true, Limit: 2, Offset: 2}, want: []string{"u3", "u2"}, wantTotal: 5},
+			{name: "offset past the end", query: user.UserQuery{Offset: 10}, want: []string{}, wantTotal: 5},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				page, err := repo.List(ctx, tt.query)
+				if err != nil {
+					t.Fatalf("List: %v", err)
+				}
+				if got := userIDs(page.Users); !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("users = %v, want %v", got, tt.want)
+				}
+				if page.Total != tt.wantTotal {
+					t.Errorf("total = %d, want %d", page.Total, tt.wantTotal)
+				}
+				normalized := tt.query.Normalized()
+				if page.Limit != normalized.Limit || page.Offset != normalized.Offset {
+					t.Errorf("limit/offset = %d/%d, want %d/%d", page.Limit, page.Offset, normalized.Limit, normalized.Offset)
+				}
+			})
+		}
+	})
+}
+
+// RunLoginAttemptRepository menguji kontrak user.LoginAttemptRepository. newRepo dipanggil sekali
+// per subtest dan harus mengembalikan repository yang kosong.
+func RunLoginAttemptRepository(t *testing.T, newRepo func(t *testing.T) user.LoginAttemptRepository) {
+	ctx := context.Background()
+	const key = "account:budi@example.com"
+
+	t.Run("unknown key", func(t *testing.T) {
+		attempt, err := newRepo(t).GetAttempt(ctx, key)
+		if err != nil {
+			t.Fatalf("GetAttempt: %v", err)
+		}
+		if attempt.Key != key || attempt.Failures != 0 || attempt.Lockouts != 0 {
+			t.Errorf("attempt = %+v, want an empty record for %s", attempt, key)
+		}
+	})
+
+	t.Run("record failure counts concurrent failures", func(t *testing.T) {
+		repo := newRepo(t)
+		at := now()
+
+		const failures = 50
+		var wg sync.WaitGroup
+		for i := 0; i < failures; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := repo.RecordFailure(ctx, key, at, at.Add(-time.Hour)); err != nil {
+					t.Errorf("RecordFailure: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		attempt, err := repo.GetAttempt(ctx, key)
+		if err != nil {
+			t.Fatalf("GetAttempt: %v", err)
+		}
+		if attempt.Failures != failures || !attempt.LastFailureAt.Equal(at) {
+			t.Errorf("attempt = %+v, want %d failures at %v", attempt, failures, at)
+		}
+	})
+
+	t.Run("lock once per threshold", func(t *testing.T) {
+		repo := newRepo(t)
+		at := now()
+		for i := 1; i <= 3; i++ {
+			attempt, err := repo.RecordFailure(ctx, key, at, at.Add(-time.Hour))
+			if err != nil {
+				t.Fatalf("RecordFailure: %v", err)
+			}
+			if attempt.Failures != i {
+				t.Fatalf("failures = %d, want %d", attempt.Failures, i)
+			}
+		}
+
+		// Dua request yang sama-sama melihat batas tercapai: hanya yang pertama mengunci.
+		lockedUntil := at.Add(time.Minute)
+		if locked, err := repo.Lock(ctx, key, 3, lockedUntil); err != nil || !locked {
+			t.Fatalf("first Lock = %v, %v; want true", locked, err)
+		}
+		if locked, err := repo.Lock(ctx, key, 3, lockedUntil); err != nil || locked {
+			t.Fatalf("second Lock = %v, %v; want false", locked, err)
+		}
+		if locked, err := repo.Lock(ctx, "ip:198.51.100.1", 1, lockedUntil); err != nil || locked {
+			t.Fatalf("Lock(unknown key) = %v, %v; want false", locked, err)
+		}
+
+		attempt, err := repo.GetAttempt(ctx, key)
+		if err != nil {
+			t.Fatalf("GetAttempt: %v", err)
+		}
+		if attempt.Failures != 0 || attempt.Lockouts != 1 || !attempt.LockedUntil.Equal(lockedUntil) {
+			t.Errorf("attempt = %+v, want 0 failures, 1 lockout until %v", attempt, lockedUntil)
+		}
+
+		// Kegagalan setelah lockout dihitung dari nol, tetapi jumlah lockout tetap diingat.
+		attempt, err = repo.RecordFailure(ctx, key, at.Add(2*time.Minute), at.Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("RecordFailure: %v", err)
+		}
+		if attempt.Failures != 1 || attempt.Lockouts != 1 {
+			t.Errorf("after lockout: failures = %d, lockouts = %d; want 1, 1", attempt.Failures, attempt.Lockouts)
+		}
+	})
+
+	t.Run("record failure resets a stale record", func(t *testing.T) {
+		repo := newRepo(t)
+		at := now()
+		for i := 0; i < 2; i++ {
+			if _, err := repo.RecordFailure(ctx, key, at, at.Add(-time.Hour)); err != nil {
+				t.Fatalf("RecordFailure: %v", err)
+			}
+		}
+		if _, err := repo.Lock(ctx, key, 2, at.Add(time.Minute)); err != nil {
+			t.Fatalf("Lock: %v", err)
+		}
+		if _, err := repo.RecordFailure(ctx, key, at, at.Add(-time.Hour)); err != nil {
+			t.Fatalf("RecordFailure: %v", err)
+		}
+
+		// Lockout masih lebih baru dari resetBefore: catatan dilanjutkan.
+		attempt, err := repo.RecordFailure(ctx, key, at.Add(30*time.Second), at.Add(30*time.Second))
+		if err != nil {
+			t.Fatalf("RecordFailure: %v", err)
+		}
+		if attempt.Failures != 2 || attempt.Lockouts != 1 {
+			t.Errorf("recent record: failures = %d, lockouts = %d; want 2, 1", attempt.Failures, attempt.Lockouts)
+		}
+
+		// Kegagalan terakhir dan akhir lockout sudah sebelum resetBefore: catatan dimulai ulang.
+		later := at.Add(2 * time.Hour)
+		attempt, err = repo.RecordFailure(ctx, key, later, later.Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("RecordFailure: %v", err)
+		}
+		if attempt.Failures != 1 || attempt.Lockouts != 0 || !attempt.LastFailureAt.Equal(later) {
+			t.Errorf("stale record: %+v, want 1 failure, 0 lockouts at %v", attempt, later)
+		}
+	})
+
+	t.Run("delete attempt", func(t *testing.T) {
+		repo := newRepo(t)
+		at := now()
+		if _, err := repo.RecordFailure(ctx, key, at, at.Add(-time.Hour)); err != nil {
+			t.Fatalf("RecordFailure: %v", err)
+		}
+		if err := repo.DeleteAttempt(ctx, key); err != nil {
+			t.Fatalf("DeleteAttempt: %v", err)
+		}
+		if err := repo.DeleteAttempt(ctx, key); err != nil {
+			t.Fatalf("DeleteAttempt(missing): %v", err)
+		}
+		attempt, err := repo.GetAttempt(ctx, key)
+		if err != nil {
+			t.Fatalf("GetAttempt: %v", err)
+		}
+		if attempt.Failures != 0 {
+			t.Errorf("failures = %d after delete, want 0", attempt.Failures)
+		}
+	})
+
+	t.Run("create lockout event", func(t *testing.T) {
+		event := &user.LockoutEvent{ID: "e1", Scope: user.LoginScopeAccount, Key: key, Failures: 3, Lockouts: 1, CreatedAt: now()}
+		if err := newRepo(t).CreateLockoutEvent(ctx, event); err != nil {
+			t.Fatalf("CreateLockoutEvent: %v", err)
+		}
+	})
+}
+
+// RunTokenRepository menguji kontrak user.TokenRepository. newRepo dipanggil sekali per subtest
+// dan harus mengembalikan repository yang kosong.
+func RunTokenRepository(t *testing.T, newRepo func(t *testing.T) user.TokenRepository) {
+	ctx := context.Background()
+
+	t.Run("create and get refresh token", func(t *testing.T) {
+		repo := newRepo(t)
+		token := newRefreshToken("h1", "u1", "s1", now())
+		if err := repo.CreateRefreshToken(ctx, token); err != nil {
+			t.Fatalf("CreateRefreshToken: %v", err)
+		}
+		got, err := repo.GetRefreshToken(ctx, "h1")
+		if err != nil {
+			t.Fatalf("GetRefreshToken: %v", err)
+		}
+		if got.UserID != "u1" || got.SessionID != "s1" || got.AccessTokenID != token.AccessTokenID ||
+			!got.ExpiresAt.Equal(token.ExpiresAt) || !got.UsedAt.IsZero() || !got.RevokedAt.IsZero() {
+			t.Errorf("token = %+v, want %+v", got, token)
+		}
+		if _, err := repo.GetRefreshToken(ctx, "missing"); !errors.Is(err, user.ErrRefreshTokenNotFound) {
+			t.Errorf("GetRefreshToken(missing): err = %v, want ErrRefreshTokenNotFound", err)
+		}
+	})
+
+	t.Run("use refresh token returns the state before use", func(t *testing.T) {
+		repo := newRepo(t)
+		if err := repo.CreateRefreshToken(ctx, newRefreshToken("h1", "u1", "s1", now())); err != nil {
+			t.Fatalf("CreateRefreshToken: %v", err)
+		}
+
+		first := now()
+		before, err := repo.UseRefreshToken(ctx, "h1", first)
+		if err != nil {
+			t.Fatalf("UseRefreshToken: %v", err)
+		}
+		if !before.UsedAt.IsZero() {
+			t.Errorf("first use: UsedAt = %v, want zero", before.UsedAt)
+		}
+
+		// Pemakaian ulang terdeteksi dari UsedAt, dan waktu pemakaian pertama tidak ditimpa.
+		for _, at := range []time.Time{first.Add(time.Minute), first.Add(-time.Minute)} {
+			before, err = repo.UseRefreshToken(ctx, "h1", at)
+			if err != nil {
+				t.Fatalf("UseRefreshToken: %v", err)
+			}
+			if !before.UsedAt.Equal(first) {
+				t.Errorf("reuse at %v: UsedAt = %v, want %v", at, before.UsedAt, first)
+			}
+		}
+
+		stored, err := repo.GetRefreshToken(ctx, "h1")
+		if err != nil {
+			t.Fatalf("GetRefreshToken: %v", err)
+		}
+		if !stored.UsedAt.Equal(first) {
+			t.Errorf("stored UsedAt = %v, want %v", stored.UsedAt, first)
+		}
+
+		if _, err := repo.UseRefreshToken(ctx, "missing", first); !errors.Is(err, user.ErrRefreshTokenNotFound) {
+			t.Errorf("UseRefreshToken(missing): err = %v, want ErrRefreshTokenNotFound", err)
+		}
+	})
+
+	t.Run("use refresh token concurrently", func(t *testing.T) {
+		repo := newRepo(t)
+		if err := repo.CreateRefreshToken(ctx, newRefreshToken("h1", "u1", "s1", now())); err != nil {
+			t.Fatalf("CreateRefreshToken: %v", err)
+		}
+
+		const callers = 20
+		var (
+			wg     sync.WaitGroup
+			mu     sync.Mutex
+			unused int
+		)
+		for i := 0; i < callers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				before, err := repo.UseRefreshToken(ctx, "h1", now())
+				if err != nil {
+					t.Errorf("UseRefreshToken: %v", err)
+					return
+				}
+				if before.UsedAt.IsZero() {
+					mu.Lock()
+					unused++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		if unused != 1 {
+			t.Errorf("%d callers saw an unused token, want exactly 1", unused)
+		}
+	})
+
+	t.Run("revoke session", func(t *testing.T) {
+		repo := newRepo(t)
+		at := now()
+		for _, token := range []*user.RefreshToken{
+			newRefreshToken("h1", "u1", "s1", at),
+			newRefreshToken("h2", "u1", "s1", at),
+			newRefreshToken("h3", "u1", "s2", at),
+		} {
+			if err := repo.CreateRefreshToken(ctx, token); err != nil {
+				t.Fatalf("CreateRefreshToken: %v", err)
+			}
+		}
+
+		revoked, err := repo.RevokeSession(ctx, "s1", at)
+		if err != nil {
+			t.Fatalf("RevokeSession: %v", err)
+		}
+		if got := tokenHashes(revoked); !reflect.DeepEqual(got, []string{"h1", "h2"}) {
+			t.Errorf("revoked = %v, want [h1 h2]", got)
+		}
+		assertRevoked(t, repo, map[string]bool{"h1": true, "h2": true, "h3": false})
+
+		// Token yang sudah dicabut tidak dikembalikan lagi.
+		if revoked, err = repo.RevokeSession(ctx, "s1", at); err != nil || len(revoked) != 0 {
+			t.Errorf("second RevokeSession = %v, %v; want no tokens", tokenHashes(revoked), err)
+		}
+	})
+
+	t.Run("revoke user sessions", func(t *testing.T) {
+		repo := newRepo(t)
+		at := now()
+		for _, token := range []*user.RefreshToken{
+			newRefreshToken("h1", "u1", "s1", at),
+			newRefreshToken("h2", "u1", "s2", at),
+			newRefreshToken("h3", "u2", "s3", at),
+		} {
+			if err := repo.CreateRefreshToken(ctx, token); err != nil {
+				t.Fatalf("CreateRefreshToken: %v", err)
+			}
+		}
+		if _, err := repo.RevokeSession(ctx, "s1", at); err != nil {
+			t.Fatalf("RevokeSession: %v", err)
+		}
+
+		revoked, err := repo.RevokeUserSessions(ctx, "u1", at)
+		if err != nil {
+			t.Fatalf("RevokeUserSessions: %v", err)
+		}
+		if got := tokenHashes(revoked); !reflect.DeepEqual(got, []string{"h2"}) {
+			t.Errorf("revoked = %v, want [h2]", got)
+		}
+		assertRevoked(t, repo, map[string]bool{"h1": true, "h2": true, "h3": false})
+	})
+
+	t.Run("revoke access token", func(t *testing.T) {
+		repo := newRepo(t)
+		token := &user.RevokedToken{ID: "jti-1", UserID: "u1", RevokedAt: now(), ExpiresAt: now().Add(time.Minute)}
+		for i := 0; i < 2; i++ {
+			if err := repo.RevokeAccessToken(ctx, token); err != nil {
+				t.Fatalf("RevokeAccessToken #%d: %v", i+1, err)
+			}
+		}
+		for jti, want := range map[string]bool{"jti-1": true, "jti-2": false} {
+			revoked, err := repo.IsAccessTokenRevoked(ctx, jti)
+			if err != nil {
+				t.Fatalf("IsAccessTokenRevoked: %v", err)
+			}
+			if revoked != want {
+				t.Errorf("IsAccessTokenRevoked(%s) = %v, want %v", jti, revoked, want)
+			}
+		}
+	})
+}
+
+// newUser membuat pengguna uji yang dibuat dan terakhir diubah pada at.
+func newUser(id, email string, at time.Time) *user.User {
+	return &user.User{ID: id, Email: email, PasswordHash: "hash-" + id, Roles: []string{"user"}, CreatedAt: at, UpdatedAt: at}
+}
+
+// newRefreshToken membuat refresh token uji yang belum dipakai dan belum dicabut.
+func newRefreshToken(hash, userID, sessionID string, at time.Time) *user.RefreshToken {
+	return &user.RefreshToken{
+		Hash:            hash,
+		UserID:          userID,
+		SessionID:       sessionID,
+		AccessTokenID:   "jti-" + hash,
+		AccessExpiresAt: at.Add(15 * time.Minute),
+		CreatedAt:       at,
+		ExpiresAt:       at.Add(24 * time.Hour),
+	}
+}
+
+// assertSameUser membandingkan field pengguna yang disimpan oleh repository.
+func assertSameUser(t *testing.T, got, want *user.User) {
+	t.Helper()
+	if got.ID != want.ID || got.Email != want.Email || got.Name != want.Name || got.PasswordHash != want.PasswordHash ||
+		!reflect.DeepEqual(got.Roles, want.Roles) || !got.CreatedAt.Equal(want.CreatedAt) ||
+		!got.UpdatedAt.Equal(want.UpdatedAt) || got.DeletedAt != nil {
+		t.Errorf("user = %+v, want %+v", got, want)
+	}
+}
+
+// assertRevoked memeriksa status pencabutan setiap refresh token berdasarkan hash-nya.
+func assertRevoked(t *testing.T, repo user.TokenRepository, want map[string]bool) {
+	t.Helper()
+	for hash, wantRevoked := range want {
+		token, err := repo.GetRefreshToken(context.Background(), hash)
+		if err != nil {
+			t.Fatalf("GetRefreshToken(%s): %v", hash, err)
+		}
+		if revoked := !token.RevokedAt.IsZero(); revoked != wantRevoked {
+			t.Errorf("%s revoked = %v, want %v", hash, revoked, wantRevoked)
+		}
+	}
+}
+
+// userIDs mengembalikan ID dari daftar pengguna sesuai urutannya.
+func userIDs(users []*user.User) []string {
+	ids := make([]string, 0, len(users))
+	for _, u := range users {
+		ids = append(ids, u.ID)
+	}
+	return ids
+}
+
+// tokenHashes mengembalikan hash dari daftar refresh token, diurutkan agar bisa dibandingkan.
+func tokenHashes(tokens []*user.RefreshToken) []string {
+	hashes := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		hashes = append(hashes, token.Hash)
+	}
+	sort.Strings(hashes)
+	return hashes
+}
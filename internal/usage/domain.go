@@ -0,0 +1,84 @@
+// Package usage berisi logika pencatatan pemakaian token AI dan laporan perkiraan biayanya.
+package usage
+
+import (
+	"context"
+	"time"
+)
+
+// Scope penghitung pemakaian. Setiap panggilan AI dihitung sekali untuk pengguna dan sekali untuk room.
+const (
+	ScopeUser = "user"
+	ScopeRoom = "room"
+)
+
+// Record adalah pemakaian token dari satu panggilan AI.
+type Record struct {
+	UserID          string // Pengguna yang memicu panggilan AI.
+	RoomID          string
+	Model           string
+	PromptTokens    int
+	CandidateTokens int
+	TotalTokens     int
+	At              time.Time
+}
+
+// Counter adalah akumulasi pemakaian untuk satu scope (pengguna atau room), satu hari (UTC) dan satu model.
+// Tag `bson` digunakan oleh driver MongoDB untuk memetakan struct ke dokumen di koleksi `usage_counters`.
+type Counter struct {
+	ID              string    `bson:"_id"` // Gabungan scope, scope_id, hari dan model; lihat counterID.
+	Scope           string    `bson:"scope"`
+	ScopeID         string    `bson:"scope_id"`
+	Day             time.Time `bson:"day"` // Awal hari dalam UTC.
+	Model           string    `bson:"model"`
+	Requests        int64     `bson:"requests"`
+	PromptTokens    int64     `bson:"prompt_tokens"`
+	CandidateTokens int64     `bson:"candidate_tokens"`
+	TotalTokens     int64     `bson:"total_tokens"`
+}
+
+// Totals adalah jumlah pemakaian beserta perkiraan biayanya.
+type Totals struct {
+	Requests        int64   `json:"requests"`
+	PromptTokens    int64   `json:"prompt_tokens"`
+	CandidateTokens int64   `json:"candidate_tokens"`
+	TotalTokens     int64   `json:"total_tokens"`
+	EstimatedCost   float64 `json:"estimated_cost"`
+}
+
+// ModelTotals adalah pemakaian untuk satu model.
+type ModelTotals struct {
+	Model string `json:"model"`
+	Totals
+}
+
+// ScopeTotals adalah pemakaian untuk satu pengguna atau satu room.
+type ScopeTotals struct {
+	ID string `json:"id"`
+	Totals
+}
+
+// Report adalah laporan pemakaian dalam rentang tanggal (inklusif, UTC).
+type Report struct {
+	From           time.Time     `json:"from"`
+	To             time.Time     `json:"to"`
+	Total          Totals        `json:"total"`
+	Models         []ModelTotals `json:"models"`
+	Users          []ScopeTotals `json:"users"`
+	Rooms          []ScopeTotals `json:"rooms"`
+	UnpricedModels []string      `json:"unpriced_models,omitempty"` // Model tanpa harga di config; biayanya dihitung 0.
+}
+
+// UsageRepository mendefinisikan kontrak (interface) untuk penyimpanan penghitung pemakaian.
+type UsageRepository interface {
+	// Increment menambahkan nilai counter ke penghitung dengan ID yang sama, atau membuatnya jika belum ada.
+	Increment(ctx context.Context, counter Counter) error
+	// List mengembalikan semua penghitung dengan Day di antara from dan to (inklusif).
+	List(ctx context.Context, from, to time.Time) ([]Counter, error)
+}
+
+// UsageUsecase mendefinisikan kontrak (interface) untuk lapisan logika bisnis pemakaian.
+type UsageUsecase interface {
+	Record(ctx context.Context, record Record) error
+	Report(ctx context.Context, from, to time.Time) (*Report, error)
+}
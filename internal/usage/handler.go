@@ -0,0 +1,68 @@
+// Package usage (lapisan handler) bertanggung jawab untuk menangani request laporan pemakaian.
+package usage
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/apperror"
+	"github.com/labstack/echo/v4"
+)
+
+// Panjang rentang laporan dalam hari: default jika `from` tidak diisi, dan batas maksimal agar satu
+// request tidak membaca seluruh riwayat penghitung.
+const (
+	defaultReportDays = 30
+	maxReportDays     = 366
+)
+
+// UsageHandler adalah struct yang menangani request HTTP untuk domain Usage.
+// Dependensi: bergantung pada UsageUsecase (kontrak lapisan bisnis).
+type UsageHandler struct {
+	usageUsecase UsageUsecase
+}
+
+// NewUsageHandler membuat instance baru dari UsageHandler.
+func NewUsageHandler(usageUsecase UsageUsecase) *UsageHandler {
+	return &UsageHandler{usageUsecase: usageUsecase}
+}
+
+// Report menangani request laporan pemakaian token dan perkiraan biaya (GET /v1/admin/usage?from=...&to=...).
+// `from` dan `to` berformat YYYY-MM-DD (UTC, inklusif); defaultnya adalah 30 hari terakhir sampai hari ini,
+// dan rentangnya paling panjang maxReportDays hari.
+// Endpoint ini diproteksi oleh JWT Auth dan hanya untuk admin.
+func (h *UsageHandler) Report(c echo.Context) error {
+	// Mengambil rentang tanggal dari query param.
+	to := time.Now().UTC()
+	if raw := c.QueryParam("to"); raw != "" {
+		parsed, err := time.Parse(dayLayout, raw)
+		if err != nil {
+			return apperror.Validation("invalid_query", "parameter to harus berformat YYYY-MM-DD")
+		}
+		to = parsed
+	}
+	from := to.AddDate(0, 0, -(defaultReportDays - 1))
+	if raw := c.QueryParam("from"); raw != "" {
+		parsed, err := time.Parse(dayLayout, raw)
+		if err != nil {
+			return apperror.Validation("invalid_query", "parameter from harus berformat YYYY-MM-DD")
+		}
+		from = parsed
+	}
+	if to.Before(from) {
+		return apperror.Validation("invalid_query", "parameter from tidak boleh setelah to")
+	}
+	if to.Sub(from) >= maxReportDays*24*time.Hour {
+		return apperror.Validation("invalid_query", fmt.Sprintf("rentang laporan maksimal %d hari", maxReportDays))
+	}
+
+	// Memanggil use case untuk menyusun laporan. Error database hanya dicatat di log oleh
+	// apperror.HTTPErrorHandler dan tidak dikirim ke client.
+	report, err := h.usageUsecase.Report(c.Request().Context(), from, to)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,51 @@
+package usage
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/apperror"
+	"github.com/labstack/echo/v4"
+)
+
+func TestReportHandlerDateRange(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = apperror.HTTPErrorHandler
+	e.GET("/v1/admin/usage", NewUsageHandler(NewUsageUsecase(NewInMemoryUsageRepository(), nil)).Report)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+	}{
+		{name: "default range", query: "", wantStatus: http.StatusOK},
+		{name: "single day", query: "?from=2026-03-02&to=2026-03-02", wantStatus: http.StatusOK},
+		{name: "maximum span", query: "?from=2025-01-01&to=2026-01-01", wantStatus: http.StatusOK},
+		{name: "span too long", query: "?from=2025-01-01&to=2026-01-02", wantStatus: http.StatusBadRequest},
+		{name: "from after to", query: "?from=2026-03-03&to=2026-03-02", wantStatus: http.StatusBadRequest},
+		{name: "invalid from", query: "?from=02-03-2026", wantStatus: http.StatusBadRequest},
+		{name: "invalid to", query: "?to=2026-3-2", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/admin/usage"+tt.query, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusBadRequest {
+				return
+			}
+			var body struct {
+				Code string `json:"code"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Code != "invalid_query" {
+				t.Errorf("body = %s, want code invalid_query", rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package usage
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// InMemoryUsageRepository adalah implementasi UsageRepository yang menyimpan penghitung di memori.
+// Berguna untuk development dan pengujian tanpa MongoDB.
+type InMemoryUsageRepository struct {
+	mu       sync.RWMutex
+	counters map[string]Counter // Kunci adalah Counter.ID.
+}
+
+// NewInMemoryUsageRepository membuat instance baru dari InMemoryUsageRepository.
+func NewInMemoryUsageRepository() *InMemoryUsageRepository {
+	return &InMemoryUsageRepository{
+		counters: make(map[string]Counter),
+	}
+}
+
+// Increment menambahkan nilai counter ke penghitung dengan ID yang sama, atau menyimpannya jika belum ada.
+func (r *InMemoryUsageRepository) Increment(ctx context.Context, counter Counter) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.counters[counter.ID]
+	if !ok {
+		r.counters[counter.ID] = counter
+		return nil
+	}
+	existing.Requests += counter.Requests
+	existing.PromptTokens += counter.PromptTokens
+	existing.CandidateTokens += counter.CandidateTokens
+	existing.TotalTokens += counter.TotalTokens
+	r.counters[counter.ID] = existing
+	return nil
+}
+
+// List mengembalikan semua penghitung dengan Day di antara from dan to (inklusif).
+func (r *InMemoryUsageRepository) List(ctx context.Context, from, to time.Time) ([]Counter, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counters := []Counter{}
+	for _, counter := range r.counters {
+		if counter.Day.Before(from) || counter.Day.After(to) {
+			continue
+		}
+		counters = append(counters, counter)
+	}
+	return counters, nil
+}
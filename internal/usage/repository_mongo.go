@@ -0,0 +1,71 @@
+package usage
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoUsageRepository adalah implementasi dari UsageRepository yang menggunakan MongoDB sebagai penyimpanannya.
+// Dependensi: bergantung pada koneksi database MongoDB (*mongo.Database).
+type MongoUsageRepository struct {
+	db         *mongo.Database // Koneksi ke database spesifik di MongoDB.
+	collection string          // Nama koleksi untuk menyimpan penghitung, yaitu "usage_counters".
+}
+
+// NewMongoUsageRepository membuat instance baru dari MongoUsageRepository.
+func NewMongoUsageRepository(db *mongo.Database) *MongoUsageRepository {
+	return &MongoUsageRepository{
+		db:         db,
+		collection: "usage_counters",
+	}
+}
+
+// EnsureIndexes membuat index `day` yang dipakai List untuk filter rentang tanggal.
+// Aman dipanggil berulang kali saat startup.
+func (r *MongoUsageRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.db.Collection(r.collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "day", Value: 1}},
+		Options: options.Index().SetName("day"),
+	})
+	return err
+}
+
+// Increment menambahkan nilai counter secara atomik dengan `$inc`, dan membuat dokumen jika belum ada.
+func (r *MongoUsageRepository) Increment(ctx context.Context, counter Counter) error {
+	update := bson.M{
+		"$setOnInsert": bson.M{
+			"scope":    counter.Scope,
+			"scope_id": counter.ScopeID,
+			"day":      counter.Day,
+			"model":    counter.Model,
+		},
+		"$inc": bson.M{
+			"requests":         counter.Requests,
+			"prompt_tokens":    counter.PromptTokens,
+			"candidate_tokens": counter.CandidateTokens,
+			"total_tokens":     counter.TotalTokens,
+		},
+	}
+	_, err := r.db.Collection(r.collection).UpdateByID(ctx, counter.ID, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// List mengembalikan semua penghitung dengan `day` di antara from dan to (inklusif).
+func (r *MongoUsageRepository) List(ctx context.Context, from, to time.Time) ([]Counter, error) {
+	filter := bson.M{"day": bson.M{"$gte": from, "$lte": to}}
+	cursor, err := r.db.Collection(r.collection).Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	counters := []Counter{}
+	if err := cursor.All(ctx, &counters); err != nil {
+		return nil, err
+	}
+	return counters, nil
+}
@@ -0,0 +1,163 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/config"
+)
+
+// UsageUsecaseImpl adalah implementasi dari UsageUsecase.
+// Dependensi: bergantung pada UsageRepository dan daftar harga per model.
+type UsageUsecaseImpl struct {
+	usageRepo UsageRepository
+	prices    map[string]config.ModelPrice // Kunci adalah nama model.
+}
+
+// NewUsageUsecase membuat instance baru dari UsageUsecaseImpl.
+func NewUsageUsecase(usageRepo UsageRepository, prices map[string]config.ModelPrice) *UsageUsecaseImpl {
+	return &UsageUsecaseImpl{
+		usageRepo: usageRepo,
+		prices:    prices,
+	}
+}
+
+// Record menambahkan pemakaian satu panggilan AI ke penghitung harian pengguna dan room.
+func (uc *UsageUsecaseImpl) Record(ctx context.Context, record Record) error {
+	if record.At.IsZero() {
+		record.At = time.Now()
+	}
+	day := startOfDay(record.At)
+
+	scopes := []struct{ scope, id string }{
+		{ScopeUser, record.UserID},
+		{ScopeRoom, record.RoomID},
+	}
+	for _, s := range scopes {
+		if s.id == "" {
+			continue
+		}
+		counter := Counter{
+			ID:              counterID(s.scope, s.id, day, record.Model),
+			Scope:           s.scope,
+			ScopeID:         s.id,
+			Day:             day,
+			Model:           record.Model,
+			Requests:        1,
+			PromptTokens:    int64(record.PromptTokens),
+			CandidateTokens: int64(record.CandidateTokens),
+			TotalTokens:     int64(record.TotalTokens),
+		}
+		if err := uc.usageRepo.Increment(ctx, counter); err != nil {
+			return fmt.Errorf("tidak bisa mencatat pemakaian %s %s: %w", s.scope, s.id, err)
+		}
+	}
+	return nil
+}
+
+// Report menyusun laporan pemakaian dan perkiraan biaya untuk rentang tanggal from..to (inklusif).
+func (uc *UsageUsecaseImpl) Report(ctx context.Context, from, to time.Time) (*Report, error) {
+	from, to = startOfDay(from), startOfDay(to)
+	if to.Before(from) {
+		return nil, fmt.Errorf("rentang tanggal tidak valid: %s setelah %s", from.Format(dayLayout), to.Format(dayLayout))
+	}
+
+	counters, err := uc.usageRepo.List(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("tidak bisa mengambil data pemakaian: %w", err)
+	}
+
+	// 1. Jumlahkan setiap penghitung ke total per model, per pengguna dan per room.
+	//    Total keseluruhan dan per model hanya dihitung dari scope pengguna agar tidak terhitung dua kali.
+	report := &Report{From: from, To: to}
+	models := map[string]*Totals{}
+	users := map[string]*Totals{}
+	rooms := map[string]*Totals{}
+	unpriced := map[string]bool{}
+	for _, counter := range counters {
+		cost, ok := uc.cost(counter)
+		if !ok {
+			unpriced[counter.Model] = true
+		}
+		switch counter.Scope {
+		case ScopeUser:
+			add(&report.Total, counter, cost)
+			add(totalsFor(models, counter.Model), counter, cost)
+			add(totalsFor(users, counter.ScopeID), counter, cost)
+		case ScopeRoom:
+			add(totalsFor(rooms, counter.ScopeID), counter, cost)
+		}
+	}
+
+	// 2. Ubah map menjadi slice yang terurut agar respons stabil.
+	for model, totals := range models {
+		report.Models = append(report.Models, ModelTotals{Model: model, Totals: *totals})
+	}
+	sort.Slice(report.Models, func(i, j int) bool { return report.Models[i].Model < report.Models[j].Model })
+	report.Users = sortedScopes(users)
+	report.Rooms = sortedScopes(rooms)
+	for model := range unpriced {
+		report.UnpricedModels = append(report.UnpricedModels, model)
+	}
+	sort.Strings(report.UnpricedModels)
+
+	return report, nil
+}
+
+// cost menghitung perkiraan biaya sebuah penghitung. Mengembalikan false jika model tidak memiliki harga.
+func (uc *UsageUsecaseImpl) cost(counter Counter) (float64, bool) {
+	price, ok := uc.prices[counter.Model]
+	if !ok {
+		return 0, false
+	}
+	return float64(counter.PromptTokens)/1e6*price.InputPerMillion +
+		float64(counter.CandidateTokens)/1e6*price.OutputPerMillion, true
+}
+
+// dayLayout adalah format tanggal untuk rentang laporan dan ID penghitung.
+const dayLayout = "2006-01-02"
+
+// startOfDay mengembalikan awal hari (UTC) dari t.
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// counterID membuat ID penghitung yang unik untuk kombinasi scope, scope_id, hari dan model.
+func counterID(scope, scopeID string, day time.Time, model string) string {
+	return fmt.Sprintf("%s:%s:%s:%s", scope, scopeID, day.Format(dayLayout), model)
+}
+
+// totalsFor mengembalikan Totals untuk key di map, dan membuatnya jika belum ada.
+func totalsFor(m map[string]*Totals, key string) *Totals {
+	if _, ok := m[key]; !ok {
+		m[key] = &Totals{}
+	}
+	return m[key]
+}
+
+// add menambahkan nilai penghitung dan biayanya ke totals.
+func add(totals *Totals, counter Counter, cost float64) {
+	totals.Requests += counter.Requests
+	totals.PromptTokens += counter.PromptTokens
+	totals.CandidateTokens += counter.CandidateTokens
+	totals.TotalTokens += counter.TotalTokens
+	totals.EstimatedCost += cost
+}
+
+// sortedScopes mengubah map total per ID menjadi slice, diurutkan dari pemakaian token terbesar.
+func sortedScopes(m map[string]*Totals) []ScopeTotals {
+	scopes := make([]ScopeTotals, 0, len(m))
+	for id, totals := range m {
+		scopes = append(scopes, ScopeTotals{ID: id, Totals: *totals})
+	}
+	sort.Slice(scopes, func(i, j int) bool {
+		if scopes[i].TotalTokens != scopes[j].TotalTokens {
+			return scopes[i].TotalTokens > scopes[j].TotalTokens
+		}
+		return scopes[i].ID < scopes[j].ID
+	})
+	return scopes
+}
@@ -0,0 +1,156 @@
+package usage
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/config"
+)
+
+// day mengembalikan waktu pada tanggal tertentu bulan Maret 2026 (UTC), pukul 10:00.
+func day(d int) time.Time {
+	return time.Date(2026, 3, d, 10, 0, 0, 0, time.UTC)
+}
+
+// newReportUsecase membuat usecase dengan repository in-memory yang sudah berisi records.
+func newReportUsecase(t *testing.T, prices map[string]config.ModelPrice, records []Record) *UsageUsecaseImpl {
+	t.Helper()
+	uc := NewUsageUsecase(NewInMemoryUsageRepository(), prices)
+	for _, record := range records {
+		if err := uc.Record(context.Background(), record); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+	return uc
+}
+
+func TestReportAggregatesDateRange(t *testing.T) {
+	prices := map[string]config.ModelPrice{"gemini": {InputPerMillion: 1, OutputPerMillion: 4}}
+	uc := newReportUsecase(t, prices, []Record{
+		{UserID: "u1", RoomID: "r1", Model: "gemini", PromptTokens: 1000, CandidateTokens: 500, TotalTokens: 1500, At: day(1)}, // Sebelum rentang.
+		{UserID: "u1", RoomID: "r1", Model: "gemini", PromptTokens: 100, CandidateTokens: 50, TotalTokens: 150, At: day(2)},
+		{UserID: "u1", RoomID: "r1", Model: "gemini", PromptTokens: 200, CandidateTokens: 100, TotalTokens: 300, At: day(3)},
+		{UserID: "u2", RoomID: "r1", Model: "gemini", PromptTokens: 300, CandidateTokens: 300, TotalTokens: 600, At: day(4).Add(13 * time.Hour)}, // Akhir hari terakhir.
+		{UserID: "u2", RoomID: "r1", Model: "gemini", PromptTokens: 1000, CandidateTokens: 1000, TotalTokens: 2000, At: day(5)},                  // Setelah rentang.
+	})
+
+	// Jam pada from dan to diabaikan: rentang mencakup seluruh hari tanggal 2 sampai 4.
+	report, err := uc.Report(context.Background(), day(2).Add(5*time.Hour), day(4))
+	if err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+
+	if !report.From.Equal(time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)) || !report.To.Equal(time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("range = %s..%s, want the whole days 2026-03-02..2026-03-04", report.From, report.To)
+	}
+	// Biaya: (600 input * $1 + 450 output * $4) / 1M.
+	wantTotal := Totals{Requests: 3, PromptTokens: 600, CandidateTokens: 450, TotalTokens: 1050, EstimatedCost: 0.0024}
+	assertTotals(t, "total", report.Total, wantTotal)
+	if len(report.Models) != 1 || report.Models[0].Model != "gemini" {
+		t.Fatalf("models = %+v, want only gemini", report.Models)
+	}
+	assertTotals(t, "gemini", report.Models[0].Totals, wantTotal)
+
+	// Pengguna diurutkan dari pemakaian token terbesar.
+	if got := scopeIDs(report.Users); !reflect.DeepEqual(got, []string{"u2", "u1"}) {
+		t.Errorf("users = %v, want [u2 u1]", got)
+	}
+	assertTotals(t, "u1", report.Users[1].Totals, Totals{Requests: 2, PromptTokens: 300, CandidateTokens: 150, TotalTokens: 450, EstimatedCost: 0.0009})
+	assertTotals(t, "u2", report.Users[0].Totals, Totals{Requests: 1, PromptTokens: 300, CandidateTokens: 300, TotalTokens: 600, EstimatedCost: 0.0015})
+	if len(report.UnpricedModels) != 0 {
+		t.Errorf("unpriced models = %v, want none", report.UnpricedModels)
+	}
+
+	if _, err := uc.Report(context.Background(), day(4), day(2)); err == nil {
+		t.Error("Report with from after to: expected an error")
+	}
+}
+
+func TestReportDoesNotDoubleCountRooms(t *testing.T) {
+	uc := newReportUsecase(t, map[string]config.ModelPrice{"gemini": {InputPerMillion: 1, OutputPerMillion: 1}}, []Record{
+		{UserID: "u1", RoomID: "r1", Model: "gemini", PromptTokens: 10, CandidateTokens: 10, TotalTokens: 20, At: day(2)},
+		{UserID: "u1", RoomID: "r2", Model: "gemini", PromptTokens: 30, CandidateTokens: 10, TotalTokens: 40, At: day(2)},
+		{UserID: "u2", RoomID: "r1", Model: "gemini", PromptTokens: 5, CandidateTokens: 5, TotalTokens: 10, At: day(2)},
+		// Balasan tanpa pengguna (misal: sambutan room) hanya tercatat di room dan tidak masuk total.
+		{RoomID: "r1", Model: "gemini", PromptTokens: 100, CandidateTokens: 100, TotalTokens: 200, At: day(2)},
+	})
+
+	report, err := uc.Report(context.Background(), day(2), day(2))
+	if err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+
+	// Setiap panggilan tercatat di scope pengguna dan room, tetapi total hanya dihitung sekali.
+	if report.Total.Requests != 3 || report.Total.TotalTokens != 70 {
+		t.Errorf("total = %+v, want 3 requests and 70 tokens", report.Total)
+	}
+	if report.Models[0].Requests != 3 || report.Models[0].TotalTokens != 70 {
+		t.Errorf("model totals = %+v, want 3 requests and 70 tokens", report.Models[0].Totals)
+	}
+
+	var userTokens, roomTokens int64
+	for _, u := range report.Users {
+		userTokens += u.TotalTokens
+	}
+	for _, r := range report.Rooms {
+		roomTokens += r.TotalTokens
+	}
+	if userTokens != report.Total.TotalTokens {
+		t.Errorf("sum of users = %d, want the total %d", userTokens, report.Total.TotalTokens)
+	}
+	if got := scopeIDs(report.Rooms); !reflect.DeepEqual(got, []string{"r1", "r2"}) || roomTokens != 270 {
+		t.Errorf("rooms = %v with %d tokens, want [r1 r2] with 270", got, roomTokens)
+	}
+}
+
+func TestReportUnpricedModels(t *testing.T) {
+	uc := newReportUsecase(t, map[string]config.ModelPrice{"gemini": {InputPerMillion: 1, OutputPerMillion: 2}}, []Record{
+		{UserID: "u1", RoomID: "r1", Model: "gemini", PromptTokens: 1000, CandidateTokens: 1000, TotalTokens: 2000, At: day(2)},
+		{UserID: "u1", RoomID: "r1", Model: "llama3.1", PromptTokens: 500, CandidateTokens: 500, TotalTokens: 1000, At: day(2)},
+		{UserID: "u2", RoomID: "r1", Model: "gpt-4o-mini", PromptTokens: 10, CandidateTokens: 10, TotalTokens: 20, At: day(2)},
+		{RoomID: "r1", Model: "fake", PromptTokens: 1, CandidateTokens: 1, TotalTokens: 2, At: day(2)}, // Hanya di scope room.
+	})
+
+	report, err := uc.Report(context.Background(), day(2), day(2))
+	if err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+
+	if want := []string{"fake", "gpt-4o-mini", "llama3.1"}; !reflect.DeepEqual(report.UnpricedModels, want) {
+		t.Errorf("unpriced models = %v, want %v", report.UnpricedModels, want)
+	}
+	// Model tanpa harga tetap dihitung tokennya, tetapi biayanya 0.
+	costs := map[string]float64{}
+	for _, m := range report.Models {
+		costs[m.Model] = m.EstimatedCost
+	}
+	if math.Abs(costs["gemini"]-0.003) > 1e-12 || costs["llama3.1"] != 0 || costs["gpt-4o-mini"] != 0 {
+		t.Errorf("model costs = %v, want only gemini priced", costs)
+	}
+	if report.Total.TotalTokens != 3020 || math.Abs(report.Total.EstimatedCost-0.003) > 1e-12 {
+		t.Errorf("total = %+v, want 3020 tokens costing 0.003", report.Total)
+	}
+}
+
+// assertTotals membandingkan totals, dengan toleransi pembulatan untuk biaya.
+func assertTotals(t *testing.T, name string, got, want Totals) {
+	t.Helper()
+	cost := got.EstimatedCost
+	got.EstimatedCost, want.EstimatedCost = 0, math.Round(want.EstimatedCost*1e12)/1e12
+	if got != (Totals{Requests: want.Requests, PromptTokens: want.PromptTokens, CandidateTokens: want.CandidateTokens, TotalTokens: want.TotalTokens}) ||
+		math.Abs(cost-want.EstimatedCost) > 1e-12 {
+		t.Errorf("%s = %+v (cost %g), want %+v", name, got, cost, want)
+	}
+}
+
+// scopeIDs mengembalikan ID setiap scope sesuai urutannya.
+func scopeIDs(scopes []ScopeTotals) []string {
+	ids := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		ids = append(ids, s.ID)
+	}
+	return ids
+}
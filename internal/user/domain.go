@@ -3,7 +3,15 @@ package user
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
 	"time"
+	"unicode"
+
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/apperror"
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/auth"
 )
 
 // User adalah struct entitas utama untuk domain pengguna.
@@ -11,15 +19,238 @@ import (
 // Tag `json` digunakan untuk serialisasi/deserialisasi JSON saat berkomunikasi via API.
 // Tag `bson` digunakan oleh driver MongoDB untuk memetakan struct ke dokumen BSON.
 type User struct {
-	ID           string    `json:"id" bson:"_id"`
-	Email        string    `json:"email" bson:"email"`
-	PasswordHash string    `json:"-" bson:"password_hash"` // `json:"-"` berarti field ini tidak akan pernah dikirim dalam response JSON.
-	CreatedAt    time.Time `json:"created_at" bson:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at" bson:"updated_at"`
+	ID           string     `json:"id" bson:"_id"`
+	Email        string     `json:"email" bson:"email"`
+	Name         string     `json:"name,omitempty" bson:"name,omitempty"` // Nama tampilan, dibawa di claim `name` token.
+	PasswordHash string     `json:"-" bson:"password_hash"`               // `json:"-"` berarti field ini tidak akan pernah dikirim dalam response JSON.
+	Roles        []string   `json:"roles" bson:"roles"`                   // Role pengguna (lihat auth.RoleAdmin dan auth.RoleUser).
+	CreatedAt    time.Time  `json:"created_at" bson:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" bson:"updated_at"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"` // Diisi saat soft delete; pengguna yang dihapus tidak bisa login.
+}
+
+// Error yang dikembalikan oleh UserRepository dan UserUsecase. Status HTTP-nya ditentukan oleh jenis
+// apperror masing-masing.
+var (
+	ErrUserNotFound = apperror.NotFound("user_not_found", "user tidak ditemukan")
+	ErrForbidden    = apperror.Forbidden("forbidden", "akses ditolak")
+	ErrInvalidRole  = apperror.Validation("invalid_role", "role tidak dikenal")
+	// ErrWrongPassword dikembalikan saat password lama yang diberikan untuk mengganti password salah.
+	ErrWrongPassword = apperror.Validation("wrong_password", "password saat ini salah")
+	// ErrEmailTaken dikembalikan saat email sudah dipakai pengguna lain, termasuk pengguna yang sudah dihapus.
+	ErrEmailTaken   = apperror.Conflict("email_taken", "email sudah terdaftar")
+	ErrInvalidEmail = apperror.Validation("invalid_email", "format email tidak valid")
+	ErrWeakPassword = apperror.Validation("weak_password", "password terlalu lemah")
+)
+
+// Aturan kekuatan password. Batas atas mengikuti bcrypt yang hanya memakai 72 byte pertama.
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+// NormalizeEmail menghapus spasi di awal/akhir dan mengubah email menjadi huruf kecil,
+// sehingga satu alamat selalu disimpan dan dicari dengan bentuk yang sama.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// ValidateEmail memastikan email (yang sudah dinormalisasi) adalah satu alamat tanpa nama tampilan,
+// dengan domain yang memiliki titik, misalnya "nama@contoh.com".
+func ValidateEmail(email string) error {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return ErrInvalidEmail
+	}
+	at := strings.LastIndex(email, "@")
+	if domain := email[at+1:]; !strings.Contains(domain, ".") || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		return ErrInvalidEmail
+	}
+	return nil
+}
+
+// ValidatePassword memastikan password memiliki panjang MinPasswordLength sampai MaxPasswordLength byte
+// serta berisi setidaknya satu huruf dan satu angka.
+func ValidatePassword(password string) error {
+	if len(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+		return ErrWeakPassword.WithDetail(fmt.Sprintf("panjang harus %d sampai %d karakter", MinPasswordLength, MaxPasswordLength))
+	}
+	var hasLetter, hasDigit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		}
+	}
+	if !hasLetter || !hasDigit {
+		return ErrWeakPassword.WithDetail("harus berisi huruf dan angka")
+	}
+	return nil
+}
+
+// ProfileUpdate berisi perubahan profil pengguna; field nil tidak diubah.
+type ProfileUpdate struct {
+	Name *string
+}
+
+// Field yang bisa dipakai untuk mengurutkan daftar pengguna.
+const (
+	SortByCreatedAt = "created_at"
+	SortByEmail     = "email"
+)
+
+// Batas jumlah pengguna yang diambil dalam satu halaman daftar.
+const (
+	DefaultUserLimit = 20
+	MaxUserLimit     = 100
+)
+
+// UserQuery menentukan halaman daftar pengguna (offset pagination). Pengguna yang sudah dihapus tidak ikut.
+type UserQuery struct {
+	EmailPrefix string // Hanya pengguna yang email-nya diawali string ini.
+	SortBy      string // SortByCreatedAt (default) atau SortByEmail.
+	Descending  bool
+	Limit       int // Jumlah maksimal pengguna; 0 berarti DefaultUserLimit.
+	Offset      int
+}
+
+// Normalized mengembalikan UserQuery dengan Limit, Offset dan SortBy yang sudah disesuaikan.
+func (q UserQuery) Normalized() UserQuery {
+	switch {
+	case q.Limit <= 0:
+		q.Limit = DefaultUserLimit
+	case q.Limit > MaxUserLimit:
+		q.Limit = MaxUserLimit
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+	if q.SortBy != SortByEmail {
+		q.SortBy = SortByCreatedAt
+	}
+	return q
 }
 
-type Filter struct {
-	Email string
+// UserPage adalah satu halaman daftar pengguna beserta jumlah total yang cocok dengan query.
+type UserPage struct {
+	Users  []*User `json:"users"`
+	Total  int64   `json:"total"`
+	Limit  int     `json:"limit"`
+	Offset int     `json:"offset"`
+}
+
+// EffectiveRoles mengembalikan role pengguna; pengguna lama tanpa role dianggap auth.RoleUser.
+func (u *User) EffectiveRoles() []string {
+	if len(u.Roles) == 0 {
+		return []string{auth.RoleUser}
+	}
+	return u.Roles
+}
+
+// ErrInvalidCredentials adalah satu-satunya error yang dikembalikan untuk login yang gagal,
+// sehingga client tidak bisa membedakan email yang tidak terdaftar dari password yang salah.
+var ErrInvalidCredentials = apperror.Unauthorized("invalid_credentials", "email atau password salah")
+
+// ErrLoginLocked adalah error yang dibungkus oleh LoginLockedError.
+var ErrLoginLocked = apperror.TooManyRequests("login_locked", "terlalu banyak percobaan login, coba lagi nanti")
+
+// LoginLockedError dikembalikan saat login ditolak karena IP atau akun sedang dikunci
+// setelah terlalu banyak kegagalan.
+type LoginLockedError struct {
+	RetryAfter time.Duration // Sisa waktu sampai lockout berakhir.
+}
+
+func (e *LoginLockedError) Error() string {
+	return ErrLoginLocked.Error()
+}
+
+// Unwrap membuat errors.Is(err, ErrLoginLocked) bernilai true dan memetakan error ini ke status 429.
+func (e *LoginLockedError) Unwrap() error {
+	return ErrLoginLocked
+}
+
+// Scope penghitung kegagalan login.
+const (
+	LoginScopeIP      = "ip"
+	LoginScopeAccount = "account"
+)
+
+// LoginAttempt adalah catatan kegagalan login berturut-turut untuk satu key (IP atau akun).
+type LoginAttempt struct {
+	Key           string    `bson:"_id"`             // Gabungan scope dan nilainya, misal: "ip:10.0.0.1".
+	Failures      int       `bson:"failures"`        // Kegagalan sejak lockout terakhir.
+	Lockouts      int       `bson:"lockouts"`        // Lockout berturut-turut; menentukan durasi lockout berikutnya.
+	LastFailureAt time.Time `bson:"last_failure_at"` // Dipakai untuk mereset catatan setelah FailureWindow tanpa kegagalan.
+	LockedUntil   time.Time `bson:"locked_until"`
+}
+
+// LockoutEvent adalah catatan satu kejadian lockout untuk ditinjau kemudian.
+type LockoutEvent struct {
+	ID          string    `json:"id" bson:"_id"`
+	Scope       string    `json:"scope" bson:"scope"` // LoginScopeIP atau LoginScopeAccount.
+	Key         string    `json:"key" bson:"key"`
+	IP          string    `json:"ip" bson:"ip"`
+	Email       string    `json:"email" bson:"email"` // Email yang dicoba; belum tentu terdaftar.
+	Failures    int       `json:"failures" bson:"failures"`
+	Lockouts    int       `json:"lockouts" bson:"lockouts"`
+	LockedUntil time.Time `json:"locked_until" bson:"locked_until"`
+	CreatedAt   time.Time `json:"created_at" bson:"created_at"`
+}
+
+// LoginPolicy mengatur batas kegagalan login dan lockout progresif.
+// Durasi lockout adalah LockoutBase yang digandakan untuk setiap lockout berturut-turut, maksimal LockoutMax.
+type LoginPolicy struct {
+	MaxAccountFailures int           // Kegagalan per akun sebelum dikunci; <= 0 untuk menonaktifkan.
+	MaxIPFailures      int           // Kegagalan per IP sebelum dikunci; <= 0 untuk menonaktifkan.
+	FailureWindow      time.Duration // Catatan kegagalan direset jika tidak ada kegagalan selama ini.
+	LockoutBase        time.Duration
+	LockoutMax         time.Duration
+}
+
+// ErrInvalidRefreshToken dikembalikan jika refresh token tidak dikenal, kedaluwarsa, sudah dicabut
+// atau sudah pernah dipakai.
+var ErrInvalidRefreshToken = apperror.Unauthorized("invalid_refresh_token", "refresh token tidak valid")
+
+// ErrRefreshTokenNotFound dikembalikan oleh TokenRepository jika refresh token tidak ditemukan.
+var ErrRefreshTokenNotFound = errors.New("refresh token not found")
+
+// TokenPair adalah pasangan token yang dikembalikan saat login dan refresh.
+type TokenPair struct {
+	AccessToken  string `json:"token"` // Access token JWT berumur pendek.
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"` // Masa berlaku access token dalam detik.
+}
+
+// RefreshToken adalah catatan satu refresh token. Token aslinya tidak pernah disimpan, hanya hash SHA-256-nya.
+// Semua refresh token hasil rotasi dari satu login berbagi SessionID yang sama.
+type RefreshToken struct {
+	Hash            string    `bson:"_id"`
+	UserID          string    `bson:"user_id"`
+	SessionID       string    `bson:"session_id"`
+	AccessTokenID   string    `bson:"access_token_id"` // `jti` access token yang diterbitkan bersama token ini.
+	AccessExpiresAt time.Time `bson:"access_expires_at"`
+	CreatedAt       time.Time `bson:"created_at"`
+	ExpiresAt       time.Time `bson:"expires_at"`
+	UsedAt          time.Time `bson:"used_at,omitempty"`    // Diisi saat token dirotasi; token yang sudah dipakai tidak berlaku lagi.
+	RevokedAt       time.Time `bson:"revoked_at,omitempty"` // Diisi saat sesi di-logout atau dicabut.
+}
+
+// RevokedToken adalah access token yang dicabut sebelum masa berlakunya habis, diidentifikasi dari `jti`-nya.
+// Catatan cukup disimpan sampai ExpiresAt, karena setelah itu token sudah ditolak oleh validasi biasa.
+type RevokedToken struct {
+	ID        string    `bson:"_id"` // Claim `jti`.
+	UserID    string    `bson:"user_id"`
+	RevokedAt time.Time `bson:"revoked_at"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// TokenPolicy mengatur masa berlaku access token dan refresh token.
+type TokenPolicy struct {
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
 }
 
 // UserRepository mendefinisikan kontrak (interface) untuk lapisan persistensi (database).
@@ -27,16 +258,78 @@ type Filter struct {
 // Ini memungkinkan kita untuk menukar implementasi database tanpa mengubah logika bisnis.
 // Dependensi: lapisan Usecase bergantung pada interface ini.
 type UserRepository interface {
-	Create(ctx context.Context, filter Filter, user *User) error
+	// Create menyimpan pengguna baru; mengembalikan ErrEmailTaken jika email sudah dipakai.
+	Create(ctx context.Context, user *User) error
+	// GetByID dan GetByEmail mengembalikan ErrUserNotFound jika pengguna tidak ada atau sudah dihapus.
 	GetByID(ctx context.Context, id string) (*User, error)
 	GetByEmail(ctx context.Context, email string) (*User, error)
+	// UpdateProfile dan UpdatePassword hanya mengubah field masing-masing beserta updated_at pengguna yang
+	// belum dihapus, sehingga dua perubahan yang berjalan bersamaan tidak saling menimpa.
+	// Keduanya mengembalikan ErrUserNotFound jika pengguna tidak ada atau sudah dihapus.
+	UpdateProfile(ctx context.Context, id, name string, at time.Time) error
+	UpdatePassword(ctx context.Context, id, passwordHash string, at time.Time) error
+	// SoftDelete mengisi deleted_at pengguna; mengembalikan ErrUserNotFound jika sudah dihapus.
+	SoftDelete(ctx context.Context, id string, at time.Time) error
+	List(ctx context.Context, query UserQuery) (*UserPage, error)
+}
+
+// LoginAttemptRepository mendefinisikan kontrak (interface) untuk penyimpanan kegagalan login dan kejadian lockout.
+type LoginAttemptRepository interface {
+	// GetAttempt mengembalikan catatan untuk key, atau LoginAttempt kosong jika belum ada.
+	GetAttempt(ctx context.Context, key string) (*LoginAttempt, error)
+	// RecordFailure menambah satu kegagalan untuk key secara atomik dan mengembalikan catatan setelah ditambah.
+	// Catatan yang kegagalan terakhir dan akhir lockout-nya sebelum resetBefore dimulai ulang dari nol.
+	RecordFailure(ctx context.Context, key string, at, resetBefore time.Time) (*LoginAttempt, error)
+	// Lock mengunci key sampai lockedUntil, mengosongkan penghitung kegagalannya dan menambah jumlah lockout,
+	// hanya jika penghitung masih minimal minFailures. Mengembalikan false jika request lain sudah lebih dulu mengunci.
+	Lock(ctx context.Context, key string, minFailures int, lockedUntil time.Time) (bool, error)
+	DeleteAttempt(ctx context.Context, key string) error
+	CreateLockoutEvent(ctx context.Context, event *LockoutEvent) error
+}
+
+// TokenRepository mendefinisikan kontrak (interface) untuk penyimpanan refresh token dan daftar access token
+// yang dicabut.
+type TokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token *RefreshToken) error
+	GetRefreshToken(ctx context.Context, hash string) (*RefreshToken, error)
+	// UseRefreshToken menandai token sebagai sudah dipakai secara atomik dan mengembalikan keadaan token
+	// sebelum ditandai, sehingga pemakaian ulang bisa dideteksi dari UsedAt yang sudah terisi.
+	UseRefreshToken(ctx context.Context, hash string, at time.Time) (*RefreshToken, error)
+	// RevokeSession mencabut semua refresh token sebuah sesi dan mengembalikan token yang dicabut.
+	RevokeSession(ctx context.Context, sessionID string, at time.Time) ([]*RefreshToken, error)
+	// RevokeUserSessions mencabut semua refresh token seorang pengguna dan mengembalikan token yang dicabut.
+	RevokeUserSessions(ctx context.Context, userID string, at time.Time) ([]*RefreshToken, error)
+	RevokeAccessToken(ctx context.Context, token *RevokedToken) error
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+// ConnectionCloser menutup koneksi real-time milik seorang pengguna, misalnya WebSocket chat,
+// saat token pengguna tersebut dicabut.
+type ConnectionCloser interface {
+	CloseUserConnections(userID, reason string)
 }
 
 // UserUsecase mendefinisikan kontrak (interface) untuk lapisan logika bisnis (use case).
 // Setiap struct use case (misal: UserUsecaseImpl) harus mengimplementasikan semua method ini.
 // Dependensi: lapisan Handler bergantung pada interface ini.
 type UserUsecase interface {
-	Create(ctx context.Context, email, password string) (*User, error)
-	GetByID(ctx context.Context, actorID, userID string) (*User, error)
-	Login(ctx context.Context, email, password string) (string, error) // Mengembalikan token JWT.
+	// Create membuat pengguna baru dengan roles; roles kosong berarti auth.RoleUser.
+	Create(ctx context.Context, email, password string, roles []string) (*User, error)
+	// GetByID mengembalikan pengguna jika actor adalah pengguna itu sendiri atau memiliki auth.PermReadAnyUser.
+	GetByID(ctx context.Context, actor *auth.Principal, userID string) (*User, error)
+	// UpdateProfile mengubah profil pengguna; hanya pengguna itu sendiri atau auth.PermManageUsers.
+	UpdateProfile(ctx context.Context, actor *auth.Principal, userID string, update ProfileUpdate) (*User, error)
+	// ChangePassword mengganti password pengguna itu sendiri setelah memverifikasi password saat ini.
+	ChangePassword(ctx context.Context, actor *auth.Principal, userID, currentPassword, newPassword string) error
+	// Delete melakukan soft delete; hanya pengguna itu sendiri atau auth.PermManageUsers.
+	Delete(ctx context.Context, actor *auth.Principal, userID string) error
+	// List mengembalikan daftar pengguna untuk admin (auth.PermManageUsers).
+	List(ctx context.Context, actor *auth.Principal, query UserQuery) (*UserPage, error)
+	// EnsureAdmin membuat akun admin awal jika email tersebut belum terdaftar.
+	EnsureAdmin(ctx context.Context, email, password string) error
+	Login(ctx context.Context, email, password, ip string) (*TokenPair, error)
+	// Refresh menukar refresh token dengan pasangan token baru; refresh token lama tidak berlaku lagi.
+	Refresh(ctx context.Context, refreshToken string) (*TokenPair, error)
+	// Logout mencabut access token (jti) dan semua token dari sesinya.
+	Logout(ctx context.Context, userID, jti, sessionID string, expiresAt time.Time) error
 }
@@ -2,13 +2,19 @@
 package user
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/apperror"
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/auth"
 	"github.com/labstack/echo/v4"
 )
 
 // UserHandler adalah struct yang menangani request HTTP untuk domain User.
+// Error dari use case dikembalikan apa adanya; apperror.HTTPErrorHandler yang memetakannya ke status HTTP.
 // Dependensi: bergantung pada UserUsecase (kontrak lapisan bisnis).
 type UserHandler struct {
 	userUsecase UserUsecase
@@ -20,23 +26,25 @@ func NewUserHandler(userUsecase UserUsecase) *UserHandler {
 }
 
 // Create menangani request untuk membuat pengguna baru (POST /v1/users).
-// Endpoint ini diproteksi oleh Basic Auth.
+// Endpoint ini diproteksi oleh JWT Auth dan hanya untuk role yang boleh membuat pengguna (admin).
 func (h *UserHandler) Create(c echo.Context) error {
-	// Struct untuk menampung data dari request body.
+	// Struct untuk menampung data dari request body. Roles opsional, default "user".
 	var req struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Email    string   `json:"email"`
+		Password string   `json:"password"`
+		Roles    []string `json:"roles"`
 	}
 
 	// Mengikat (bind) data JSON dari body request ke dalam struct `req`.
 	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "body request tidak valid"})
+		return apperror.ErrInvalidBody
 	}
 
 	// Memanggil lapisan use case untuk menjalankan logika bisnis pembuatan user.
-	user, err := h.userUsecase.Create(c.Request().Context(), req.Email, req.Password)
+	// Email yang sudah terdaftar menghasilkan 409 Conflict, input yang tidak valid 400 Bad Request.
+	user, err := h.userUsecase.Create(c.Request().Context(), req.Email, req.Password, req.Roles)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return err
 	}
 
 	// Mengembalikan data user yang baru dibuat dengan status 201 Created.
@@ -47,18 +55,20 @@ func (h *UserHandler) Create(c echo.Context) error {
 // Endpoint ini diproteksi oleh JWT Auth.
 func (h *UserHandler) GetByID(c echo.Context) error {
 	// Mengambil data pengguna dari token JWT yang sudah divalidasi oleh middleware.
-	userToken := c.Get("user").(*jwt.Token)
-	claims := userToken.Claims.(*jwt.RegisteredClaims)
-	actorID := claims.Subject // ID pengguna yang melakukan request (aktor).
+	principal, err := auth.PrincipalFromContext(c)
+	if err != nil {
+		return err
+	}
 
 	// Mengambil ID pengguna yang ingin dilihat datanya dari parameter URL.
 	userID := c.Param("id")
 
 	// Memanggil lapisan use case, yang akan berisi logika otorisasi.
-	user, err := h.userUsecase.GetByID(c.Request().Context(), actorID, userID)
+	// Pengguna yang melakukan request (aktor) beserta role-nya diteruskan dari token.
+	// Aktor yang tidak berhak menghasilkan 403 Forbidden, user yang tidak ada 404 Not Found.
+	user, err := h.userUsecase.GetByID(c.Request().Context(), principal, userID)
 	if err != nil {
-		// Jika use case mengembalikan error (misal: akses ditolak), kirim status 403 Forbidden.
-		return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+		return err
 	}
 
 	return c.JSON(http.StatusOK, user)
@@ -73,16 +83,168 @@ func (h *UserHandler) Login(c echo.Context) error {
 	}
 
 	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "body request tidak valid"})
+		return apperror.ErrInvalidBody
 	}
 
 	// Memanggil lapisan use case untuk memvalidasi kredensial dan mendapatkan token.
-	token, err := h.userUsecase.Login(c.Request().Context(), req.Email, req.Password)
+	// IP client dipakai untuk membatasi percobaan login per IP.
+	tokens, err := h.userUsecase.Login(c.Request().Context(), req.Email, req.Password, c.RealIP())
+	if err != nil {
+		// Jika IP atau akun sedang dikunci, response 429 dilengkapi header Retry-After.
+		var locked *LoginLockedError
+		if errors.As(err, &locked) {
+			c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+		}
+		return err
+	}
+
+	// Mengembalikan access token dan refresh token ke client.
+	return c.JSON(http.StatusOK, tokens)
+}
+
+// Refresh menangani request untuk menukar refresh token dengan pasangan token baru (POST /v1/token/refresh).
+// Endpoint ini publik; refresh token lama tidak berlaku lagi setelah dipakai.
+func (h *UserHandler) Refresh(c echo.Context) error {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return apperror.ErrInvalidBody
+	}
+
+	tokens, err := h.userUsecase.Refresh(c.Request().Context(), req.RefreshToken)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, tokens)
+}
+
+// Logout menangani request untuk mencabut token yang sedang dipakai beserta sesinya (POST /v1/logout).
+// Endpoint ini diproteksi oleh JWT Auth.
+func (h *UserHandler) Logout(c echo.Context) error {
+	// Mengambil ID pengguna, jti, sesi dan waktu kedaluwarsa dari token yang sudah divalidasi oleh middleware.
+	principal, err := auth.PrincipalFromContext(c)
+	if err != nil {
+		return err
+	}
+	if principal.TokenID == "" {
+		return auth.ErrNoPrincipal
+	}
+
+	err = h.userUsecase.Logout(c.Request().Context(), principal.UserID, principal.TokenID, principal.SessionID, principal.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// UpdateProfile menangani request untuk mengubah profil pengguna (PATCH /v1/users/:id).
+// Endpoint ini diproteksi oleh JWT Auth; hanya pengguna itu sendiri atau admin yang bisa mengubahnya.
+func (h *UserHandler) UpdateProfile(c echo.Context) error {
+	principal, err := auth.PrincipalFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	// Field yang tidak dikirim (nil) tidak diubah.
+	var req struct {
+		Name *string `json:"name"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return apperror.ErrInvalidBody
+	}
+
+	user, err := h.userUsecase.UpdateProfile(c.Request().Context(), principal, c.Param("id"), ProfileUpdate{Name: req.Name})
 	if err != nil {
-		// Jika kredensial tidak valid, kirim status 401 Unauthorized.
-		return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return err
 	}
 
-	// Mengembalikan token JWT ke client.
-	return c.JSON(http.StatusOK, map[string]string{"token": token})
+	return c.JSON(http.StatusOK, user)
+}
+
+// ChangePassword menangani request untuk mengganti password (PUT /v1/users/:id/password).
+// Endpoint ini diproteksi oleh JWT Auth; password saat ini wajib dikirim dan semua sesi dicabut setelahnya.
+func (h *UserHandler) ChangePassword(c echo.Context) error {
+	principal, err := auth.PrincipalFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	var req struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return apperror.ErrInvalidBody
+	}
+
+	err = h.userUsecase.ChangePassword(c.Request().Context(), principal, c.Param("id"), req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Delete menangani request untuk menghapus pengguna (DELETE /v1/users/:id).
+// Endpoint ini diproteksi oleh JWT Auth; hanya pengguna itu sendiri atau admin yang bisa menghapusnya.
+func (h *UserHandler) Delete(c echo.Context) error {
+	principal, err := auth.PrincipalFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.userUsecase.Delete(c.Request().Context(), principal, c.Param("id")); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// List menangani request daftar pengguna untuk admin (GET /v1/admin/users).
+// Query parameter: `email` (prefix), `limit`, `offset` dan `sort` (`created_at` atau `email`,
+// awalan `-` untuk urutan menurun).
+func (h *UserHandler) List(c echo.Context) error {
+	principal, err := auth.PrincipalFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	query := UserQuery{EmailPrefix: c.QueryParam("email")}
+	if query.Limit, err = queryInt(c, "limit"); err != nil {
+		return apperror.Validation("invalid_query", "limit tidak valid")
+	}
+	if query.Offset, err = queryInt(c, "offset"); err != nil {
+		return apperror.Validation("invalid_query", "offset tidak valid")
+	}
+	if sortParam := c.QueryParam("sort"); sortParam != "" {
+		query.Descending = strings.HasPrefix(sortParam, "-")
+		query.SortBy = strings.TrimPrefix(sortParam, "-")
+		if query.SortBy != SortByCreatedAt && query.SortBy != SortByEmail {
+			return apperror.Validation("invalid_query", "sort tidak valid")
+		}
+	}
+
+	page, err := h.userUsecase.List(c.Request().Context(), principal, query)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, page)
+}
+
+// queryInt membaca query parameter bilangan bulat non-negatif; parameter kosong dianggap 0.
+func queryInt(c echo.Context, name string) (int, error) {
+	raw := c.QueryParam(name)
+	if raw == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return 0, errors.New("invalid integer")
+	}
+	return n, nil
 }
\ No newline at end of file
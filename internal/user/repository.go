@@ -3,10 +3,15 @@ package user
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 )
 
 // InMemoryUserRepository is an in-memory implementation of the UserRepository.
+// Users are stored and returned as copies, so callers cannot modify stored records by accident.
+// repotest.RunUserRepository checks that it behaves like MongoUserRepository.
 type InMemoryUserRepository struct {
 	mu    sync.RWMutex
 	users map[string]*User
@@ -19,7 +24,8 @@ func NewInMemoryUserRepository() *InMemoryUserRepository {
 	}
 }
 
-// Create saves a new user.
+// Create saves a new user. Like the unique index in MongoUserRepository, an email that is already
+// used, including by a deleted user, is rejected with ErrEmailTaken.
 func (r *InMemoryUserRepository) Create(ctx context.Context, user *User) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -27,32 +33,318 @@ func (r *InMemoryUserRepository) Create(ctx context.Context, user *User) error {
 	if _, exists := r.users[user.ID]; exists {
 		return fmt.Errorf("user with id %s already exists", user.ID)
 	}
-	
-	r.users[user.ID] = user
+	for _, existing := range r.users {
+		if existing.Email == user.Email {
+			return ErrEmailTaken
+		}
+	}
+
+	stored := *user
+	r.users[user.ID] = &stored
 	return nil
 }
 
-// GetByID retrieves a user by their ID.
+// GetByID retrieves a user that has not been deleted by their ID.
 func (r *InMemoryUserRepository) GetByID(ctx context.Context, id string) (*User, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	user, exists := r.users[id]
-	if !exists {
-		return nil, fmt.Errorf("user with id %s not found", id)
+	if !exists || user.DeletedAt != nil {
+		return nil, ErrUserNotFound
 	}
-	return user, nil
+	copied := *user
+	return &copied, nil
 }
 
-// GetByEmail retrieves a user by their email.
+// GetByEmail retrieves a user that has not been deleted by their email.
 func (r *InMemoryUserRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
+
 	for _, user := range r.users {
-		fmt.Printf("Looking for user with email: %s\n", user.Email) // Debugging line
-		if user.Email == email {
-			return user, nil
+		if user.Email == email && user.DeletedAt == nil {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+// UpdateProfile changes only the name and updated_at of a user that has not been deleted.
+func (r *InMemoryUserRepository) UpdateProfile(ctx context.Context, id, name string, at time.Time) error {
+	return r.update(id, func(stored *User) {
+		stored.Name = name
+		stored.UpdatedAt = at
+	})
+}
+
+// UpdatePassword changes only the password hash and updated_at of a user that has not been deleted.
+func (r *InMemoryUserRepository) UpdatePassword(ctx context.Context, id, passwordHash string, at time.Time) error {
+	return r.update(id, func(stored *User) {
+		stored.PasswordHash = passwordHash
+		stored.UpdatedAt = at
+	})
+}
+
+// update applies change to a user that has not been deleted while holding the write lock.
+func (r *InMemoryUserRepository) update(id string, change func(stored *User)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, exists := r.users[id]
+	if !exists || stored.DeletedAt != nil {
+		return ErrUserNotFound
+	}
+	change(stored)
+	return nil
+}
+
+// SoftDelete marks a user as deleted by setting deleted_at.
+func (r *InMemoryUserRepository) SoftDelete(ctx context.Context, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, exists := r.users[id]
+	if !exists || stored.DeletedAt != nil {
+		return ErrUserNotFound
+	}
+	stored.DeletedAt = &at
+	stored.UpdatedAt = at
+	return nil
+}
+
+// List returns a page of users that have not been deleted, filtered by email prefix and sorted
+// the same way as MongoUserRepository (by the sort field, then by ID).
+func (r *InMemoryUserRepository) List(ctx context.Context, query UserQuery) (*UserPage, error) {
+	query = query.Normalized()
+
+	r.mu.RLock()
+	var matched []*User
+	for _, user := range r.users {
+		if user.DeletedAt == nil && strings.HasPrefix(user.Email, query.EmailPrefix) {
+			copied := *user
+			matched = append(matched, &copied)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(matched, func(i, j int) bool {
+		a, b := matched[i], matched[j]
+		if query.Descending {
+			a, b = b, a
+		}
+		if query.SortBy == SortByEmail && a.Email != b.Email {
+			return a.Email < b.Email
+		}
+		if query.SortBy == SortByCreatedAt && !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	})
+
+	page := &UserPage{Users: []*User{}, Total: int64(len(matched)), Limit: query.Limit, Offset: query.Offset}
+	if query.Offset < len(matched) {
+		end := query.Offset + query.Limit
+		if end > len(matched) {
+			end = len(matched)
+		}
+		page.Users = matched[query.Offset:end]
+	}
+	return page, nil
+}
+
+// InMemoryLoginAttemptRepository is an in-memory implementation of the LoginAttemptRepository.
+type InMemoryLoginAttemptRepository struct {
+	mu       sync.RWMutex
+	attempts map[string]LoginAttempt
+	events   []LockoutEvent
+}
+
+// NewInMemoryLoginAttemptRepository creates a new InMemoryLoginAttemptRepository.
+func NewInMemoryLoginAttemptRepository() *InMemoryLoginAttemptRepository {
+	return &InMemoryLoginAttemptRepository{
+		attempts: make(map[string]LoginAttempt),
+	}
+}
+
+// GetAttempt returns a copy of the attempt record for key, or an empty record if there is none.
+func (r *InMemoryLoginAttemptRepository) GetAttempt(ctx context.Context, key string) (*LoginAttempt, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	attempt, exists := r.attempts[key]
+	if !exists {
+		return &LoginAttempt{Key: key}, nil
+	}
+	return &attempt, nil
+}
+
+// RecordFailure atomically resets a stale record and adds one failure to it.
+func (r *InMemoryLoginAttemptRepository) RecordFailure(ctx context.Context, key string, at, resetBefore time.Time) (*LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempt := r.attempts[key]
+	attempt.Key = key
+	if attempt.LastFailureAt.Before(resetBefore) && attempt.LockedUntil.Before(resetBefore) {
+		attempt.Failures, attempt.Lockouts = 0, 0
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = at
+	r.attempts[key] = attempt
+	return &attempt, nil
+}
+
+// Lock locks key if it still has at least minFailures failures.
+func (r *InMemoryLoginAttemptRepository) Lock(ctx context.Context, key string, minFailures int, lockedUntil time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempt, exists := r.attempts[key]
+	if !exists || attempt.Failures < minFailures {
+		return false, nil
+	}
+	attempt.Failures = 0
+	attempt.Lockouts++
+	attempt.LockedUntil = lockedUntil
+	r.attempts[key] = attempt
+	return true, nil
+}
+
+// DeleteAttempt removes the attempt record for key, if any.
+func (r *InMemoryLoginAttemptRepository) DeleteAttempt(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.attempts, key)
+	return nil
+}
+
+// CreateLockoutEvent stores a lockout event.
+func (r *InMemoryLoginAttemptRepository) CreateLockoutEvent(ctx context.Context, event *LockoutEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, *event)
+	return nil
+}
+
+// LockoutEvents returns a copy of all recorded lockout events, oldest first.
+func (r *InMemoryLoginAttemptRepository) LockoutEvents() []LockoutEvent {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]LockoutEvent(nil), r.events...)
+}
+
+// InMemoryTokenRepository is an in-memory implementation of the TokenRepository.
+// Expired records are not purged; they are simply rejected by the usecase.
+type InMemoryTokenRepository struct {
+	mu      sync.Mutex
+	refresh map[string]RefreshToken
+	revoked map[string]RevokedToken
+}
+
+// NewInMemoryTokenRepository creates a new InMemoryTokenRepository.
+func NewInMemoryTokenRepository() *InMemoryTokenRepository {
+	return &InMemoryTokenRepository{
+		refresh: make(map[string]RefreshToken),
+		revoked: make(map[string]RevokedToken),
+	}
+}
+
+// CreateRefreshToken stores a new refresh token.
+func (r *InMemoryTokenRepository) CreateRefreshToken(ctx context.Context, token *RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.refresh[token.Hash]; exists {
+		return fmt.Errorf("refresh token already exists")
+	}
+	r.refresh[token.Hash] = *token
+	return nil
+}
+
+// GetRefreshToken returns a copy of the refresh token with the given hash, or ErrRefreshTokenNotFound.
+func (r *InMemoryTokenRepository) GetRefreshToken(ctx context.Context, hash string) (*RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, exists := r.refresh[hash]
+	if !exists {
+		return nil, ErrRefreshTokenNotFound
+	}
+	return &token, nil
+}
+
+// UseRefreshToken marks the refresh token as used and returns its state from before the call.
+func (r *InMemoryTokenRepository) UseRefreshToken(ctx context.Context, hash string, at time.Time) (*RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, exists := r.refresh[hash]
+	if !exists {
+		return nil, ErrRefreshTokenNotFound
+	}
+	before := token
+	if token.UsedAt.IsZero() {
+		token.UsedAt = at
+		r.refresh[hash] = token
+	}
+	return &before, nil
+}
+
+// RevokeSession revokes every unrevoked refresh token of the session and returns them.
+func (r *InMemoryTokenRepository) RevokeSession(ctx context.Context, sessionID string, at time.Time) ([]*RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var revoked []*RefreshToken
+	for hash, token := range r.refresh {
+		if token.SessionID != sessionID || !token.RevokedAt.IsZero() {
+			continue
+		}
+		before := token
+		revoked = append(revoked, &before)
+		token.RevokedAt = at
+		r.refresh[hash] = token
+	}
+	return revoked, nil
+}
+
+// RevokeUserSessions revokes every unrevoked refresh token of the user and returns them.
+func (r *InMemoryTokenRepository) RevokeUserSessions(ctx context.Context, userID string, at time.Time) ([]*RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var revoked []*RefreshToken
+	for hash, token := range r.refresh {
+		if token.UserID != userID || !token.RevokedAt.IsZero() {
+			continue
 		}
+		before := token
+		revoked = append(revoked, &before)
+		token.RevokedAt = at
+		r.refresh[hash] = token
 	}
-	return nil, fmt.Errorf("user with email %s not found", email)
+	return revoked, nil
+}
+
+// RevokeAccessToken adds the access token to the revocation list.
+func (r *InMemoryTokenRepository) RevokeAccessToken(ctx context.Context, token *RevokedToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.revoked[token.ID] = *token
+	return nil
+}
+
+// IsAccessTokenRevoked reports whether the access token with the given jti has been revoked.
+func (r *InMemoryTokenRepository) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, revoked := r.revoked[jti]
+	return revoked, nil
 }
@@ -0,0 +1,63 @@
+package user_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gemini-cli/portfolio-chat-ai-go/internal/repotest"
+	"github.com/gemini-cli/portfolio-chat-ai-go/internal/user"
+)
+
+// Repository in-memory selalu diuji; repository MongoDB hanya jika MONGO_URI diisi.
+
+func TestUserRepositoryContract(t *testing.T) {
+	t.Run("memory", func(t *testing.T) {
+		repotest.RunUserRepository(t, func(t *testing.T) user.UserRepository {
+			return user.NewInMemoryUserRepository()
+		})
+	})
+	t.Run("mongo", func(t *testing.T) {
+		repotest.RunUserRepository(t, func(t *testing.T) user.UserRepository {
+			repo := user.NewMongoUserRepository(repotest.MongoDatabase(t))
+			if err := repo.EnsureIndexes(context.Background()); err != nil {
+				t.Fatalf("EnsureIndexes: %v", err)
+			}
+			return repo
+		})
+	})
+}
+
+func TestLoginAttemptRepositoryContract(t *testing.T) {
+	t.Run("memory", func(t *testing.T) {
+		repotest.RunLoginAttemptRepository(t, func(t *testing.T) user.LoginAttemptRepository {
+			return user.NewInMemoryLoginAttemptRepository()
+		})
+	})
+	t.Run("mongo", func(t *testing.T) {
+		repotest.RunLoginAttemptRepository(t, func(t *testing.T) user.LoginAttemptRepository {
+			repo := user.NewMongoLoginAttemptRepository(repotest.MongoDatabase(t))
+			if err := repo.EnsureIndexes(context.Background(), time.Hour); err != nil {
+				t.Fatalf("EnsureIndexes: %v", err)
+			}
+			return repo
+		})
+	})
+}
+
+func TestTokenRepositoryContract(t *testing.T) {
+	t.Run("memory", func(t *testing.T) {
+		repotest.RunTokenRepository(t, func(t *testing.T) user.TokenRepository {
+			return user.NewInMemoryTokenRepository()
+		})
+	})
+	t.Run("mongo", func(t *testing.T) {
+		repotest.RunTokenRepository(t, func(t *testing.T) user.TokenRepository {
+			repo := user.NewMongoTokenRepository(repotest.MongoDatabase(t))
+			if err := repo.EnsureIndexes(context.Background()); err != nil {
+				t.Fatalf("EnsureIndexes: %v", err)
+			}
+			return repo
+		})
+	})
+}
@@ -2,8 +2,15 @@ package user
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -24,38 +31,453 @@ func NewMongoUserRepository(db *mongo.Database) *MongoUserRepository {
 	}
 }
 
-// Create menyimpan sebuah entitas User baru ke dalam koleksi `users` di MongoDB.
-func (r *MongoUserRepository) Create(ctx context.Context, filter Filter, user *User) error {
-	opts := options.FindOneAndUpdate().SetUpsert(true)
-
-	update := bson.M{
-		"$set": bson.M{
-			"email":         user.Email,
-			"password_hash": user.PasswordHash,
-			"updated_at":    user.UpdatedAt,
+// EnsureIndexes membuat index unik `email` (untuk login, pencarian awalan email dan mencegah email ganda)
+// serta index `created_at` untuk mengurutkan daftar pengguna. Aman dipanggil berulang kali saat startup.
+// Email lama yang belum dinormalisasi diperbaiki dulu oleh normalizeEmails. Index unik gagal dibuat jika
+// koleksi sudah berisi email ganda; data tersebut harus dibereskan dulu.
+func (r *MongoUserRepository) EnsureIndexes(ctx context.Context) error {
+	if err := r.normalizeEmails(ctx); err != nil {
+		return err
+	}
+
+	indexes := r.db.Collection(r.collection).Indexes()
+
+	// Index `email` lama (tidak unik) dihapus dulu, karena index dengan key yang sama tidak bisa
+	// dibuat ulang dengan opsi berbeda.
+	specs, err := indexes.ListSpecifications(ctx)
+	if err != nil {
+		return err
+	}
+	for _, spec := range specs {
+		if spec.Name == "email" && (spec.Unique == nil || !*spec.Unique) {
+			if _, err := indexes.DropOne(ctx, spec.Name); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = indexes.CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().SetName("email").SetUnique(true),
 		},
-		"$setOnInsert": bson.M{
-			"_id":        user.ID,
-			"created_at": user.CreatedAt,
+		{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetName("created_at"),
 		},
+	})
+	return err
+}
+
+// unnormalizedEmail mencocokkan email yang mengandung huruf besar atau spasi di awal/akhir,
+// yaitu email yang tersimpan sebelum NormalizeEmail diterapkan saat registrasi.
+var unnormalizedEmail = primitive.Regex{Pattern: `[A-Z]|^\s|\s$`}
+
+// normalizeEmails mengubah email lama ke bentuk NormalizeEmail, agar pengguna tersebut tetap bisa login
+// dan index unik juga mencegah email yang hanya berbeda huruf besar/kecil. Jika ada email yang setelah
+// dinormalisasi bertabrakan dengan pengguna lain, tidak ada yang diubah dan error dikembalikan,
+// karena akun mana yang dipertahankan harus diputuskan secara manual.
+func (r *MongoUserRepository) normalizeEmails(ctx context.Context) error {
+	collection := r.db.Collection(r.collection)
+	cursor, err := collection.Find(ctx, bson.M{"email": unnormalizedEmail}, options.Find().SetProjection(bson.M{"email": 1}))
+	if err != nil {
+		return err
+	}
+	var users []struct {
+		ID    string `bson:"_id"`
+		Email string `bson:"email"`
+	}
+	if err := cursor.All(ctx, &users); err != nil {
+		return err
 	}
 
-	result := r.db.Collection(r.collection).FindOneAndUpdate(ctx, filter, update, opts)
-	return result.Err()
+	// 1. Periksa semua tabrakan dulu, baik dengan email yang sudah normal maupun antar email lama.
+	owners := make(map[string]string, len(users))
+	var conflicts []string
+	for _, u := range users {
+		email := NormalizeEmail(u.Email)
+		if owner, ok := owners[email]; ok {
+			conflicts = append(conflicts, fmt.Sprintf("%s (users %s and %s)", email, owner, u.ID))
+			continue
+		}
+		owners[email] = u.ID
+
+		var existing struct {
+			ID string `bson:"_id"`
+		}
+		err := collection.FindOne(ctx, bson.M{"email": email, "_id": bson.M{"$ne": u.ID}}).Decode(&existing)
+		switch {
+		case err == nil:
+			conflicts = append(conflicts, fmt.Sprintf("%s (users %s and %s)", email, existing.ID, u.ID))
+		case !errors.Is(err, mongo.ErrNoDocuments):
+			return err
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("cannot normalize user emails, resolve these duplicates first: %s", strings.Join(conflicts, ", "))
+	}
+
+	// 2. Baru setelah itu email diperbarui satu per satu.
+	for _, u := range users {
+		update := bson.M{"$set": bson.M{"email": NormalizeEmail(u.Email)}}
+		if _, err := collection.UpdateOne(ctx, bson.M{"_id": u.ID}, update); err != nil {
+			return fmt.Errorf("failed to normalize email of user %s: %w", u.ID, err)
+		}
+	}
+	return nil
+}
+
+// Create menyimpan sebuah entitas User baru ke dalam koleksi `users` di MongoDB.
+// Email yang sudah dipakai ditolak oleh index unik `email` dan dikembalikan sebagai ErrEmailTaken.
+func (r *MongoUserRepository) Create(ctx context.Context, user *User) error {
+	_, err := r.db.Collection(r.collection).InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrEmailTaken
+	}
+	return err
 }
 
 // GetByID mencari dan mengembalikan seorang pengguna berdasarkan ID-nya dari database.
 func (r *MongoUserRepository) GetByID(ctx context.Context, id string) (*User, error) {
 	var user User
-	// Mencari satu dokumen di koleksi `users` dimana field `_id` sama dengan id yang diberikan.
-	err := r.db.Collection(r.collection).FindOne(ctx, bson.M{"_id": id}).Decode(&user)
-	return &user, err
+	// Mencari satu dokumen di koleksi `users` dimana field `_id` sama dengan id yang diberikan
+	// dan pengguna tersebut belum dihapus.
+	err := r.db.Collection(r.collection).FindOne(ctx, bson.M{"_id": id, "deleted_at": notDeleted}).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
 }
 
 // GetByEmail mencari dan mengembalikan seorang pengguna berdasarkan email-nya dari database.
 func (r *MongoUserRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
 	var user User
-	// Mencari satu dokumen di koleksi `users` dimana field `email` sama dengan email yang diberikan.
-	err := r.db.Collection(r.collection).FindOne(ctx, bson.M{"email": email}).Decode(&user)
-	return &user, err
+	// Mencari satu dokumen di koleksi `users` dimana field `email` sama dengan email yang diberikan
+	// dan pengguna tersebut belum dihapus.
+	err := r.db.Collection(r.collection).FindOne(ctx, bson.M{"email": email, "deleted_at": notDeleted}).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// UpdateProfile mengubah nama dan updated_at pengguna yang belum dihapus.
+func (r *MongoUserRepository) UpdateProfile(ctx context.Context, id, name string, at time.Time) error {
+	return r.set(ctx, id, bson.M{"name": name, "updated_at": at})
+}
+
+// UpdatePassword mengubah hash password dan updated_at pengguna yang belum dihapus.
+func (r *MongoUserRepository) UpdatePassword(ctx context.Context, id, passwordHash string, at time.Time) error {
+	return r.set(ctx, id, bson.M{"password_hash": passwordHash, "updated_at": at})
+}
+
+// set mengisi fields pada pengguna yang belum dihapus dengan satu `$set`; field lain tidak disentuh.
+func (r *MongoUserRepository) set(ctx context.Context, id string, fields bson.M) error {
+	result, err := r.db.Collection(r.collection).UpdateOne(ctx, bson.M{"_id": id, "deleted_at": notDeleted}, bson.M{"$set": fields})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// SoftDelete menandai pengguna sebagai dihapus dengan mengisi `deleted_at`. Dokumen tetap disimpan.
+func (r *MongoUserRepository) SoftDelete(ctx context.Context, id string, at time.Time) error {
+	update := bson.M{"$set": bson.M{"deleted_at": at, "updated_at": at}}
+	result, err := r.db.Collection(r.collection).UpdateOne(ctx, bson.M{"_id": id, "deleted_at": notDeleted}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// List mengambil satu halaman pengguna yang belum dihapus, dengan pencarian awalan email dan pengurutan.
+func (r *MongoUserRepository) List(ctx context.Context, query UserQuery) (*UserPage, error) {
+	query = query.Normalized()
+	coll := r.db.Collection(r.collection)
+
+	// 1. Awalan email dicari dengan regex berjangkar `^`, sehingga index `email` tetap bisa dipakai.
+	filter := bson.M{"deleted_at": notDeleted}
+	if query.EmailPrefix != "" {
+		filter["email"] = bson.M{"$regex": "^" + regexp.QuoteMeta(query.EmailPrefix)}
+	}
+
+	total, err := coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. `_id` dipakai sebagai pengurut kedua agar urutan halaman stabil.
+	direction := 1
+	if query.Descending {
+		direction = -1
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: query.SortBy, Value: direction}, {Key: "_id", Value: direction}}).
+		SetSkip(int64(query.Offset)).
+		SetLimit(int64(query.Limit))
+
+	cursor, err := coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	users := []*User{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	return &UserPage{Users: users, Total: total, Limit: query.Limit, Offset: query.Offset}, nil
+}
+
+// notDeleted adalah filter untuk pengguna yang belum di-soft delete.
+var notDeleted = bson.M{"$exists": false}
+
+// MongoLoginAttemptRepository adalah implementasi dari LoginAttemptRepository yang menggunakan MongoDB.
+// Catatan kegagalan disimpan di koleksi "login_attempts" dan kejadian lockout di koleksi "login_lockouts".
+type MongoLoginAttemptRepository struct {
+	db                *mongo.Database
+	attemptCollection string
+	eventCollection   string
+}
+
+// NewMongoLoginAttemptRepository membuat instance baru dari MongoLoginAttemptRepository.
+func NewMongoLoginAttemptRepository(db *mongo.Database) *MongoLoginAttemptRepository {
+	return &MongoLoginAttemptRepository{
+		db:                db,
+		attemptCollection: "login_attempts",
+		eventCollection:   "login_lockouts",
+	}
+}
+
+// EnsureIndexes membuat TTL index agar catatan kegagalan yang sudah tidak aktif selama ttl dihapus otomatis,
+// serta index `created_at` untuk meninjau kejadian lockout. Aman dipanggil berulang kali saat startup.
+func (r *MongoLoginAttemptRepository) EnsureIndexes(ctx context.Context, ttl time.Duration) error {
+	_, err := r.db.Collection(r.attemptCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "last_failure_at", Value: 1}},
+		Options: options.Index().SetName("last_failure_at_ttl").SetExpireAfterSeconds(int32(ttl.Seconds())),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Collection(r.eventCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "created_at", Value: -1}},
+		Options: options.Index().SetName("created_at"),
+	})
+	return err
+}
+
+// GetAttempt mengembalikan catatan kegagalan untuk key, atau LoginAttempt kosong jika belum ada.
+func (r *MongoLoginAttemptRepository) GetAttempt(ctx context.Context, key string) (*LoginAttempt, error) {
+	var attempt LoginAttempt
+	err := r.db.Collection(r.attemptCollection).FindOne(ctx, bson.M{"_id": key}).Decode(&attempt)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return &LoginAttempt{Key: key}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// RecordFailure menambah satu kegagalan untuk key secara atomik dan mengembalikan catatan setelah ditambah.
+func (r *MongoLoginAttemptRepository) RecordFailure(ctx context.Context, key string, at, resetBefore time.Time) (*LoginAttempt, error) {
+	collection := r.db.Collection(r.attemptCollection)
+
+	// 1. Mulai ulang catatan yang sudah tidak aktif. Filter dievaluasi secara atomik, sehingga kegagalan
+	//    yang baru dicatat oleh request lain (last_failure_at terbaru) tidak ikut terhapus.
+	_, err := collection.UpdateOne(ctx, bson.M{
+		"_id":             key,
+		"last_failure_at": bson.M{"$lt": resetBefore},
+		"locked_until":    bson.M{"$lt": resetBefore},
+	}, bson.M{"$set": bson.M{"failures": 0, "lockouts": 0}})
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Tambah penghitung dan kembalikan hasilnya dalam satu operasi.
+	var attempt LoginAttempt
+	err = collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, bson.M{
+		"$inc":         bson.M{"failures": 1},
+		"$set":         bson.M{"last_failure_at": at},
+		"$setOnInsert": bson.M{"lockouts": 0, "locked_until": time.Time{}},
+	}, options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&attempt)
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// Lock mengunci key sampai lockedUntil jika penghitung kegagalannya masih minimal minFailures.
+func (r *MongoLoginAttemptRepository) Lock(ctx context.Context, key string, minFailures int, lockedUntil time.Time) (bool, error) {
+	result, err := r.db.Collection(r.attemptCollection).UpdateOne(ctx, bson.M{
+		"_id":      key,
+		"failures": bson.M{"$gte": minFailures},
+	}, bson.M{
+		"$set": bson.M{"failures": 0, "locked_until": lockedUntil},
+		"$inc": bson.M{"lockouts": 1},
+	})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// DeleteAttempt menghapus catatan kegagalan untuk key, jika ada.
+func (r *MongoLoginAttemptRepository) DeleteAttempt(ctx context.Context, key string) error {
+	_, err := r.db.Collection(r.attemptCollection).DeleteOne(ctx, bson.M{"_id": key})
+	return err
+}
+
+// CreateLockoutEvent menyimpan satu kejadian lockout.
+func (r *MongoLoginAttemptRepository) CreateLockoutEvent(ctx context.Context, event *LockoutEvent) error {
+	_, err := r.db.Collection(r.eventCollection).InsertOne(ctx, event)
+	return err
+}
+
+// MongoTokenRepository adalah implementasi dari TokenRepository yang menggunakan MongoDB.
+// Refresh token disimpan di koleksi "refresh_tokens" dan access token yang dicabut di koleksi "revoked_tokens".
+type MongoTokenRepository struct {
+	db                *mongo.Database
+	refreshCollection string
+	revokedCollection string
+}
+
+// NewMongoTokenRepository membuat instance baru dari MongoTokenRepository.
+func NewMongoTokenRepository(db *mongo.Database) *MongoTokenRepository {
+	return &MongoTokenRepository{
+		db:                db,
+		refreshCollection: "refresh_tokens",
+		revokedCollection: "revoked_tokens",
+	}
+}
+
+// EnsureIndexes membuat TTL index agar refresh token dan catatan pencabutan dihapus otomatis setelah
+// kedaluwarsa, serta index `session_id` dan `user_id` untuk mencabut sesi. Aman dipanggil berulang kali saat startup.
+func (r *MongoTokenRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.db.Collection(r.refreshCollection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0),
+		},
+		{
+			Keys:    bson.D{{Key: "session_id", Value: 1}},
+			Options: options.Index().SetName("session_id"),
+		},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}},
+			Options: options.Index().SetName("user_id"),
+		},
+		{
+			// Hanya token yang sudah dicabut yang memiliki revocation_id.
+			Keys:    bson.D{{Key: "revocation_id", Value: 1}},
+			Options: options.Index().SetName("revocation_id").SetSparse(true),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Collection(r.revokedCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+// CreateRefreshToken menyimpan refresh token baru.
+func (r *MongoTokenRepository) CreateRefreshToken(ctx context.Context, token *RefreshToken) error {
+	_, err := r.db.Collection(r.refreshCollection).InsertOne(ctx, token)
+	return err
+}
+
+// GetRefreshToken mencari refresh token berdasarkan hash-nya, atau ErrRefreshTokenNotFound.
+func (r *MongoTokenRepository) GetRefreshToken(ctx context.Context, hash string) (*RefreshToken, error) {
+	var token RefreshToken
+	err := r.db.Collection(r.refreshCollection).FindOne(ctx, bson.M{"_id": hash}).Decode(&token)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// UseRefreshToken menandai refresh token sebagai sudah dipakai dengan satu operasi atomik dan
+// mengembalikan dokumen sebelum diubah. Waktu pemakaian pertama tidak pernah ditimpa.
+func (r *MongoTokenRepository) UseRefreshToken(ctx context.Context, hash string, at time.Time) (*RefreshToken, error) {
+	// $ifNull hanya mengisi used_at jika belum ada, sehingga UsedAt pertama tetap terjaga meskipun
+	// pemakaian berikutnya membawa waktu yang lebih awal (misalnya dari instance dengan jam yang berbeda).
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{"used_at": bson.M{"$ifNull": bson.A{"$used_at", at}}}}}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+
+	var token RefreshToken
+	err := r.db.Collection(r.refreshCollection).FindOneAndUpdate(ctx, bson.M{"_id": hash}, update, opts).Decode(&token)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// RevokeSession mencabut semua refresh token yang belum dicabut dalam sebuah sesi.
+func (r *MongoTokenRepository) RevokeSession(ctx context.Context, sessionID string, at time.Time) ([]*RefreshToken, error) {
+	return r.revokeRefreshTokens(ctx, bson.M{"session_id": sessionID, "revoked_at": bson.M{"$exists": false}}, at)
+}
+
+// RevokeUserSessions mencabut semua refresh token yang belum dicabut milik seorang pengguna.
+func (r *MongoTokenRepository) RevokeUserSessions(ctx context.Context, userID string, at time.Time) ([]*RefreshToken, error) {
+	return r.revokeRefreshTokens(ctx, bson.M{"user_id": userID, "revoked_at": bson.M{"$exists": false}}, at)
+}
+
+// revokeRefreshTokens mengisi revoked_at pada semua refresh token yang cocok dengan filter
+// dan mengembalikan token tersebut.
+func (r *MongoTokenRepository) revokeRefreshTokens(ctx context.Context, filter bson.M, at time.Time) ([]*RefreshToken, error) {
+	coll := r.db.Collection(r.refreshCollection)
+
+	// UpdateMany dijalankan lebih dulu dan setiap token yang dicabut ditandai dengan ID pencabutan ini,
+	// sehingga token yang dibuat di antara dua query tidak bisa tercabut tanpa ikut dikembalikan,
+	// dan token yang dicabut oleh pemanggil lain (walaupun pada waktu yang sama) tidak ikut dikembalikan.
+	revocationID := uuid.NewString()
+	update := bson.M{"$set": bson.M{"revoked_at": at, "revocation_id": revocationID}}
+	if _, err := coll.UpdateMany(ctx, filter, update); err != nil {
+		return nil, err
+	}
+
+	cursor, err := coll.Find(ctx, bson.M{"revocation_id": revocationID})
+	if err != nil {
+		return nil, err
+	}
+	var tokens []*RefreshToken
+	if err := cursor.All(ctx, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// RevokeAccessToken memasukkan access token ke daftar pencabutan. Mencabut token yang sama dua kali bukan error.
+func (r *MongoTokenRepository) RevokeAccessToken(ctx context.Context, token *RevokedToken) error {
+	_, err := r.db.Collection(r.revokedCollection).ReplaceOne(ctx, bson.M{"_id": token.ID}, token, options.Replace().SetUpsert(true))
+	return err
+}
+
+// IsAccessTokenRevoked memeriksa apakah access token dengan jti tersebut sudah dicabut.
+func (r *MongoTokenRepository) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	err := r.db.Collection(r.revokedCollection).FindOne(ctx, bson.M{"_id": jti}).Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	return err == nil, err
 }
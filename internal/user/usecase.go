@@ -2,32 +2,73 @@ package user
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"log"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/auth"
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/jwtkeys"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
 // UserUsecaseImpl adalah implementasi dari UserUsecase yang berisi logika bisnis utama.
-// Dependensi: bergantung pada UserRepository (untuk akses data) dan JWT Secret.
+// Dependensi: bergantung pada UserRepository (untuk akses data), LoginAttemptRepository (untuk
+// perlindungan brute-force), TokenRepository (untuk refresh token dan pencabutan token) dan kunci JWT.
 type UserUsecaseImpl struct {
-	userRepo  UserRepository // Kontrak ke lapisan repository.
-	jwtSecret []byte         // Kunci rahasia untuk membuat token.
+	userRepo    UserRepository         // Kontrak ke lapisan repository.
+	attemptRepo LoginAttemptRepository // Penyimpanan kegagalan login dan lockout.
+	tokenRepo   TokenRepository        // Penyimpanan refresh token dan access token yang dicabut.
+	connections ConnectionCloser       // Boleh nil; dipanggil saat token pengguna dicabut.
+	loginPolicy LoginPolicy            // Batas kegagalan login dan durasi lockout.
+	tokenPolicy TokenPolicy            // Masa berlaku access token dan refresh token.
+	jwtKeys     *jwtkeys.KeySet        // Kunci untuk menandatangani token.
 }
 
 // NewUserUsecase membuat instance baru dari UserUsecaseImpl.
-func NewUserUsecase(userRepo UserRepository, jwtSecret string) *UserUsecaseImpl {
+func NewUserUsecase(userRepo UserRepository, attemptRepo LoginAttemptRepository, tokenRepo TokenRepository, connections ConnectionCloser, loginPolicy LoginPolicy, tokenPolicy TokenPolicy, jwtKeys *jwtkeys.KeySet) *UserUsecaseImpl {
 	return &UserUsecaseImpl{
-		userRepo:  userRepo,
-		jwtSecret: []byte(jwtSecret),
+		userRepo:    userRepo,
+		attemptRepo: attemptRepo,
+		tokenRepo:   tokenRepo,
+		connections: connections,
+		loginPolicy: loginPolicy,
+		tokenPolicy: tokenPolicy,
+		jwtKeys:     jwtKeys,
 	}
 }
 
 // Create adalah logika bisnis untuk membuat pengguna baru.
-// Termasuk hashing password dan memanggil repository untuk menyimpan data.
-func (uc *UserUsecaseImpl) Create(ctx context.Context, email, password string) (*User, error) {
+// Termasuk normalisasi dan validasi email, validasi kekuatan password dan role, hashing password
+// dan memanggil repository untuk menyimpan data.
+func (uc *UserUsecaseImpl) Create(ctx context.Context, email, password string, roles []string) (*User, error) {
+	// Input divalidasi sebelum password di-hash.
+	email = NormalizeEmail(email)
+	if err := ValidateEmail(email); err != nil {
+		return nil, err
+	}
+	if err := ValidatePassword(password); err != nil {
+		return nil, err
+	}
+
+	// Hanya role yang dikenal yang boleh diberikan; tanpa role berarti pengguna biasa.
+	if len(roles) == 0 {
+		roles = []string{auth.RoleUser}
+	}
+	for _, role := range roles {
+		if !auth.ValidRole(role) {
+			return nil, ErrInvalidRole.WithDetail(role)
+		}
+	}
+
 	// Melakukan hash pada password menggunakan bcrypt untuk keamanan.
 	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -40,16 +81,14 @@ func (uc *UserUsecaseImpl) Create(ctx context.Context, email, password string) (
 		ID:           uuid.NewString(),
 		Email:        email,
 		PasswordHash: string(passwordHash),
+		Roles:        roles,
 		CreatedAt:    now,
 		UpdatedAt:    now,
 	}
 
-	filter := Filter{
-		Email: email,
-	}
-
-	// Memanggil lapisan repository untuk menyimpan user baru.
-	if err := uc.userRepo.Create(ctx, filter, newUser); err != nil {
+	// Memanggil lapisan repository untuk menyimpan user baru. Email yang sudah dipakai ditolak
+	// dengan ErrEmailTaken, sehingga data pengguna lain tidak pernah tertimpa.
+	if err := uc.userRepo.Create(ctx, newUser); err != nil {
 		return nil, fmt.Errorf("tidak bisa membuat user: %w", err)
 	}
 
@@ -58,10 +97,10 @@ func (uc *UserUsecaseImpl) Create(ctx context.Context, email, password string) (
 
 // GetByID adalah logika bisnis untuk mendapatkan pengguna berdasarkan ID.
 // Termasuk logika otorisasi untuk memeriksa apakah pengguna yang meminta berhak melihat data ini.
-func (uc *UserUsecaseImpl) GetByID(ctx context.Context, actorID, userID string) (*User, error) {
-	// Logika Otorisasi: hanya pengguna itu sendiri yang bisa melihat datanya.
-	if actorID != userID {
-		return nil, fmt.Errorf("akses ditolak: aktor %s tidak bisa mengakses data user %s", actorID, userID)
+func (uc *UserUsecaseImpl) GetByID(ctx context.Context, actor *auth.Principal, userID string) (*User, error) {
+	// Logika Otorisasi: pengguna itu sendiri, atau role yang boleh membaca semua pengguna (admin).
+	if err := authorizeSelfOr(actor, userID, auth.PermReadAnyUser); err != nil {
+		return nil, err
 	}
 
 	// Memanggil repository untuk mengambil data dari database.
@@ -73,34 +112,453 @@ func (uc *UserUsecaseImpl) GetByID(ctx context.Context, actorID, userID string)
 	return user, nil
 }
 
+// UpdateProfile adalah logika bisnis untuk mengubah profil pengguna.
+// Hanya pengguna itu sendiri atau role yang boleh mengelola pengguna (admin) yang bisa mengubahnya.
+func (uc *UserUsecaseImpl) UpdateProfile(ctx context.Context, actor *auth.Principal, userID string, update ProfileUpdate) (*User, error) {
+	if err := authorizeSelfOr(actor, userID, auth.PermManageUsers); err != nil {
+		return nil, err
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("tidak bisa mendapatkan user: %w", err)
+	}
+
+	// Hanya field yang dikirim yang diubah. Nama baru ikut di token berikutnya (login atau refresh).
+	if update.Name == nil {
+		return user, nil
+	}
+	user.Name = strings.TrimSpace(*update.Name)
+	user.UpdatedAt = time.Now()
+
+	if err := uc.userRepo.UpdateProfile(ctx, userID, user.Name, user.UpdatedAt); err != nil {
+		return nil, fmt.Errorf("tidak bisa mengubah user: %w", err)
+	}
+	return user, nil
+}
+
+// ChangePassword adalah logika bisnis untuk mengganti password. Hanya pengguna itu sendiri yang bisa
+// menggantinya, dan password saat ini harus benar. Setelah berhasil, semua sesi pengguna dicabut
+// sehingga pengguna harus login ulang dengan password baru.
+func (uc *UserUsecaseImpl) ChangePassword(ctx context.Context, actor *auth.Principal, userID, currentPassword, newPassword string) error {
+	if actor.UserID != userID {
+		return fmt.Errorf("%w: aktor %s tidak bisa mengganti password user %s", ErrForbidden, actor.UserID, userID)
+	}
+	if err := ValidatePassword(newPassword); err != nil {
+		return err
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("tidak bisa mendapatkan user: %w", err)
+	}
+
+	// 1. Verifikasi password saat ini.
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(currentPassword)); err != nil {
+		return ErrWrongPassword
+	}
+
+	// 2. Simpan hash password baru.
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("tidak bisa hash password: %w", err)
+	}
+	if err := uc.userRepo.UpdatePassword(ctx, userID, string(passwordHash), time.Now()); err != nil {
+		return fmt.Errorf("tidak bisa mengubah user: %w", err)
+	}
+
+	// 3. Cabut semua sesi yang dibuat dengan password lama.
+	if err := uc.revokeUserSessions(ctx, userID); err != nil {
+		return fmt.Errorf("tidak bisa mencabut sesi: %w", err)
+	}
+	return nil
+}
+
+// Delete adalah logika bisnis untuk menghapus pengguna (soft delete). Data tetap disimpan dengan
+// `deleted_at`, tetapi pengguna tidak bisa login lagi dan semua sesinya dicabut.
+func (uc *UserUsecaseImpl) Delete(ctx context.Context, actor *auth.Principal, userID string) error {
+	if err := authorizeSelfOr(actor, userID, auth.PermManageUsers); err != nil {
+		return err
+	}
+
+	if err := uc.userRepo.SoftDelete(ctx, userID, time.Now()); err != nil {
+		return fmt.Errorf("tidak bisa menghapus user: %w", err)
+	}
+
+	if err := uc.revokeUserSessions(ctx, userID); err != nil {
+		return fmt.Errorf("tidak bisa mencabut sesi: %w", err)
+	}
+	return nil
+}
+
+// List adalah logika bisnis untuk menampilkan daftar pengguna. Hanya untuk role yang boleh
+// mengelola pengguna (admin).
+func (uc *UserUsecaseImpl) List(ctx context.Context, actor *auth.Principal, query UserQuery) (*UserPage, error) {
+	if !actor.Can(auth.PermManageUsers) {
+		return nil, fmt.Errorf("%w: aktor %s tidak bisa melihat daftar user", ErrForbidden, actor.UserID)
+	}
+
+	page, err := uc.userRepo.List(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("tidak bisa mendapatkan daftar user: %w", err)
+	}
+	return page, nil
+}
+
+// authorizeSelfOr mengizinkan aksi jika actor adalah pengguna itu sendiri atau memiliki permission perm.
+func authorizeSelfOr(actor *auth.Principal, userID string, perm auth.Permission) error {
+	if actor.UserID != userID && !actor.Can(perm) {
+		return fmt.Errorf("%w: aktor %s tidak bisa mengakses data user %s", ErrForbidden, actor.UserID, userID)
+	}
+	return nil
+}
+
 // Login adalah logika bisnis untuk autentikasi pengguna.
-// Memvalidasi kredensial dan membuat token JWT jika berhasil.
-func (uc *UserUsecaseImpl) Login(ctx context.Context, email, password string) (string, error) {
-	// 1. Cari pengguna berdasarkan email.
+// Memvalidasi kredensial dan membuat sesi baru (access token dan refresh token) jika berhasil. Kegagalan dihitung per IP dan per akun;
+// setelah batas LoginPolicy terlampaui, login ditolak dengan *LoginLockedError sampai lockout berakhir.
+// Semua kegagalan kredensial mengembalikan ErrInvalidCredentials.
+func (uc *UserUsecaseImpl) Login(ctx context.Context, email, password, ip string) (*TokenPair, error) {
+	// 1. Tolak lebih awal jika IP atau akun sedang dikunci.
+	email = NormalizeEmail(email)
+	keys := uc.loginKeys(email, ip)
+	if err := uc.checkLockout(ctx, keys); err != nil {
+		return nil, err
+	}
+
+	// 2. Cari pengguna berdasarkan email lalu bandingkan password dengan hash di database.
+	//    Jika email tidak ditemukan, password tetap dibandingkan dengan hash tiruan agar waktu respons
+	//    tidak membocorkan apakah email terdaftar.
 	user, err := uc.userRepo.GetByEmail(ctx, email)
+	passwordHash := dummyPasswordHash()
+	if err == nil {
+		passwordHash = []byte(user.PasswordHash)
+	}
+	if compareErr := bcrypt.CompareHashAndPassword(passwordHash, []byte(password)); err != nil || compareErr != nil {
+		uc.registerLoginFailure(ctx, keys, email, ip)
+		return nil, ErrInvalidCredentials
+	}
+
+	// 3. Login berhasil: hapus catatan kegagalan akun. Catatan IP tetap, agar satu akun yang valid
+	//    tidak bisa dipakai untuk mereset penghitung IP.
+	if err := uc.attemptRepo.DeleteAttempt(ctx, keys[1].key); err != nil {
+		log.Printf("failed to reset login attempts for %s: %v", keys[1].key, err)
+	}
+
+	// 4. Jika kredensial valid, buat sesi baru beserta pasangan tokennya.
+	return uc.issueTokens(ctx, user, uuid.NewString())
+}
+
+// EnsureAdmin membuat akun admin awal jika email tersebut belum terdaftar. Akun yang sudah ada tidak diubah,
+// sehingga password yang diganti setelah startup pertama tidak tertimpa.
+func (uc *UserUsecaseImpl) EnsureAdmin(ctx context.Context, email, password string) error {
+	_, err := uc.userRepo.GetByEmail(ctx, NormalizeEmail(email))
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, ErrUserNotFound) {
+		return fmt.Errorf("tidak bisa mencari admin: %w", err)
+	}
+
+	admin, err := uc.Create(ctx, email, password, []string{auth.RoleAdmin})
+	if err != nil {
+		return err
+	}
+	log.Printf("created initial admin user %s", admin.ID)
+	return nil
+}
+
+// Refresh menukar refresh token dengan pasangan token baru dalam sesi yang sama (rotasi).
+// Refresh token yang dipakai ulang dianggap bocor: seluruh sesinya dicabut, termasuk access token
+// yang diterbitkan bersamanya, dan koneksi real-time pengguna ditutup.
+func (uc *UserUsecaseImpl) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	if refreshToken == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// 1. Tandai token sebagai sudah dipakai secara atomik, agar dua request bersamaan tidak bisa
+	//    menukar token yang sama.
+	now := time.Now()
+	stored, err := uc.tokenRepo.UseRefreshToken(ctx, hashToken(refreshToken), now)
+	if errors.Is(err, ErrRefreshTokenNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
 	if err != nil {
-		return "", fmt.Errorf("kredensial tidak valid: %w", err)
+		return nil, fmt.Errorf("tidak bisa membaca refresh token: %w", err)
 	}
 
-	// 2. Bandingkan password yang diberikan dengan hash yang ada di database.
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
-		return "", fmt.Errorf("kredensial tidak valid: %w", err)
+	// 2. Tolak token yang sudah dicabut atau kedaluwarsa.
+	if !stored.RevokedAt.IsZero() || !now.Before(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
 	}
 
-	// 3. Jika kredensial valid, buat token JWT.
-	claims := &jwt.RegisteredClaims{
-		Issuer:    "portfolio-chat-ai-go",
-		Subject:   user.ID, // ID pengguna disimpan di dalam token.
-		Audience:  jwt.ClaimStrings{"users"},
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24)), // Token berlaku 24 jam.
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
+	// 3. Token yang sudah pernah dirotasi dipakai lagi: cabut seluruh sesinya.
+	if !stored.UsedAt.IsZero() {
+		log.Printf("refresh token reuse detected for user %s, revoking session %s", stored.UserID, stored.SessionID)
+		if err := uc.revokeSession(ctx, stored.SessionID); err != nil {
+			log.Printf("failed to revoke session %s: %v", stored.SessionID, err)
+		}
+		uc.closeConnections(stored.UserID)
+		return nil, ErrInvalidRefreshToken
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err := token.SignedString(uc.jwtSecret)
+	// 4. Terbitkan pasangan token baru untuk sesi yang sama, dengan role terbaru pengguna.
+	user, err := uc.userRepo.GetByID(ctx, stored.UserID)
+	if errors.Is(err, ErrUserNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
 	if err != nil {
-		return "", fmt.Errorf("tidak bisa menandatangani token: %w", err)
+		return nil, fmt.Errorf("tidak bisa mendapatkan user: %w", err)
+	}
+	return uc.issueTokens(ctx, user, stored.SessionID)
+}
+
+// Logout mencabut access token yang sedang dipakai beserta sesi login-nya, sehingga refresh token
+// dari sesi tersebut tidak bisa dipakai lagi. Koneksi real-time pengguna ikut ditutup.
+func (uc *UserUsecaseImpl) Logout(ctx context.Context, userID, jti, sessionID string, expiresAt time.Time) error {
+	// 1. Masukkan access token ke daftar pencabutan sampai masa berlakunya habis.
+	revoked := &RevokedToken{ID: jti, UserID: userID, RevokedAt: time.Now(), ExpiresAt: expiresAt}
+	if err := uc.tokenRepo.RevokeAccessToken(ctx, revoked); err != nil {
+		return fmt.Errorf("tidak bisa mencabut token: %w", err)
 	}
 
-	return signedToken, nil
+	// 2. Cabut semua refresh token dan access token lain dari sesi yang sama.
+	if sessionID != "" {
+		if err := uc.revokeSession(ctx, sessionID); err != nil {
+			return fmt.Errorf("tidak bisa mencabut sesi: %w", err)
+		}
+	}
+
+	// 3. Tutup koneksi WebSocket yang dibuka dengan token yang sudah dicabut.
+	uc.closeConnections(userID)
+	return nil
+}
+
+// issueTokens membuat access token JWT dan refresh token baru untuk sebuah sesi, lalu menyimpan
+// hash refresh token tersebut. Role pengguna dibawa di dalam access token.
+func (uc *UserUsecaseImpl) issueTokens(ctx context.Context, user *User, sessionID string) (*TokenPair, error) {
+	userID := user.ID
+	now := time.Now()
+	accessExpiresAt := now.Add(uc.tokenPolicy.AccessTTL)
+
+	// 1. Buat access token. Claim `jti` dipakai untuk mencabut token sebelum kedaluwarsa,
+	//    dan `sid` untuk mencabut seluruh sesi saat logout.
+	claims := &auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Issuer:    "portfolio-chat-ai-go",
+			Subject:   userID, // ID pengguna disimpan di dalam token.
+			Audience:  jwt.ClaimStrings{"users"},
+			ExpiresAt: jwt.NewNumericDate(accessExpiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+		Name:      user.Name,
+		Roles:     user.EffectiveRoles(),
+		SessionID: sessionID,
+	}
+	signedToken, err := uc.jwtKeys.Sign(claims)
+	if err != nil {
+		return nil, fmt.Errorf("tidak bisa menandatangani token: %w", err)
+	}
+
+	// 2. Buat refresh token acak; hanya hash-nya yang disimpan.
+	refreshToken, err := newRefreshToken()
+	if err != nil {
+		return nil, fmt.Errorf("tidak bisa membuat refresh token: %w", err)
+	}
+	stored := &RefreshToken{
+		Hash:            hashToken(refreshToken),
+		UserID:          userID,
+		SessionID:       sessionID,
+		AccessTokenID:   claims.ID,
+		AccessExpiresAt: accessExpiresAt,
+		CreatedAt:       now,
+		ExpiresAt:       now.Add(uc.tokenPolicy.RefreshTTL),
+	}
+	if err := uc.tokenRepo.CreateRefreshToken(ctx, stored); err != nil {
+		return nil, fmt.Errorf("tidak bisa menyimpan refresh token: %w", err)
+	}
+
+	return &TokenPair{
+		AccessToken:  signedToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(uc.tokenPolicy.AccessTTL.Seconds()),
+	}, nil
+}
+
+// revokeSession mencabut semua refresh token sebuah sesi beserta access token yang belum kedaluwarsa
+// yang diterbitkan bersamanya.
+func (uc *UserUsecaseImpl) revokeSession(ctx context.Context, sessionID string) error {
+	tokens, err := uc.tokenRepo.RevokeSession(ctx, sessionID, time.Now())
+	if err != nil {
+		return err
+	}
+	return uc.revokeAccessTokens(ctx, tokens)
+}
+
+// revokeUserSessions mencabut semua sesi seorang pengguna dan menutup koneksi real-time-nya.
+func (uc *UserUsecaseImpl) revokeUserSessions(ctx context.Context, userID string) error {
+	tokens, err := uc.tokenRepo.RevokeUserSessions(ctx, userID, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := uc.revokeAccessTokens(ctx, tokens); err != nil {
+		return err
+	}
+	uc.closeConnections(userID)
+	return nil
+}
+
+// revokeAccessTokens memasukkan access token yang belum kedaluwarsa dari refresh token yang dicabut
+// ke daftar pencabutan.
+func (uc *UserUsecaseImpl) revokeAccessTokens(ctx context.Context, tokens []*RefreshToken) error {
+	now := time.Now()
+	for _, t := range tokens {
+		if t.AccessTokenID == "" || !now.Before(t.AccessExpiresAt) {
+			continue
+		}
+		revoked := &RevokedToken{ID: t.AccessTokenID, UserID: t.UserID, RevokedAt: now, ExpiresAt: t.AccessExpiresAt}
+		if err := uc.tokenRepo.RevokeAccessToken(ctx, revoked); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// closeConnections menutup koneksi real-time pengguna setelah tokennya dicabut.
+func (uc *UserUsecaseImpl) closeConnections(userID string) {
+	if uc.connections != nil {
+		uc.connections.CloseUserConnections(userID, "token revoked")
+	}
+}
+
+// newRefreshToken membuat refresh token acak 256-bit yang di-encode base64url.
+func newRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken mengembalikan hash SHA-256 (hex) dari sebuah refresh token untuk disimpan dan dicari.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// loginKey adalah satu penghitung kegagalan login beserta batasnya.
+type loginKey struct {
+	scope       string
+	key         string
+	maxFailures int
+}
+
+// loginKeys mengembalikan penghitung kegagalan untuk IP (indeks 0) dan akun (indeks 1).
+// Akun diidentifikasi dari email yang dicoba, terdaftar maupun tidak.
+func (uc *UserUsecaseImpl) loginKeys(email, ip string) []loginKey {
+	return []loginKey{
+		{scope: LoginScopeIP, key: LoginScopeIP + ":" + ip, maxFailures: uc.loginPolicy.MaxIPFailures},
+		{scope: LoginScopeAccount, key: LoginScopeAccount + ":" + NormalizeEmail(email), maxFailures: uc.loginPolicy.MaxAccountFailures},
+	}
+}
+
+// checkLockout mengembalikan *LoginLockedError jika salah satu penghitung sedang dikunci.
+// Kegagalan membaca penyimpanan hanya dicatat di log agar login tetap bisa dipakai.
+func (uc *UserUsecaseImpl) checkLockout(ctx context.Context, keys []loginKey) error {
+	now := time.Now()
+	for _, k := range keys {
+		if k.maxFailures <= 0 {
+			continue
+		}
+		attempt, err := uc.attemptRepo.GetAttempt(ctx, k.key)
+		if err != nil {
+			log.Printf("failed to read login attempts for %s: %v", k.key, err)
+			continue
+		}
+		if now.Before(attempt.LockedUntil) {
+			return &LoginLockedError{RetryAfter: attempt.LockedUntil.Sub(now)}
+		}
+	}
+	return nil
+}
+
+// registerLoginFailure menambah satu kegagalan ke setiap penghitung. Penghitung yang mencapai batasnya
+// dikunci dengan durasi yang berlipat untuk setiap lockout berturut-turut, dan kejadiannya dicatat.
+func (uc *UserUsecaseImpl) registerLoginFailure(ctx context.Context, keys []loginKey, email, ip string) {
+	now := time.Now()
+	for _, k := range keys {
+		if k.maxFailures <= 0 {
+			continue
+		}
+
+		// 1. Tambah penghitung secara atomik. Kegagalan dan lockout lama dilupakan setelah FailureWindow
+		//    tanpa aktivitas (dihitung dari kegagalan terakhir atau akhir lockout, mana yang lebih akhir).
+		attempt, err := uc.attemptRepo.RecordFailure(ctx, k.key, now, now.Add(-uc.loginPolicy.FailureWindow))
+		if err != nil {
+			log.Printf("failed to record login failure for %s: %v", k.key, err)
+			continue
+		}
+		if attempt.Failures < k.maxFailures {
+			continue
+		}
+
+		// 2. Kunci jika batas tercapai, berdasarkan hitungan yang dikembalikan. Jika beberapa request
+		//    mencapai batas bersamaan, hanya satu yang berhasil mengunci dan mencatat kejadiannya.
+		lockedUntil := now.Add(uc.lockoutDuration(attempt.Lockouts))
+		locked, err := uc.attemptRepo.Lock(ctx, k.key, k.maxFailures, lockedUntil)
+		if err != nil {
+			log.Printf("failed to lock %s: %v", k.key, err)
+			continue
+		}
+		if !locked {
+			continue
+		}
+		event := &LockoutEvent{
+			ID:          uuid.NewString(),
+			Scope:       k.scope,
+			Key:         k.key,
+			IP:          ip,
+			Email:       email,
+			Failures:    attempt.Failures,
+			Lockouts:    attempt.Lockouts + 1,
+			LockedUntil: lockedUntil,
+			CreatedAt:   now,
+		}
+		log.Printf("login lockout: %s locked until %s after %d failures (lockout #%d)", k.key, event.LockedUntil.Format(time.RFC3339), event.Failures, event.Lockouts)
+		if err := uc.attemptRepo.CreateLockoutEvent(ctx, event); err != nil {
+			log.Printf("failed to record lockout event for %s: %v", k.key, err)
+		}
+	}
+}
+
+// lockoutDuration menghitung durasi lockout ke-(n+1): LockoutBase * 2^n, maksimal LockoutMax
+// (atau 24 jam jika LockoutMax tidak diatur).
+func (uc *UserUsecaseImpl) lockoutDuration(n int) time.Duration {
+	max := uc.loginPolicy.LockoutMax
+	if max <= 0 {
+		max = 24 * time.Hour
+	}
+	d := uc.loginPolicy.LockoutBase
+	for i := 0; i < n && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// dummyPasswordHash mengembalikan hash bcrypt tiruan untuk menyamakan waktu respons login
+// saat email tidak ditemukan. Hash dibuat sekali saat pertama kali dibutuhkan.
+func dummyPasswordHash() []byte {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte(uuid.NewString()), bcrypt.DefaultCost)
+	})
+	return dummyHash
 }
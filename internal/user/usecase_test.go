@@ -0,0 +1,245 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/auth"
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/jwtkeys"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestConcurrentLoginFailuresLockOnce(t *testing.T) {
+	ctx := context.Background()
+	keys, err := jwtkeys.NewHMACKeySet("test-secret")
+	if err != nil {
+		t.Fatalf("NewHMACKeySet: %v", err)
+	}
+	attempts := NewInMemoryLoginAttemptRepository()
+	policy := LoginPolicy{MaxAccountFailures: 5, FailureWindow: 15 * time.Minute, LockoutBase: time.Minute, LockoutMax: time.Hour}
+	uc := NewUserUsecase(NewInMemoryUserRepository(), attempts, NewInMemoryTokenRepository(), nil, policy, TokenPolicy{}, keys)
+	if _, err := uc.Create(ctx, "budi@example.com", "rahasia123", nil); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// Kegagalan yang datang bersamaan tidak boleh saling menimpa: tepat mencapai batas berarti tepat satu lockout.
+	var wg sync.WaitGroup
+	for i := 0; i < policy.MaxAccountFailures; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := uc.Login(ctx, "budi@example.com", "salah", fmt.Sprintf("203.0.113.%d", i))
+			if !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("wrong password: err = %v, want ErrInvalidCredentials", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if events := attempts.LockoutEvents(); len(events) != 1 {
+		t.Fatalf("lockout events = %d, want 1", len(events))
+	}
+	if _, err := uc.Login(ctx, "budi@example.com", "rahasia123", "203.0.113.99"); !errors.Is(err, ErrLoginLocked) {
+		t.Fatalf("login after lockout: err = %v, want ErrLoginLocked", err)
+	}
+}
+
+// recordingCloser adalah ConnectionCloser uji yang mencatat pengguna yang koneksinya ditutup.
+type recordingCloser struct {
+	mu     sync.Mutex
+	closed []string
+}
+
+func (c *recordingCloser) CloseUserConnections(userID, reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = append(c.closed, userID)
+}
+
+// sessionFixture berisi usecase dengan repository in-memory dan satu pengguna terdaftar.
+type sessionFixture struct {
+	uc     *UserUsecaseImpl
+	tokens *InMemoryTokenRepository
+	closer *recordingCloser
+	keys   *jwtkeys.KeySet
+	user   *User
+}
+
+const fixturePassword = "rahasia123"
+
+func newSessionFixture(t *testing.T) *sessionFixture {
+	t.Helper()
+	keys, err := jwtkeys.NewHMACKeySet("test-secret")
+	if err != nil {
+		t.Fatalf("NewHMACKeySet: %v", err)
+	}
+	f := &sessionFixture{tokens: NewInMemoryTokenRepository(), closer: &recordingCloser{}, keys: keys}
+	f.uc = NewUserUsecase(NewInMemoryUserRepository(), NewInMemoryLoginAttemptRepository(), f.tokens, f.closer,
+		LoginPolicy{}, TokenPolicy{AccessTTL: time.Minute, RefreshTTL: time.Hour}, keys)
+	if f.user, err = f.uc.Create(context.Background(), "budi@example.com", fixturePassword, nil); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return f
+}
+
+// login membuka sesi baru untuk pengguna fixture.
+func (f *sessionFixture) login(t *testing.T) *TokenPair {
+	t.Helper()
+	pair, err := f.uc.Login(context.Background(), "budi@example.com", fixturePassword, "203.0.113.1")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	return pair
+}
+
+// claims memverifikasi access token dan mengembalikan claims-nya.
+func (f *sessionFixture) claims(t *testing.T, pair *TokenPair) *auth.Claims {
+	t.Helper()
+	claims := &auth.Claims{}
+	if _, err := jwt.ParseWithClaims(pair.AccessToken, claims, f.keys.Keyfunc); err != nil {
+		t.Fatalf("parse access token: %v", err)
+	}
+	return claims
+}
+
+// accessRevoked melaporkan apakah access token dari pair sudah masuk daftar pencabutan.
+func (f *sessionFixture) accessRevoked(t *testing.T, pair *TokenPair) bool {
+	t.Helper()
+	revoked, err := f.tokens.IsAccessTokenRevoked(context.Background(), f.claims(t, pair).ID)
+	if err != nil {
+		t.Fatalf("IsAccessTokenRevoked: %v", err)
+	}
+	return revoked
+}
+
+func TestRefreshRotatesTokens(t *testing.T) {
+	ctx := context.Background()
+	f := newSessionFixture(t)
+	first := f.login(t)
+
+	second, err := f.uc.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.AccessToken == first.AccessToken {
+		t.Error("refresh returned the same tokens, want new ones")
+	}
+	firstClaims, secondClaims := f.claims(t, first), f.claims(t, second)
+	if secondClaims.SessionID != firstClaims.SessionID || secondClaims.Subject != f.user.ID {
+		t.Errorf("refreshed claims = sid %s sub %s, want sid %s sub %s", secondClaims.SessionID, secondClaims.Subject, firstClaims.SessionID, f.user.ID)
+	}
+
+	// Token baru bisa dirotasi lagi, dan rotasi biasa tidak mencabut apa pun.
+	if _, err := f.uc.Refresh(ctx, second.RefreshToken); err != nil {
+		t.Fatalf("second Refresh: %v", err)
+	}
+	if f.accessRevoked(t, first) || len(f.closer.closed) != 0 {
+		t.Error("a normal rotation revoked tokens or closed connections")
+	}
+
+	for _, token := range []string{"", "tidak-dikenal"} {
+		if _, err := f.uc.Refresh(ctx, token); !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Errorf("Refresh(%q): err = %v, want ErrInvalidRefreshToken", token, err)
+		}
+	}
+}
+
+func TestRefreshReuseRevokesSession(t *testing.T) {
+	ctx := context.Background()
+	f := newSessionFixture(t)
+	first := f.login(t)
+	other := f.login(t) // Sesi lain dari pengguna yang sama tidak ikut dicabut.
+
+	second, err := f.uc.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	// Token lama dipakai lagi: dianggap bocor.
+	if _, err := f.uc.Refresh(ctx, first.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("reused Refresh: err = %v, want ErrInvalidRefreshToken", err)
+	}
+	if _, err := f.uc.Refresh(ctx, second.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("Refresh with the rotated token: err = %v, want ErrInvalidRefreshToken after reuse", err)
+	}
+	if !f.accessRevoked(t, second) {
+		t.Error("access token of the rotated token was not revoked")
+	}
+	if !reflect.DeepEqual(f.closer.closed, []string{f.user.ID}) {
+		t.Errorf("closed connections = %v, want [%s]", f.closer.closed, f.user.ID)
+	}
+
+	if f.accessRevoked(t, other) {
+		t.Error("access token of another session was revoked")
+	}
+	if _, err := f.uc.Refresh(ctx, other.RefreshToken); err != nil {
+		t.Errorf("Refresh in another session: %v", err)
+	}
+}
+
+func TestLogoutRevokesSession(t *testing.T) {
+	ctx := context.Background()
+	f := newSessionFixture(t)
+	pair := f.login(t)
+	other := f.login(t)
+	claims := f.claims(t, pair)
+
+	if err := f.uc.Logout(ctx, f.user.ID, claims.ID, claims.SessionID, claims.ExpiresAt.Time); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+
+	if !f.accessRevoked(t, pair) {
+		t.Error("jti of the logged out token is not in the revocation list")
+	}
+	if _, err := f.uc.Refresh(ctx, pair.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("Refresh after logout: err = %v, want ErrInvalidRefreshToken", err)
+	}
+	if !reflect.DeepEqual(f.closer.closed, []string{f.user.ID}) {
+		t.Errorf("closed connections = %v, want [%s]", f.closer.closed, f.user.ID)
+	}
+	if f.accessRevoked(t, other) {
+		t.Error("logout revoked another session")
+	}
+}
+
+func TestRevokingAllSessionsClosesConnections(t *testing.T) {
+	tests := []struct {
+		name   string
+		revoke func(f *sessionFixture) error
+	}{
+		{name: "change password", revoke: func(f *sessionFixture) error {
+			actor := &auth.Principal{UserID: f.user.ID}
+			return f.uc.ChangePassword(context.Background(), actor, f.user.ID, fixturePassword, "rahasia456")
+		}},
+		{name: "delete", revoke: func(f *sessionFixture) error {
+			return f.uc.Delete(context.Background(), &auth.Principal{UserID: f.user.ID}, f.user.ID)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSessionFixture(t)
+			sessions := []*TokenPair{f.login(t), f.login(t)}
+
+			if err := tt.revoke(f); err != nil {
+				t.Fatalf("revoke: %v", err)
+			}
+
+			for i, pair := range sessions {
+				if !f.accessRevoked(t, pair) {
+					t.Errorf("session %d: access token not revoked", i)
+				}
+				if _, err := f.uc.Refresh(context.Background(), pair.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+					t.Errorf("session %d: Refresh err = %v, want ErrInvalidRefreshToken", i, err)
+				}
+			}
+			if !reflect.DeepEqual(f.closer.closed, []string{f.user.ID}) {
+				t.Errorf("closed connections = %v, want [%s]", f.closer.closed, f.user.ID)
+			}
+		})
+	}
+}
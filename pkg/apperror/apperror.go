@@ -0,0 +1,95 @@
+// Package apperror menyediakan error bertipe untuk lapisan domain. Setiap error memiliki jenis (Kind)
+// yang menentukan status HTTP, kode yang stabil untuk dibaca client, dan pesan yang aman ditampilkan.
+// Handler cukup mengembalikan error; HTTPErrorHandler yang memetakannya ke response JSON yang seragam.
+package apperror
+
+import (
+	"net/http"
+)
+
+// Kind adalah jenis error yang menentukan status HTTP-nya.
+type Kind string
+
+// Jenis error yang dikenali oleh HTTPErrorHandler.
+const (
+	KindValidation      Kind = "validation"        // 400 Bad Request
+	KindUnauthorized    Kind = "unauthorized"      // 401 Unauthorized
+	KindForbidden       Kind = "forbidden"         // 403 Forbidden
+	KindNotFound        Kind = "not_found"         // 404 Not Found
+	KindConflict        Kind = "conflict"          // 409 Conflict
+	KindTooManyRequests Kind = "too_many_requests" // 429 Too Many Requests
+	KindInternal        Kind = "internal"          // 500 Internal Server Error
+)
+
+// Error adalah error domain yang pesannya aman dikirim ke client.
+// Error biasanya dideklarasikan sebagai variabel sentinel di paket domain dan dibungkus dengan
+// fmt.Errorf("...: %w", err) untuk konteks log; konteks tersebut tidak pernah dikirim ke client.
+type Error struct {
+	Kind    Kind
+	Code    string // Kode yang bisa dibaca mesin, misal: "user_not_found".
+	Message string // Pesan untuk client.
+}
+
+// New membuat Error baru.
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// Validation membuat Error untuk input yang tidak valid (400).
+func Validation(code, message string) *Error { return New(KindValidation, code, message) }
+
+// Unauthorized membuat Error untuk kredensial atau token yang tidak valid (401).
+func Unauthorized(code, message string) *Error { return New(KindUnauthorized, code, message) }
+
+// Forbidden membuat Error untuk aksi yang tidak diizinkan (403).
+func Forbidden(code, message string) *Error { return New(KindForbidden, code, message) }
+
+// NotFound membuat Error untuk data yang tidak ditemukan (404).
+func NotFound(code, message string) *Error { return New(KindNotFound, code, message) }
+
+// Conflict membuat Error untuk data yang bentrok dengan data yang sudah ada (409).
+func Conflict(code, message string) *Error { return New(KindConflict, code, message) }
+
+// TooManyRequests membuat Error untuk request yang ditolak karena batas percobaan (429).
+func TooManyRequests(code, message string) *Error { return New(KindTooManyRequests, code, message) }
+
+// ErrInvalidBody dikembalikan handler jika body request tidak bisa dibaca.
+var ErrInvalidBody = Validation("invalid_body", "body request tidak valid")
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is membuat errors.Is mengenali Error dengan Code yang sama, termasuk salinan dari WithDetail.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithDetail mengembalikan salinan error dengan keterangan tambahan di pesannya, misal:
+// "password terlalu lemah: harus berisi huruf dan angka". Keterangan ikut dikirim ke client.
+func (e *Error) WithDetail(detail string) *Error {
+	copied := *e
+	copied.Message = e.Message + ": " + detail
+	return &copied
+}
+
+// StatusCode mengembalikan status HTTP untuk jenis error ini.
+func (e *Error) StatusCode() int {
+	switch e.Kind {
+	case KindValidation:
+		return http.StatusBadRequest
+	case KindUnauthorized:
+		return http.StatusUnauthorized
+	case KindForbidden:
+		return http.StatusForbidden
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindConflict:
+		return http.StatusConflict
+	case KindTooManyRequests:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
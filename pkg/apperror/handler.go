@@ -0,0 +1,72 @@
+package apperror
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// internalMessage adalah satu-satunya pesan yang dikirim ke client untuk error yang tidak dikenal.
+const internalMessage = "terjadi kesalahan pada server"
+
+// Response adalah body JSON untuk semua response error.
+type Response struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"` // Sama dengan header X-Request-Id, untuk mencari log terkait.
+}
+
+// HTTPErrorHandler adalah echo.HTTPErrorHandler terpusat untuk seluruh aplikasi.
+// *Error dipetakan berdasarkan Kind-nya dan *echo.HTTPError (misal: dari middleware atau rute yang tidak ada)
+// berdasarkan status-nya. Error lain dianggap error internal: detailnya hanya dicatat di log,
+// sedangkan client menerima pesan umum dengan status 500.
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	status, body := resolve(err)
+	body.RequestID = c.Response().Header().Get(echo.HeaderXRequestID)
+	if status >= http.StatusInternalServerError {
+		c.Logger().Errorf("%s %s failed (request_id=%s): %v", c.Request().Method, c.Path(), body.RequestID, err)
+	}
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(status)
+	} else {
+		err = c.JSON(status, body)
+	}
+	if err != nil {
+		c.Logger().Errorf("failed to write error response: %v", err)
+	}
+}
+
+// resolve menentukan status dan body response untuk err.
+func resolve(err error) (int, Response) {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		status := appErr.StatusCode()
+		if status >= http.StatusInternalServerError {
+			return status, Response{Code: string(KindInternal), Message: internalMessage}
+		}
+		return status, Response{Code: appErr.Code, Message: appErr.Message}
+	}
+
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		message, ok := httpErr.Message.(string)
+		if !ok {
+			message = http.StatusText(httpErr.Code)
+		}
+		return httpErr.Code, Response{Code: statusCode(httpErr.Code), Message: message}
+	}
+
+	return http.StatusInternalServerError, Response{Code: string(KindInternal), Message: internalMessage}
+}
+
+// statusCode mengubah status HTTP menjadi kode error, misal: 503 menjadi "service_unavailable".
+func statusCode(status int) string {
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
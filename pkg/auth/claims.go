@@ -0,0 +1,86 @@
+// Package auth mendefinisikan claims token JWT aplikasi dan cara membaca pengguna yang terautentikasi
+// (principal) dari request. Semua domain memakai tipe yang sama, sehingga token yang dibuat saat login
+// selalu bisa dibaca oleh endpoint mana pun.
+package auth
+
+import (
+	"time"
+
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/apperror"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// ContextKey adalah key di echo.Context tempat middleware JWT menyimpan *jwt.Token yang sudah divalidasi.
+const ContextKey = "user"
+
+// ErrNoPrincipal dikembalikan jika request tidak membawa token yang valid dengan Claims aplikasi.
+var ErrNoPrincipal = apperror.Unauthorized("invalid_token", "token tidak valid")
+
+// Claims adalah claims token akses aplikasi. Claim standar (sub, exp, jti, ...) berada di RegisteredClaims.
+type Claims struct {
+	jwt.RegisteredClaims
+	Name      string   `json:"name,omitempty"`   // Nama tampilan pengguna.
+	Roles     []string `json:"roles,omitempty"`  // Role pengguna, misal: "admin".
+	SessionID string   `json:"sid,omitempty"`    // Sesi login; sama untuk semua token hasil refresh.
+	Scopes    []string `json:"scopes,omitempty"` // Izin tambahan yang diberikan ke token.
+}
+
+// NewClaims mengembalikan Claims kosong untuk diisi saat parsing token (echojwt.Config.NewClaimsFunc).
+func NewClaims(c echo.Context) jwt.Claims {
+	return new(Claims)
+}
+
+// Principal adalah pengguna yang terautentikasi untuk sebuah request.
+type Principal struct {
+	UserID    string
+	Name      string // Kosong jika token tidak membawa nama.
+	Roles     []string
+	Scopes    []string
+	SessionID string
+	TokenID   string    // Claim `jti`, dipakai untuk mencabut token.
+	ExpiresAt time.Time // Waktu kedaluwarsa token.
+}
+
+// HasRole memeriksa apakah principal memiliki role tertentu.
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// DisplayName mengembalikan nama tampilan pengguna, atau ID-nya jika token tidak membawa nama.
+func (p *Principal) DisplayName() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return p.UserID
+}
+
+// PrincipalFromContext membaca principal dari token yang disimpan middleware JWT di echo.Context.
+func PrincipalFromContext(c echo.Context) (*Principal, error) {
+	token, ok := c.Get(ContextKey).(*jwt.Token)
+	if !ok {
+		return nil, ErrNoPrincipal
+	}
+	claims, ok := token.Claims.(*Claims)
+	if !ok || claims.Subject == "" {
+		return nil, ErrNoPrincipal
+	}
+
+	p := &Principal{
+		UserID:    claims.Subject,
+		Name:      claims.Name,
+		Roles:     claims.Roles,
+		Scopes:    claims.Scopes,
+		SessionID: claims.SessionID,
+		TokenID:   claims.ID,
+	}
+	if claims.ExpiresAt != nil {
+		p.ExpiresAt = claims.ExpiresAt.Time
+	}
+	return p, nil
+}
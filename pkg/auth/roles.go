@@ -0,0 +1,51 @@
+package auth
+
+// Role yang dikenal aplikasi. Pengguna tanpa role diperlakukan sebagai RoleUser.
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// Permission adalah satu aksi yang bisa diizinkan untuk sebuah role.
+type Permission string
+
+// Permission yang diperiksa oleh middleware dan usecase.
+const (
+	PermReadAnyUser   Permission = "users:read_any" // Membaca data pengguna lain.
+	PermCreateUser    Permission = "users:create"   // Membuat pengguna baru.
+	PermManageUsers   Permission = "users:manage"   // Melihat daftar, mengubah dan menghapus pengguna lain.
+	PermModerateRooms Permission = "rooms:moderate" // Menghapus pesan di room mana pun.
+	PermViewUsage     Permission = "usage:read"     // Melihat laporan pemakaian token.
+)
+
+// rolePermissions memetakan setiap role ke permission yang dimilikinya.
+var rolePermissions = map[string][]Permission{
+	RoleAdmin: {PermReadAnyUser, PermCreateUser, PermManageUsers, PermModerateRooms, PermViewUsage},
+	RoleUser:  {},
+}
+
+// ValidRole memeriksa apakah role dikenal aplikasi.
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// RoleHasPermission memeriksa apakah sebuah role memiliki permission tertentu.
+func RoleHasPermission(role string, perm Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Can memeriksa apakah salah satu role principal memiliki permission tertentu.
+func (p *Principal) Can(perm Permission) bool {
+	for _, role := range p.Roles {
+		if RoleHasPermission(role, perm) {
+			return true
+		}
+	}
+	return false
+}
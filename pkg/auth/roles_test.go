@@ -0,0 +1,42 @@
+package auth
+
+import "testing"
+
+func TestPrincipalCan(t *testing.T) {
+	tests := []struct {
+		name  string
+		roles []string
+		perm  Permission
+		want  bool
+	}{
+		{name: "user reads any user", roles: []string{RoleUser}, perm: PermReadAnyUser, want: false},
+		{name: "admin reads any user", roles: []string{RoleAdmin}, perm: PermReadAnyUser, want: true},
+		{name: "user creates user", roles: []string{RoleUser}, perm: PermCreateUser, want: false},
+		{name: "admin creates user", roles: []string{RoleAdmin}, perm: PermCreateUser, want: true},
+		{name: "user manages users", roles: []string{RoleUser}, perm: PermManageUsers, want: false},
+		{name: "admin manages users", roles: []string{RoleAdmin}, perm: PermManageUsers, want: true},
+		{name: "user moderates rooms", roles: []string{RoleUser}, perm: PermModerateRooms, want: false},
+		{name: "admin moderates rooms", roles: []string{RoleAdmin}, perm: PermModerateRooms, want: true},
+		{name: "user views usage", roles: []string{RoleUser}, perm: PermViewUsage, want: false},
+		{name: "admin views usage", roles: []string{RoleAdmin}, perm: PermViewUsage, want: true},
+		{name: "any role grants", roles: []string{RoleUser, RoleAdmin}, perm: PermModerateRooms, want: true},
+		{name: "no roles", roles: nil, perm: PermReadAnyUser, want: false},
+		{name: "unknown role", roles: []string{"superuser"}, perm: PermReadAnyUser, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Principal{UserID: "u1", Roles: tt.roles}
+			if got := p.Can(tt.perm); got != tt.want {
+				t.Errorf("Can(%s) with roles %v = %v, want %v", tt.perm, tt.roles, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidRole(t *testing.T) {
+	for role, want := range map[string]bool{RoleAdmin: true, RoleUser: true, "superuser": false, "": false} {
+		if got := ValidRole(role); got != want {
+			t.Errorf("ValidRole(%q) = %v, want %v", role, got, want)
+		}
+	}
+}
@@ -5,19 +5,30 @@ package bootstrap
 
 import (
 	"context"
+	"fmt"
 	"log"
 
 	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/config"
 	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/database"
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/jwtkeys"
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/llm"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// Penyimpanan data yang bisa dipilih melalui STORAGE.
+const (
+	StorageMongo  = "mongo"
+	StorageMemory = "memory" // Semua data disimpan di memori proses; untuk development dan pengujian.
+)
+
 // Application adalah container utama yang menampung semua dependensi inti aplikasi,
 // seperti konfigurasi (Env) dan koneksi database (Mongo).
 // Jika ada koneksi lain seperti Redis, ia akan ditambahkan di sini.
 type Application struct {
-	Env   *config.Config // Menyimpan semua konfigurasi dari environment.
-	Mongo *mongo.Client  // Client untuk koneksi ke MongoDB.
+	Env     *config.Config  // Menyimpan semua konfigurasi dari environment.
+	Mongo   *mongo.Client   // Client untuk koneksi ke MongoDB; nil jika STORAGE=memory.
+	LLM     llm.Provider    // Penyedia LLM untuk balasan AI di chat.
+	JWTKeys *jwtkeys.KeySet // Kunci untuk menandatangani dan memverifikasi token JWT.
 }
 
 // NewApplication memuat konfigurasi, menginisialisasi koneksi, dan mengembalikan container aplikasi.
@@ -28,23 +39,73 @@ func NewApplication() *Application {
 	// 1. Memuat semua konfigurasi dari environment.
 	app.Env = config.NewConfig()
 
-	// 2. Menggunakan konfigurasi untuk membuat koneksi ke MongoDB.
-	mongoClient, err := database.ConnectMongo(context.Background(), app.Env.MongoURI)
+	// 2. Menggunakan konfigurasi untuk membuat koneksi ke MongoDB, kecuali data disimpan di memori.
+	mongoClient, err := connectStorage(app.Env)
 	if err != nil {
-		log.Fatalf("Gagal terhubung ke MongoDB: %v", err)
+		log.Fatalf("Gagal menyiapkan penyimpanan: %v", err)
 	}
 	app.Mongo = mongoClient
-	log.Println("Berhasil terhubung ke MongoDB.")
+
+	// 3. Membuat penyedia LLM sesuai dengan LLM_PROVIDER.
+	llmProvider, err := NewLLMProvider(app.Env)
+	if err != nil {
+		log.Fatalf("Gagal membuat penyedia LLM: %v", err)
+	}
+	app.LLM = llmProvider
+	log.Printf("Menggunakan penyedia LLM: %s", app.Env.LLMProvider)
+
+	// 4. Memuat kunci JWT dari file PEM, atau memakai JWT_SECRET (HS256) jika tidak ada.
+	jwtKeys, err := NewJWTKeySet(app.Env)
+	if err != nil {
+		log.Fatalf("Gagal memuat kunci JWT: %v", err)
+	}
+	app.JWTKeys = jwtKeys
+	if kid := jwtKeys.SigningKeyID(); kid != "" {
+		log.Printf("Menandatangani token JWT dengan kunci %s", kid)
+	} else {
+		log.Println("Menandatangani token JWT dengan HS256 (JWT_SECRET)")
+	}
 
 	// Inisialisasi koneksi lain (misal: Redis) bisa ditambahkan di sini.
 
 	return app
 }
 
+// NewJWTKeySet membuat KeySet dari JWT_KEYS dan JWT_SIGNING_KID, atau KeySet HS256 dari JWT_SECRET
+// jika tidak ada kunci asimetris yang dikonfigurasi.
+func NewJWTKeySet(cfg *config.Config) (*jwtkeys.KeySet, error) {
+	if len(cfg.JWTKeyFiles) == 0 {
+		return jwtkeys.NewHMACKeySet(cfg.JWTSecret)
+	}
+	return jwtkeys.LoadKeySet(cfg.JWTKeyFiles, cfg.JWTSigningKeyID)
+}
+
+// connectStorage membuat koneksi ke MongoDB untuk STORAGE=mongo. Untuk STORAGE=memory tidak ada
+// koneksi yang dibuat dan client yang dikembalikan nil.
+func connectStorage(cfg *config.Config) (*mongo.Client, error) {
+	switch cfg.Storage {
+	case StorageMongo:
+		client, err := database.ConnectMongo(context.Background(), cfg.MongoURI)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to MongoDB: %w", err)
+		}
+		log.Println("Berhasil terhubung ke MongoDB.")
+		return client, nil
+	case StorageMemory:
+		log.Println("Menggunakan penyimpanan memori; data hilang saat aplikasi berhenti.")
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown storage %q (use %q or %q)", cfg.Storage, StorageMongo, StorageMemory)
+	}
+}
+
 // Close secara graceful menutup semua koneksi yang ada di dalam container aplikasi.
 // Fungsi ini dipanggil menggunakan `defer` di `main.go` untuk memastikan semua koneksi ditutup
 // saat aplikasi berhenti.
 func (app *Application) Close() {
+	if app.Mongo == nil {
+		return
+	}
 	if err := database.DisconnectMongo(context.Background(), app.Mongo); err != nil {
 		log.Fatalf("Gagal menutup koneksi MongoDB: %v", err)
 	}
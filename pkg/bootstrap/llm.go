@@ -0,0 +1,100 @@
+package bootstrap
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/config"
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/gemini"
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/llm"
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/ollama"
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/openai"
+)
+
+// Nama penyedia LLM yang bisa dipilih melalui LLM_PROVIDER.
+const (
+	LLMProviderGemini = "gemini"
+	LLMProviderOpenAI = "openai"
+	LLMProviderOllama = "ollama"
+	LLMProviderFake   = "fake"
+)
+
+// NewLLMProvider membuat penyedia LLM sesuai dengan config. Jika LLM_FALLBACKS diisi, penyedia utama
+// dibungkus llm.FallbackProvider sehingga penyedia/model cadangan dicoba berurutan saat yang utama gagal.
+func NewLLMProvider(cfg *config.Config) (llm.Provider, error) {
+	// 1. Penyedia utama dari LLM_PROVIDER.
+	primary, err := newLLMProvider(cfg, cfg.LLMProvider, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.LLMFallbacks) == 0 {
+		return primary, nil
+	}
+
+	// 2. Penyedia cadangan dengan format "provider:model" (model boleh dikosongkan).
+	providers := []llm.Provider{primary}
+	names := []string{cfg.LLMProvider}
+	for _, spec := range cfg.LLMFallbacks {
+		name, model, _ := strings.Cut(spec, ":")
+		provider, err := newLLMProvider(cfg, name, model)
+		if err != nil {
+			return nil, fmt.Errorf("LLM_FALLBACKS %q: %w", spec, err)
+		}
+		providers = append(providers, provider)
+		names = append(names, spec)
+	}
+	return llm.NewFallbackProvider(providers, names), nil
+}
+
+// newLLMProvider membuat satu penyedia LLM berdasarkan namanya. Model kosong berarti memakai model
+// dari config (GEMINI_MODEL untuk "gemini", LLM_MODEL untuk yang lain).
+func newLLMProvider(cfg *config.Config, name, model string) (llm.Provider, error) {
+	switch name {
+	case LLMProviderGemini:
+		if cfg.GeminiAPIKey == "" {
+			return nil, fmt.Errorf("GEMINI_API_KEY wajib diisi untuk LLM_PROVIDER=%s", LLMProviderGemini)
+		}
+		if model == "" {
+			model = cfg.GeminiModel
+		}
+		return gemini.NewClientWithConfig(gemini.ClientConfig{
+			APIKey:  cfg.GeminiAPIKey,
+			BaseURL: cfg.GeminiBaseURL,
+			Model:   model,
+			Generation: llm.GenerationOptions{
+				Temperature:      cfg.GeminiTemperature,
+				TopP:             cfg.GeminiTopP,
+				TopK:             cfg.GeminiTopK,
+				MaxOutputTokens:  cfg.GeminiMaxOutputTokens,
+				StopSequences:    cfg.GeminiStopSequences,
+				CandidateCount:   cfg.GeminiCandidateCount,
+				Seed:             cfg.GeminiSeed,
+				PresencePenalty:  cfg.GeminiPresencePenalty,
+				FrequencyPenalty: cfg.GeminiFrequencyPenalty,
+			},
+			Timeout:       cfg.GeminiTimeout,
+			StreamTimeout: cfg.GeminiStreamTimeout,
+			Retry: gemini.RetryPolicy{
+				MaxAttempts: cfg.GeminiRetryMaxAttempts,
+				BaseDelay:   cfg.GeminiRetryBaseDelay,
+				MaxDelay:    cfg.GeminiRetryMaxDelay,
+			},
+			BreakerThreshold: cfg.GeminiBreakerThreshold,
+			BreakerCooldown:  cfg.GeminiBreakerCooldown,
+		}), nil
+	case LLMProviderOpenAI:
+		if model == "" {
+			model = cfg.LLMModel
+		}
+		return openai.NewClient(cfg.LLMBaseURL, cfg.LLMAPIKey, model, cfg.LLMTimeout), nil
+	case LLMProviderOllama:
+		if model == "" {
+			model = cfg.LLMModel
+		}
+		return ollama.NewClient(cfg.LLMBaseURL, model, cfg.LLMTimeout), nil
+	case LLMProviderFake:
+		return llm.NewFakeProvider(cfg.FakeLLMReplies, cfg.FakeLLMLatency, cfg.FakeLLMFailEvery), nil
+	default:
+		return nil, fmt.Errorf("penyedia LLM tidak dikenal: %q", name)
+	}
+}
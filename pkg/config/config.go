@@ -3,8 +3,13 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -12,13 +17,120 @@ import (
 // Config menampung semua variabel konfigurasi aplikasi yang diambil dari environment.
 type Config struct {
 	AppPort       string `env:"APP_PORT,required"`
-	MongoURI      string `env:"MONGO_URI,required"`
-	MongoDbName   string `env:"MONGO_DB_NAME,required"`
-	JWTSecret     string `env:"JWT_SECRET,required"`
-	BasicAuthUser string `env:"BASIC_AUTH_USER,required"`
-	BasicAuthPass string `env:"BASIC_AUTH_PASS,required"`
-	PromptTema    string `env:"PROMPT_TEMA,required"`
-	GeminiAPIKey  string `env:"GEMINI_API_KEY,required"`
+	Storage       string `env:"STORAGE"`              // Penyimpanan data: "mongo" (default) atau "memory" (tanpa MongoDB, data hilang saat restart).
+	MongoURI      string `env:"MONGO_URI"`            // Wajib jika Storage adalah "mongo".
+	MongoDbName   string `env:"MONGO_DB_NAME"`        // Wajib jika Storage adalah "mongo".
+	JWTSecret     string `env:"JWT_SECRET"`           // Wajib jika JWTKeyFiles kosong (fallback HS256).
+	PromptTema    string `env:"PROMPT_TEMA,required"` // Persona AI, dikirim sebagai system instruction. Mendukung placeholder {{room_id}}, {{user_name}}, {{date}}.
+	WelcomePrompt string `env:"WELCOME_PROMPT"`       // Prompt pengguna untuk membuat pesan sambutan room.
+	GeminiAPIKey  string `env:"GEMINI_API_KEY"`       // Wajib jika LLMProvider adalah "gemini".
+
+	// IP atau CIDR reverse proxy yang boleh mengisi X-Forwarded-For, dipisah koma. Jika kosong,
+	// IP client diambil langsung dari koneksi (misal: untuk lockout login per IP).
+	TrustedProxies []string `env:"TRUSTED_PROXIES"`
+
+	// Model, endpoint, dan pengaturan generasi default untuk penyedia "gemini".
+	// Pengaturan generasi yang tidak di-set (nil) memakai default dari model.
+	GeminiModel            string   `env:"GEMINI_MODEL"`
+	GeminiBaseURL          string   `env:"GEMINI_BASE_URL"` // Termasuk versi API, misal: ".../v1beta".
+	GeminiTemperature      *float64 `env:"GEMINI_TEMPERATURE"`
+	GeminiTopP             *float64 `env:"GEMINI_TOP_P"`
+	GeminiTopK             *int     `env:"GEMINI_TOP_K"`
+	GeminiMaxOutputTokens  *int     `env:"GEMINI_MAX_OUTPUT_TOKENS"`
+	GeminiCandidateCount   *int     `env:"GEMINI_CANDIDATE_COUNT"`
+	GeminiStopSequences    []string `env:"GEMINI_STOP_SEQUENCES"` // Dipisah dengan "|".
+	GeminiSeed             *int     `env:"GEMINI_SEED"`
+	GeminiPresencePenalty  *float64 `env:"GEMINI_PRESENCE_PENALTY"`
+	GeminiFrequencyPenalty *float64 `env:"GEMINI_FREQUENCY_PENALTY"`
+
+	// Timeout, retry, dan circuit breaker untuk panggilan ke Gemini.
+	GeminiTimeout          time.Duration `env:"GEMINI_TIMEOUT"`            // Batas waktu satu percobaan non-streaming.
+	GeminiStreamTimeout    time.Duration `env:"GEMINI_STREAM_TIMEOUT"`     // Batas waktu satu percobaan streaming, termasuk membaca stream.
+	GeminiRetryMaxAttempts int           `env:"GEMINI_RETRY_MAX_ATTEMPTS"` // Jumlah percobaan total; 1 untuk menonaktifkan retry.
+	GeminiRetryBaseDelay   time.Duration `env:"GEMINI_RETRY_BASE_DELAY"`
+	GeminiRetryMaxDelay    time.Duration `env:"GEMINI_RETRY_MAX_DELAY"`   // Retry-After yang lebih lama dari ini tidak ditunggu.
+	GeminiBreakerThreshold int           `env:"GEMINI_BREAKER_THRESHOLD"` // Kegagalan berturut-turut sebelum breaker terbuka; < 0 untuk menonaktifkan.
+	GeminiBreakerCooldown  time.Duration `env:"GEMINI_BREAKER_COOLDOWN"`
+
+	// Penyedia LLM yang dipakai chat: "gemini", "openai" (API yang kompatibel dengan OpenAI),
+	// "ollama", atau "fake" (deterministik, untuk pengujian dan development lokal).
+	LLMProvider string `env:"LLM_PROVIDER"`
+	// Pengaturan untuk penyedia "openai" dan "ollama". Nilai kosong memakai default penyedia.
+	LLMBaseURL string `env:"LLM_BASE_URL"`
+	LLMModel   string `env:"LLM_MODEL"`
+	LLMAPIKey  string `env:"LLM_API_KEY"`
+	// Batas waktu satu request ke penyedia "openai" dan "ollama", termasuk membaca stream balasan.
+	LLMTimeout time.Duration `env:"LLM_TIMEOUT"`
+	// Penyedia/model cadangan yang dicoba berurutan saat penyedia utama gagal,
+	// dengan format "provider:model" dipisah koma, misal: "gemini:gemini-2.0-flash,ollama:llama3.1".
+	LLMFallbacks []string `env:"LLM_FALLBACKS"`
+	// Pengaturan untuk penyedia "fake".
+	FakeLLMReplies   []string      `env:"FAKE_LLM_REPLIES"`    // Balasan yang dipakai bergiliran, dipisah dengan "|".
+	FakeLLMLatency   time.Duration `env:"FAKE_LLM_LATENCY"`    // Jeda sebelum setiap potongan balasan.
+	FakeLLMFailEvery int           `env:"FAKE_LLM_FAIL_EVERY"` // Jika > 0, setiap panggilan ke-N gagal.
+
+	// Harga per model untuk perkiraan biaya di laporan pemakaian, dengan format
+	// "model=input:output" dipisah koma, dalam satuan harga per satu juta token.
+	ModelPrices map[string]ModelPrice `env:"MODEL_PRICES"`
+
+	// Akun admin awal yang dibuat saat startup jika email tersebut belum terdaftar.
+	// Admin lain dibuat melalui POST /v1/users oleh admin yang sudah ada.
+	AdminEmail    string `env:"ADMIN_EMAIL"`
+	AdminPassword string `env:"ADMIN_PASSWORD"`
+
+	// Kunci asimetris (RS256/EdDSA) untuk token JWT dengan format "kid=path/ke/kunci.pem" dipisah koma.
+	// JWTSigningKeyID adalah kunci yang dipakai untuk menandatangani; kunci lain hanya untuk verifikasi
+	// selama rotasi. Jika kosong, token ditandatangani dengan HS256 memakai JWTSecret.
+	JWTKeyFiles     map[string]string `env:"JWT_KEYS"`
+	JWTSigningKeyID string            `env:"JWT_SIGNING_KID"`
+
+	// Masa berlaku token: access token JWT dibuat singkat dan diperbarui dengan refresh token,
+	// yang dirotasi setiap kali dipakai.
+	JWTAccessTTL  time.Duration `env:"JWT_ACCESS_TTL"`
+	JWTRefreshTTL time.Duration `env:"JWT_REFRESH_TTL"`
+
+	// Perlindungan brute-force pada login: batas kegagalan per akun dan per IP sebelum dikunci,
+	// dengan lockout progresif dari LoginLockoutBase (digandakan setiap lockout) sampai LoginLockoutMax.
+	LoginMaxAccountFailures int           `env:"LOGIN_MAX_ACCOUNT_FAILURES"`
+	LoginMaxIPFailures      int           `env:"LOGIN_MAX_IP_FAILURES"`
+	LoginFailureWindow      time.Duration `env:"LOGIN_FAILURE_WINDOW"` // Kegagalan dilupakan setelah tidak ada percobaan selama ini.
+	LoginLockoutBase        time.Duration `env:"LOGIN_LOCKOUT_BASE"`
+	LoginLockoutMax         time.Duration `env:"LOGIN_LOCKOUT_MAX"`
+
+	// Kuota chat per pengguna dan per room; 0 berarti tanpa batas.
+	// Pesan per menit membatasi pesan masuk, sedangkan panggilan dan token per hari (UTC) membatasi balasan AI.
+	QuotaUserMessagesPerMinute int64 `env:"QUOTA_USER_MESSAGES_PER_MINUTE"`
+	QuotaRoomMessagesPerMinute int64 `env:"QUOTA_ROOM_MESSAGES_PER_MINUTE"`
+	QuotaUserTypingPerMinute   int64 `env:"QUOTA_USER_TYPING_PER_MINUTE"` // Frame `typing` yang melampaui batas dibuang.
+	QuotaUserAICallsPerDay     int64 `env:"QUOTA_USER_AI_CALLS_PER_DAY"`
+	QuotaRoomAICallsPerDay     int64 `env:"QUOTA_ROOM_AI_CALLS_PER_DAY"`
+	QuotaUserTokensPerDay      int64 `env:"QUOTA_USER_TOKENS_PER_DAY"`
+	QuotaRoomTokensPerDay      int64 `env:"QUOTA_ROOM_TOKENS_PER_DAY"`
+
+	// Batas riwayat percakapan yang dikirim ke AI. Giliran terlama dibuang lebih dulu.
+	AIHistoryMaxMessages int `env:"AI_HISTORY_MAX_MESSAGES"`
+	AIHistoryMaxTokens   int `env:"AI_HISTORY_MAX_TOKENS"` // Perkiraan jumlah token, bukan hitungan pasti.
+
+	// Jumlah pesan terakhir yang dikirim ke client saat bergabung ke room.
+	ChatHistoryLimit int `env:"CHAT_HISTORY_LIMIT"`
+
+	// Ukuran buffer pesan keluar per koneksi WebSocket dan kebijakan saat buffer penuh
+	// ("drop" untuk membuang pesan, "disconnect" untuk menutup koneksi).
+	WSSendBufferSize     int    `env:"WS_SEND_BUFFER_SIZE"`
+	WSSlowConsumerPolicy string `env:"WS_SLOW_CONSUMER_POLICY"`
+
+	// Heartbeat dan batas koneksi WebSocket.
+	WSPingInterval   time.Duration `env:"WS_PING_INTERVAL"`    // Jeda pengiriman ping ke client; harus lebih kecil dari WSPongWait.
+	WSPongWait       time.Duration `env:"WS_PONG_WAIT"`        // Batas waktu menunggu pong sebelum koneksi dianggap mati.
+	WSWriteWait      time.Duration `env:"WS_WRITE_WAIT"`       // Batas waktu untuk satu penulisan ke client.
+	WSMaxMessageSize int64         `env:"WS_MAX_MESSAGE_SIZE"` // Ukuran maksimal satu pesan masuk (byte).
+	WSIdleTimeout    time.Duration `env:"WS_IDLE_TIMEOUT"`     // Koneksi ditutup jika client tidak mengirim pesan selama ini; 0 untuk menonaktifkan.
+}
+
+// ModelPrice adalah harga sebuah model per satu juta token.
+type ModelPrice struct {
+	InputPerMillion  float64 // Harga token prompt.
+	OutputPerMillion float64 // Harga token balasan.
 }
 
 // NewConfig membuat instance Config baru dengan membaca environment variables.
@@ -30,20 +142,114 @@ func NewConfig() *Config {
 		log.Println("No .env file found, reading variables from environment.")
 	}
 
-	return &Config{
+	// Koneksi MongoDB hanya dibutuhkan jika data disimpan di MongoDB.
+	storage := getEnvWithFallback("STORAGE", "mongo")
+	requireMongo := storage == "mongo"
+
+	cfg := &Config{
 		// Untuk AppPort, nilai default diberikan jika tidak ada di environment.
-		AppPort: getEnvWithFallback("APP_PORT", "8080"),
+		AppPort:        getEnvWithFallback("APP_PORT", "8080"),
+		Storage:        storage,
+		TrustedProxies: getEnvListWithFallback("TRUSTED_PROXIES", ",", nil),
 
 		// Untuk variabel krusial, aplikasi akan berhenti jika tidak di-set.
-		JWTSecret:     getEnvOrFatal("JWT_SECRET"),
-		BasicAuthUser: getEnvOrFatal("BASIC_AUTH_USER"),
-		BasicAuthPass: getEnvOrFatal("BASIC_AUTH_PASS"),
-		MongoURI:      getEnvOrFatal("MONGO_URI"),
-		MongoDbName:   getEnvOrFatal("MONGO_DB"),
+		JWTSecret:   getEnvWithFallback("JWT_SECRET", ""),
+		MongoURI:    getEnvOrFatalIf(requireMongo, "MONGO_URI"),
+		MongoDbName: getEnvOrFatalIf(requireMongo, "MONGO_DB"),
+
+		PromptTema:    getEnvOrFatal("PROMPT_TEMA"),
+		WelcomePrompt: getEnvWithFallback("WELCOME_PROMPT", "Sapa pengunjung yang baru bergabung dan perkenalkan dirimu secara singkat."),
+		GeminiAPIKey:  getEnvWithFallback("GEMINI_API_KEY", ""),
+
+		GeminiModel:            getEnvWithFallback("GEMINI_MODEL", "gemini-2.5-flash"),
+		GeminiBaseURL:          getEnvWithFallback("GEMINI_BASE_URL", "https://generativelanguage.googleapis.com/v1"),
+		GeminiTemperature:      getEnvFloatOptional("GEMINI_TEMPERATURE"),
+		GeminiTopP:             getEnvFloatOptional("GEMINI_TOP_P"),
+		GeminiTopK:             getEnvIntOptional("GEMINI_TOP_K"),
+		GeminiMaxOutputTokens:  getEnvIntOptional("GEMINI_MAX_OUTPUT_TOKENS"),
+		GeminiCandidateCount:   getEnvIntOptional("GEMINI_CANDIDATE_COUNT"),
+		GeminiStopSequences:    getEnvListWithFallback("GEMINI_STOP_SEQUENCES", "|", nil),
+		GeminiSeed:             getEnvIntOptional("GEMINI_SEED"),
+		GeminiPresencePenalty:  getEnvFloatOptional("GEMINI_PRESENCE_PENALTY"),
+		GeminiFrequencyPenalty: getEnvFloatOptional("GEMINI_FREQUENCY_PENALTY"),
+
+		GeminiTimeout:          getEnvDurationWithFallback("GEMINI_TIMEOUT", 30*time.Second),
+		GeminiStreamTimeout:    getEnvDurationWithFallback("GEMINI_STREAM_TIMEOUT", 2*time.Minute),
+		GeminiRetryMaxAttempts: getEnvIntWithFallback("GEMINI_RETRY_MAX_ATTEMPTS", 3),
+		GeminiRetryBaseDelay:   getEnvDurationWithFallback("GEMINI_RETRY_BASE_DELAY", 500*time.Millisecond),
+		GeminiRetryMaxDelay:    getEnvDurationWithFallback("GEMINI_RETRY_MAX_DELAY", 10*time.Second),
+		GeminiBreakerThreshold: getEnvIntWithFallback("GEMINI_BREAKER_THRESHOLD", 5),
+		GeminiBreakerCooldown:  getEnvDurationWithFallback("GEMINI_BREAKER_COOLDOWN", 30*time.Second),
+
+		LLMProvider:      getEnvWithFallback("LLM_PROVIDER", "gemini"),
+		LLMBaseURL:       getEnvWithFallback("LLM_BASE_URL", ""),
+		LLMModel:         getEnvWithFallback("LLM_MODEL", ""),
+		LLMAPIKey:        getEnvWithFallback("LLM_API_KEY", ""),
+		LLMTimeout:       getEnvDurationWithFallback("LLM_TIMEOUT", 2*time.Minute),
+		LLMFallbacks:     getEnvListWithFallback("LLM_FALLBACKS", ",", nil),
+		FakeLLMReplies:   getEnvListWithFallback("FAKE_LLM_REPLIES", "|", nil),
+		FakeLLMLatency:   getEnvDurationWithFallback("FAKE_LLM_LATENCY", 0),
+		FakeLLMFailEvery: getEnvIntWithFallback("FAKE_LLM_FAIL_EVERY", 0),
+
+		ModelPrices: getEnvModelPrices("MODEL_PRICES"),
+
+		AdminEmail:    getEnvWithFallback("ADMIN_EMAIL", ""),
+		AdminPassword: getEnvWithFallback("ADMIN_PASSWORD", ""),
+
+		JWTKeyFiles:     getEnvMap("JWT_KEYS"),
+		JWTSigningKeyID: getEnvWithFallback("JWT_SIGNING_KID", ""),
+
+		JWTAccessTTL:  getEnvDurationWithFallback("JWT_ACCESS_TTL", 15*time.Minute),
+		JWTRefreshTTL: getEnvDurationWithFallback("JWT_REFRESH_TTL", 30*24*time.Hour),
+
+		LoginMaxAccountFailures: getEnvIntWithFallback("LOGIN_MAX_ACCOUNT_FAILURES", 5),
+		LoginMaxIPFailures:      getEnvIntWithFallback("LOGIN_MAX_IP_FAILURES", 20),
+		LoginFailureWindow:      getEnvDurationWithFallback("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+		LoginLockoutBase:        getEnvDurationWithFallback("LOGIN_LOCKOUT_BASE", time.Minute),
+		LoginLockoutMax:         getEnvDurationWithFallback("LOGIN_LOCKOUT_MAX", time.Hour),
+
+		QuotaUserMessagesPerMinute: int64(getEnvIntWithFallback("QUOTA_USER_MESSAGES_PER_MINUTE", 20)),
+		QuotaRoomMessagesPerMinute: int64(getEnvIntWithFallback("QUOTA_ROOM_MESSAGES_PER_MINUTE", 60)),
+		QuotaUserTypingPerMinute:   int64(getEnvIntWithFallback("QUOTA_USER_TYPING_PER_MINUTE", 60)),
+		QuotaUserAICallsPerDay:     int64(getEnvIntWithFallback("QUOTA_USER_AI_CALLS_PER_DAY", 200)),
+		QuotaRoomAICallsPerDay:     int64(getEnvIntWithFallback("QUOTA_ROOM_AI_CALLS_PER_DAY", 1000)),
+		QuotaUserTokensPerDay:      int64(getEnvIntWithFallback("QUOTA_USER_TOKENS_PER_DAY", 200000)),
+		QuotaRoomTokensPerDay:      int64(getEnvIntWithFallback("QUOTA_ROOM_TOKENS_PER_DAY", 1000000)),
+
+		AIHistoryMaxMessages: getEnvIntWithFallback("AI_HISTORY_MAX_MESSAGES", 20),
+		AIHistoryMaxTokens:   getEnvIntWithFallback("AI_HISTORY_MAX_TOKENS", 4000),
+
+		ChatHistoryLimit: getEnvIntWithFallback("CHAT_HISTORY_LIMIT", 50),
+
+		WSSendBufferSize:     getEnvIntWithFallback("WS_SEND_BUFFER_SIZE", 64),
+		WSSlowConsumerPolicy: getEnvWithFallback("WS_SLOW_CONSUMER_POLICY", "disconnect"),
+
+		WSPingInterval:   getEnvDurationWithFallback("WS_PING_INTERVAL", 30*time.Second),
+		WSPongWait:       getEnvDurationWithFallback("WS_PONG_WAIT", 60*time.Second),
+		WSWriteWait:      getEnvDurationWithFallback("WS_WRITE_WAIT", 10*time.Second),
+		WSMaxMessageSize: int64(getEnvIntWithFallback("WS_MAX_MESSAGE_SIZE", 8192)),
+		WSIdleTimeout:    getEnvDurationWithFallback("WS_IDLE_TIMEOUT", 15*time.Minute),
+	}
 
-		PromptTema:   getEnvOrFatal("PROMPT_TEMA"),
-		GeminiAPIKey: getEnvOrFatal("GEMINI_API_KEY"),
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("FATAL ERROR: Invalid configuration: %v", err)
 	}
+	return cfg
+}
+
+// Validate memeriksa kombinasi nilai konfigurasi yang tidak bisa diperiksa per variabel.
+func (c *Config) Validate() error {
+	// Read deadline hanya diperpanjang saat pong diterima, sehingga ping harus dikirim sebelum
+	// WSPongWait habis; jika tidak, setiap koneksi yang sehat akan diputus.
+	if c.WSPongWait > 0 {
+		if c.WSPingInterval <= 0 {
+			return errors.New("WS_PING_INTERVAL must be set when WS_PONG_WAIT is set")
+		}
+		if c.WSPingInterval >= c.WSPongWait {
+			return fmt.Errorf("WS_PING_INTERVAL (%s) must be shorter than WS_PONG_WAIT (%s)", c.WSPingInterval, c.WSPongWait)
+		}
+	}
+	return nil
 }
 
 // getEnvWithFallback membaca environment variable berdasarkan key, atau mengembalikan nilai fallback jika tidak ada.
@@ -54,6 +260,111 @@ func getEnvWithFallback(key, fallback string) string {
 	return fallback
 }
 
+// getEnvIntWithFallback membaca environment variable berupa angka, atau mengembalikan nilai fallback jika tidak ada.
+// Aplikasi akan berhenti jika nilainya bukan angka yang valid.
+func getEnvIntWithFallback(key string, fallback int) int {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("FATAL ERROR: Environment variable %s must be an integer: %v", key, err)
+	}
+	return n
+}
+
+// getEnvIntOptional membaca environment variable berupa angka, atau mengembalikan nil jika tidak ada.
+// Aplikasi akan berhenti jika nilainya bukan angka yang valid.
+func getEnvIntOptional(key string) *int {
+	if _, ok := os.LookupEnv(key); !ok {
+		return nil
+	}
+	n := getEnvIntWithFallback(key, 0)
+	return &n
+}
+
+// getEnvFloatOptional membaca environment variable berupa bilangan desimal, atau mengembalikan nil jika tidak ada.
+// Aplikasi akan berhenti jika nilainya bukan bilangan yang valid.
+func getEnvFloatOptional(key string) *float64 {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Fatalf("FATAL ERROR: Environment variable %s must be a number: %v", key, err)
+	}
+	return &f
+}
+
+// getEnvDurationWithFallback membaca environment variable berupa durasi (misal: "30s", "5m"),
+// atau mengembalikan nilai fallback jika tidak ada. Aplikasi akan berhenti jika formatnya tidak valid.
+func getEnvDurationWithFallback(key string, fallback time.Duration) time.Duration {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("FATAL ERROR: Environment variable %s must be a duration: %v", key, err)
+	}
+	return d
+}
+
+// getEnvListWithFallback membaca environment variable berupa daftar yang dipisah dengan sep,
+// atau mengembalikan nilai fallback jika tidak ada. Elemen kosong diabaikan.
+func getEnvListWithFallback(key, sep string, fallback []string) []string {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	var list []string
+	for _, item := range strings.Split(value, sep) {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvMap membaca daftar pasangan dengan format "key=value,key=value".
+// Aplikasi akan berhenti jika formatnya tidak valid.
+func getEnvMap(key string) map[string]string {
+	values := make(map[string]string)
+	for _, item := range getEnvListWithFallback(key, ",", nil) {
+		k, v, ok := strings.Cut(item, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			log.Fatalf("FATAL ERROR: Environment variable %s must use the format key=value, got %q", key, item)
+		}
+		values[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return values
+}
+
+// getEnvModelPrices membaca daftar harga model dengan format "model=input:output,model=input:output".
+// Aplikasi akan berhenti jika formatnya tidak valid.
+func getEnvModelPrices(key string) map[string]ModelPrice {
+	prices := make(map[string]ModelPrice)
+	for _, item := range getEnvListWithFallback(key, ",", nil) {
+		model, price, ok := strings.Cut(item, "=")
+		input, output, ok2 := strings.Cut(price, ":")
+		if !ok || !ok2 {
+			log.Fatalf("FATAL ERROR: Environment variable %s must use the format model=input:output, got %q", key, item)
+		}
+		in, err := strconv.ParseFloat(strings.TrimSpace(input), 64)
+		if err != nil {
+			log.Fatalf("FATAL ERROR: Environment variable %s has an invalid input price for %s: %v", key, model, err)
+		}
+		out, err := strconv.ParseFloat(strings.TrimSpace(output), 64)
+		if err != nil {
+			log.Fatalf("FATAL ERROR: Environment variable %s has an invalid output price for %s: %v", key, model, err)
+		}
+		prices[strings.TrimSpace(model)] = ModelPrice{InputPerMillion: in, OutputPerMillion: out}
+	}
+	return prices
+}
+
 // getEnvOrFatal membaca environment variable berdasarkan key, atau menghentikan aplikasi jika tidak ada.
 func getEnvOrFatal(key string) string {
 	if value, ok := os.LookupEnv(key); ok {
@@ -63,3 +374,11 @@ func getEnvOrFatal(key string) string {
 	log.Fatalf("FATAL ERROR: Required environment variable not set: %s", key)
 	return ""
 }
+
+// getEnvOrFatalIf sama dengan getEnvOrFatal jika required bernilai true; selain itu variabel boleh kosong.
+func getEnvOrFatalIf(required bool, key string) string {
+	if required {
+		return getEnvOrFatal(key)
+	}
+	return getEnvWithFallback(key, "")
+}
@@ -0,0 +1,30 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateWebSocketKeepalive(t *testing.T) {
+	tests := []struct {
+		name    string
+		ping    time.Duration
+		pong    time.Duration
+		wantErr bool
+	}{
+		{name: "ping shorter than pong wait", ping: 30 * time.Second, pong: time.Minute},
+		{name: "keepalive disabled", ping: 0, pong: 0},
+		{name: "ping without pong wait", ping: 30 * time.Second, pong: 0},
+		{name: "ping equal to pong wait", ping: time.Minute, pong: time.Minute, wantErr: true},
+		{name: "ping longer than pong wait", ping: 2 * time.Minute, pong: time.Minute, wantErr: true},
+		{name: "pong wait without ping", ping: 0, pong: time.Minute, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{WSPingInterval: tt.ping, WSPongWait: tt.pong}
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
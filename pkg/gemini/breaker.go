@@ -0,0 +1,108 @@
+package gemini
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/llm"
+)
+
+// ErrCircuitOpen dikembalikan tanpa menghubungi Gemini selama circuit breaker terbuka.
+var ErrCircuitOpen = fmt.Errorf("gemini circuit breaker is open: %w", llm.ErrUnavailable)
+
+// Status circuit breaker.
+const (
+	breakerClosed   = iota // Normal: semua panggilan diteruskan.
+	breakerOpen            // Upstream dianggap tidak sehat: semua panggilan langsung gagal.
+	breakerHalfOpen        // Masa uji: satu panggilan diteruskan untuk memeriksa pemulihan upstream.
+)
+
+// circuitBreaker menghentikan panggilan ke Gemini setelah sejumlah kegagalan berturut-turut,
+// lalu mencoba lagi dengan satu panggilan setelah cooldown berlalu.
+// Threshold <= 0 menonaktifkan circuit breaker.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    int
+	failures int
+	openedAt time.Time
+}
+
+// newCircuitBreaker membuat circuitBreaker baru dalam status tertutup.
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// allow mengembalikan ErrCircuitOpen jika panggilan tidak boleh diteruskan ke Gemini.
+func (b *circuitBreaker) allow() error {
+	if b.threshold <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		// Cooldown selesai: izinkan satu panggilan uji.
+		b.state = breakerHalfOpen
+		return nil
+	case breakerHalfOpen:
+		// Panggilan uji sedang berjalan; panggilan lain tetap ditolak.
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+// success mencatat panggilan yang berhasil dan menutup circuit breaker.
+func (b *circuitBreaker) success() {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != breakerClosed {
+		log.Printf("gemini circuit breaker closed")
+	}
+	b.state = breakerClosed
+	b.failures = 0
+}
+
+// cancel dipanggil jika panggilan dibatalkan oleh pemanggil sebelum hasilnya diketahui.
+// Panggilan uji yang dibatalkan tidak menutup maupun memperpanjang status terbuka;
+// panggilan berikutnya langsung menjadi panggilan uji yang baru.
+func (b *circuitBreaker) cancel() {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == breakerHalfOpen {
+		b.state = breakerOpen
+		b.openedAt = time.Now().Add(-b.cooldown)
+	}
+}
+
+// failure mencatat kegagalan upstream. Circuit breaker terbuka setelah threshold kegagalan berturut-turut,
+// atau langsung jika panggilan uji pada status half-open gagal.
+func (b *circuitBreaker) failure() {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		if b.state != breakerOpen {
+			log.Printf("gemini circuit breaker opened after %d consecutive failures", b.failures)
+		}
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+	}
+}
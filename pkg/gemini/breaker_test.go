@@ -0,0 +1,122 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/llm"
+)
+
+func TestCircuitBreakerOpensAndHalfOpens(t *testing.T) {
+	const cooldown = 50 * time.Millisecond
+	b := newCircuitBreaker(2, cooldown)
+
+	// 1. Tertutup sampai threshold kegagalan berturut-turut tercapai.
+	b.failure()
+	if err := b.allow(); err != nil {
+		t.Fatalf("allow after 1 failure: %v", err)
+	}
+	b.failure()
+	err := b.allow()
+	if !errors.Is(err, ErrCircuitOpen) || !errors.Is(err, llm.ErrUnavailable) {
+		t.Fatalf("allow after threshold = %v, want ErrCircuitOpen wrapping llm.ErrUnavailable", err)
+	}
+
+	// 2. Setelah cooldown hanya satu panggilan uji yang diizinkan.
+	time.Sleep(cooldown)
+	if err := b.allow(); err != nil {
+		t.Fatalf("probe after cooldown: %v", err)
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("second call while half-open = %v, want ErrCircuitOpen", err)
+	}
+
+	// 3. Panggilan uji yang gagal langsung membuka kembali circuit breaker.
+	b.failure()
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("allow after failed probe = %v, want ErrCircuitOpen", err)
+	}
+
+	// 4. Panggilan uji yang berhasil menutupnya.
+	time.Sleep(cooldown)
+	if err := b.allow(); err != nil {
+		t.Fatalf("probe after cooldown: %v", err)
+	}
+	b.success()
+	for i := 0; i < 3; i++ {
+		if err := b.allow(); err != nil {
+			t.Fatalf("allow after successful probe: %v", err)
+		}
+	}
+
+	// Hitungan kegagalan dimulai ulang setelah ditutup.
+	b.failure()
+	if err := b.allow(); err != nil {
+		t.Fatalf("allow after 1 new failure: %v", err)
+	}
+}
+
+func TestCircuitBreakerCanceledProbe(t *testing.T) {
+	b := newCircuitBreaker(1, time.Hour)
+	b.failure()
+	b.openedAt = time.Now().Add(-time.Hour)
+
+	if err := b.allow(); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	// Panggilan uji yang dibatalkan pemanggil tidak membuat circuit breaker menunggu cooldown lagi.
+	b.cancel()
+	if err := b.allow(); err != nil {
+		t.Fatalf("probe after canceled probe: %v", err)
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	b := newCircuitBreaker(-1, time.Hour)
+	for i := 0; i < 10; i++ {
+		b.failure()
+	}
+	if err := b.allow(); err != nil {
+		t.Fatalf("disabled breaker rejected a call: %v", err)
+	}
+}
+
+func TestClientFailsFastWhileCircuitIsOpen(t *testing.T) {
+	srv, calls := scriptedServer(t, []int{503, 200}, nil)
+	client := NewClientWithConfig(ClientConfig{
+		BaseURL:          srv.URL,
+		Retry:            RetryPolicy{MaxAttempts: 1},
+		BreakerThreshold: 1,
+		BreakerCooldown:  50 * time.Millisecond,
+	})
+	ctx := context.Background()
+
+	if _, err := client.GenerateContent(ctx, "hai"); !errors.Is(err, llm.ErrUnavailable) {
+		t.Fatalf("first call = %v, want llm.ErrUnavailable", err)
+	}
+	if _, err := client.GenerateContent(ctx, "hai"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("call while open = %v, want ErrCircuitOpen", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("requests while open = %d, want 1", got)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if _, err := client.GenerateContent(ctx, "hai"); err != nil {
+		t.Fatalf("probe after cooldown: %v", err)
+	}
+	if _, err := client.GenerateContent(ctx, "hai"); err != nil {
+		t.Fatalf("call after recovery: %v", err)
+	}
+
+	// Error permanen (4xx) tidak membuka circuit breaker.
+	badSrv, _ := scriptedServer(t, []int{400}, nil)
+	client = NewClientWithConfig(ClientConfig{BaseURL: badSrv.URL, Retry: RetryPolicy{MaxAttempts: 1}, BreakerThreshold: 1})
+	for i := 0; i < 3; i++ {
+		if _, err := client.GenerateContent(ctx, "hai"); errors.Is(err, ErrCircuitOpen) {
+			t.Fatal("400 responses opened the circuit breaker")
+		}
+	}
+}
@@ -7,91 +7,309 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/llm"
 )
 
 const (
-	// TODO: Model dan URL bisa dibuat lebih dinamis melalui config jika diperlukan.
-	geminiAPIURL = "https://generativelanguage.googleapis.com/v1/models/gemini-2.5-flash:generateContent"
+	// DefaultBaseURL adalah base URL Gemini API, termasuk versi API.
+	DefaultBaseURL = "https://generativelanguage.googleapis.com/v1"
+	// DefaultModel dipakai jika model tidak diatur di config.
+	DefaultModel = "gemini-2.5-flash"
+
+	// DefaultTimeout adalah batas waktu satu percobaan `generateContent` dan `countTokens`.
+	DefaultTimeout = 30 * time.Second
+	// DefaultStreamTimeout adalah batas waktu satu percobaan `streamGenerateContent`, termasuk membaca stream.
+	DefaultStreamTimeout = 2 * time.Minute
+	// DefaultBreakerThreshold adalah jumlah kegagalan berturut-turut sebelum circuit breaker terbuka.
+	DefaultBreakerThreshold = 5
+	// DefaultBreakerCooldown adalah lama circuit breaker terbuka sebelum panggilan uji diizinkan.
+	DefaultBreakerCooldown = 30 * time.Second
 )
 
+// ClientConfig menampung pengaturan Gemini Client.
+type ClientConfig struct {
+	APIKey  string
+	BaseURL string // Kosong berarti DefaultBaseURL.
+	Model   string // Kosong berarti DefaultModel.
+	// Generation adalah pengaturan generasi default untuk setiap panggilan.
+	// Bisa ditimpa per panggilan melalui llm.Request.Options.
+	Generation llm.GenerationOptions
+
+	// Ketahanan terhadap upstream yang lambat atau tidak sehat. Nilai nol memakai default;
+	// Retry.MaxAttempts = 1 menonaktifkan retry, dan BreakerThreshold < 0 menonaktifkan circuit breaker.
+	Timeout          time.Duration
+	StreamTimeout    time.Duration
+	Retry            RetryPolicy
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
 // Client adalah klien untuk Gemini API.
 type Client struct {
 	apiKey     string
+	baseURL    string
+	model      string
+	generation llm.GenerationOptions
 	httpClient *http.Client
+
+	timeout       time.Duration
+	streamTimeout time.Duration
+	retry         RetryPolicy
+	breaker       *circuitBreaker
 }
 
-// NewClient membuat instance baru dari Gemini Client.
+// NewClient membuat instance baru dari Gemini Client dengan model dan endpoint default.
 func NewClient(apiKey string) *Client {
+	return NewClientWithConfig(ClientConfig{APIKey: apiKey})
+}
+
+// NewClientWithConfig membuat instance baru dari Gemini Client dengan pengaturan dari cfg.
+func NewClientWithConfig(cfg ClientConfig) *Client {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultBaseURL
+	}
+	if cfg.Model == "" {
+		cfg.Model = DefaultModel
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.StreamTimeout == 0 {
+		cfg.StreamTimeout = DefaultStreamTimeout
+	}
+	if cfg.Retry == (RetryPolicy{}) {
+		cfg.Retry = DefaultRetryPolicy
+	}
+	if cfg.BreakerThreshold == 0 {
+		cfg.BreakerThreshold = DefaultBreakerThreshold
+	}
+	if cfg.BreakerCooldown == 0 {
+		cfg.BreakerCooldown = DefaultBreakerCooldown
+	}
 	return &Client{
-		apiKey:     apiKey,
-		httpClient: &http.Client{},
+		apiKey:        cfg.APIKey,
+		baseURL:       strings.TrimRight(cfg.BaseURL, "/"),
+		model:         cfg.Model,
+		generation:    cfg.Generation,
+		httpClient:    &http.Client{},
+		timeout:       cfg.Timeout,
+		streamTimeout: cfg.StreamTimeout,
+		retry:         cfg.Retry,
+		breaker:       newCircuitBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown),
 	}
 }
 
 // GenerateContent mengirimkan prompt ke Gemini API dan mengembalikan respons teks.
 func (c *Client) GenerateContent(ctx context.Context, prompt string) (string, error) {
-	// 1. Membuat body request sesuai dengan struktur JSON yang dibutuhkan.
-	reqBody := GeminiRequest{
-		Contents: []Content{
-			{
-				Parts: []Part{
-					{Text: prompt},
-				},
-			},
-		},
-	}
-
-	payload, err := json.Marshal(reqBody)
+	return c.GenerateConversation(ctx, []Content{NewTextContent(RoleUser, prompt)})
+}
+
+// GenerateConversation mengirimkan seluruh percakapan (berurutan dari yang terlama) ke Gemini API
+// dan mengembalikan respons teks untuk giliran berikutnya.
+func (c *Client) GenerateConversation(ctx context.Context, contents []Content) (string, error) {
+	text, _, err := c.generate(ctx, c.newGeminiRequest("", contents, nil))
+	return text, err
+}
+
+// generate mengirim body request ke endpoint `generateContent` dan mengembalikan teks balasan
+// beserta pemakaian token yang dilaporkan Gemini.
+func (c *Client) generate(ctx context.Context, body GeminiRequest) (string, llm.Usage, error) {
+	// 1. Mengirim request ke endpoint `generateContent`, dengan retry untuk error sementara.
+	resp, err := c.do(ctx, "generateContent", nil, body, c.timeout)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request body: %w", err)
+		return "", llm.Usage{}, err
 	}
+	defer resp.Body.Close()
 
-	// 2. Membuat HTTP request.
-	url := fmt.Sprintf("%s?key=%s", geminiAPIURL, c.apiKey)
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(payload))
+	// 2. Membaca respons.
+	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to create http request: %w", err)
+		return "", llm.Usage{}, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var geminiResp GeminiResponse
+	if err := json.Unmarshal(respBody, &geminiResp); err != nil {
+		return "", llm.Usage{}, fmt.Errorf("failed to unmarshal response body: %w", err)
+	}
+
+	// 3. Mengekstrak teks dari respons.
+	if text, ok := geminiResp.text(); ok {
+		return text, geminiResp.UsageMetadata.toLLM(), nil
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	// 3. Mengirim request.
-	resp, err := c.httpClient.Do(req)
+	return "", llm.Usage{}, fmt.Errorf("no content found in gemini response")
+}
+
+// StreamGenerateContent mengirimkan prompt ke endpoint `streamGenerateContent` (SSE) dan memanggil
+// onChunk untuk setiap potongan teks yang diterima. Jika onChunk mengembalikan error, streaming dihentikan.
+// Mengembalikan teks lengkap hasil penggabungan semua potongan.
+func (c *Client) StreamGenerateContent(ctx context.Context, prompt string, onChunk func(text string) error) (string, error) {
+	return c.StreamConversation(ctx, []Content{NewTextContent(RoleUser, prompt)}, onChunk)
+}
+
+// StreamConversation sama seperti StreamGenerateContent, tetapi mengirimkan seluruh percakapan
+// (berurutan dari yang terlama) sehingga Gemini bisa menjawab pertanyaan lanjutan.
+func (c *Client) StreamConversation(ctx context.Context, contents []Content, onChunk func(text string) error) (string, error) {
+	text, _, err := c.stream(ctx, c.newGeminiRequest("", contents, nil), onChunk)
+	return text, err
+}
+
+// stream mengirim body request ke endpoint `streamGenerateContent` dan meneruskan setiap potongan teks ke onChunk.
+// Pemakaian token diambil dari potongan terakhir yang membawa `usageMetadata`.
+func (c *Client) stream(ctx context.Context, body GeminiRequest, onChunk func(text string) error) (string, llm.Usage, error) {
+	// 1. Mengirim request dengan `alt=sse` agar respons dikirim sebagai Server-Sent Events.
+	//    Retry hanya terjadi sebelum stream dimulai, sehingga tidak ada potongan teks yang terkirim dua kali.
+	resp, err := c.do(ctx, "streamGenerateContent", map[string]string{"alt": "sse"}, body, c.streamTimeout)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request to gemini api: %w", err)
+		return "", llm.Usage{}, err
 	}
 	defer resp.Body.Close()
 
-	// 4. Membaca dan memeriksa respons.
-	respBody, err := io.ReadAll(resp.Body)
+	// 2. Membaca event SSE. Setiap field `data:` berisi satu GeminiResponse.
+	var full strings.Builder
+	var usage llm.Usage
+	err = llm.ReadSSE(resp.Body, func(data string) error {
+		var chunk GeminiResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("failed to unmarshal stream chunk: %w", err)
+		}
+		if chunk.UsageMetadata != nil {
+			usage = chunk.UsageMetadata.toLLM()
+		}
+
+		text, ok := chunk.text()
+		if !ok || text == "" {
+			return nil
+		}
+		full.WriteString(text)
+		return onChunk(text)
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %w", err)
+		return full.String(), usage, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("gemini api returned non-200 status: %d, body: %s", resp.StatusCode, string(respBody))
+	// 3. Stream yang selesai tanpa teks sama sekali dianggap error, sama seperti GenerateContent.
+	if full.Len() == 0 {
+		return "", llm.Usage{}, fmt.Errorf("no content found in gemini response")
 	}
 
-	var geminiResp GeminiResponse
-	if err := json.Unmarshal(respBody, &geminiResp); err != nil {
-		return "", fmt.Errorf("failed to unmarshal response body: %w", err)
+	return full.String(), usage, nil
+}
+
+// newGeminiRequest membuat body request dari instruksi sistem (boleh kosong) dan percakapan,
+// dengan pengaturan generasi default yang ditimpa oleh override (jika ada).
+func (c *Client) newGeminiRequest(system string, contents []Content, override *llm.GenerationOptions) GeminiRequest {
+	body := GeminiRequest{
+		Contents:         contents,
+		GenerationConfig: newGenerationConfig(c.generation.Merge(override)),
+	}
+	if system != "" {
+		// Instruksi sistem tidak memiliki role; Gemini menerapkannya ke seluruh percakapan.
+		body.SystemInstruction = &Content{Parts: []Part{{Text: system}}}
+	}
+	return body
+}
+
+// newRequest membuat HTTP request POST ke method Gemini tertentu (misal: `generateContent`)
+// dengan body JSON dan API key di header `x-goog-api-key`.
+func (c *Client) newRequest(ctx context.Context, method string, query map[string]string, body GeminiRequest) (*http.Request, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	// Catat pengaturan yang dipakai di setiap panggilan untuk memudahkan debugging.
+	if method != "countTokens" {
+		settings, _ := json.Marshal(body.GenerationConfig)
+		log.Printf("gemini %s: model=%s generationConfig=%s", method, c.model, settings)
 	}
 
-	// 5. Mengekstrak teks dari respons.
-	if len(geminiResp.Candidates) > 0 && len(geminiResp.Candidates[0].Content.Parts) > 0 {
-		return geminiResp.Candidates[0].Content.Parts[0].Text, nil
+	// API key dikirim lewat header, bukan query string, agar tidak ikut tercatat di log bersama URL
+	// (misal: di pesan *url.Error saat request gagal).
+	endpoint := fmt.Sprintf("%s/models/%s:%s", c.baseURL, c.model, method)
+	if len(query) > 0 {
+		values := url.Values{}
+		for k, v := range query {
+			values.Set(k, v)
+		}
+		endpoint += "?" + values.Encode()
 	}
 
-	return "", fmt.Errorf("no content found in gemini response")
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", c.apiKey)
+	if method == "streamGenerateContent" {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+
+	return req, nil
+}
+
+// NewTextContent membuat satu giliran percakapan berisi teks dengan role tertentu.
+func NewTextContent(role, text string) Content {
+	return Content{
+		Role:  role,
+		Parts: []Part{{Text: text}},
+	}
 }
 
 // --- Structs for JSON Marshalling/Unmarshalling ---
 
 type GeminiRequest struct {
-	Contents []Content `json:"contents"`
+	SystemInstruction *Content          `json:"systemInstruction,omitempty"`
+	Contents          []Content         `json:"contents"`
+	GenerationConfig  *GenerationConfig `json:"generationConfig,omitempty"`
 }
 
+// GenerationConfig adalah pengaturan generasi Gemini. Field yang tidak diisi memakai default model.
+type GenerationConfig struct {
+	StopSequences    []string `json:"stopSequences,omitempty"`
+	CandidateCount   *int     `json:"candidateCount,omitempty"`
+	MaxOutputTokens  *int     `json:"maxOutputTokens,omitempty"`
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"topP,omitempty"`
+	TopK             *int     `json:"topK,omitempty"`
+	Seed             *int     `json:"seed,omitempty"`
+	PresencePenalty  *float64 `json:"presencePenalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequencyPenalty,omitempty"`
+}
+
+// newGenerationConfig mengubah llm.GenerationOptions menjadi GenerationConfig.
+// Mengembalikan nil jika tidak ada pengaturan sama sekali, agar field tidak dikirim.
+func newGenerationConfig(opts llm.GenerationOptions) *GenerationConfig {
+	if opts.IsZero() {
+		return nil
+	}
+	return &GenerationConfig{
+		StopSequences:    opts.StopSequences,
+		CandidateCount:   opts.CandidateCount,
+		MaxOutputTokens:  opts.MaxOutputTokens,
+		Temperature:      opts.Temperature,
+		TopP:             opts.TopP,
+		TopK:             opts.TopK,
+		Seed:             opts.Seed,
+		PresencePenalty:  opts.PresencePenalty,
+		FrequencyPenalty: opts.FrequencyPenalty,
+	}
+}
+
+// Role yang dikenali Gemini untuk setiap giliran percakapan.
+const (
+	RoleUser  = "user"
+	RoleModel = "model"
+)
+
 type Content struct {
+	Role  string `json:"role,omitempty"`
 	Parts []Part `json:"parts"`
 }
 
@@ -100,9 +318,36 @@ type Part struct {
 }
 
 type GeminiResponse struct {
-	Candidates []Candidate `json:"candidates"`
+	Candidates    []Candidate    `json:"candidates"`
+	UsageMetadata *UsageMetadata `json:"usageMetadata,omitempty"`
+}
+
+type UsageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	TotalTokenCount      int `json:"totalTokenCount"`
+}
+
+// toLLM mengubah UsageMetadata menjadi llm.Usage. Aman dipanggil pada nil.
+func (u *UsageMetadata) toLLM() llm.Usage {
+	if u == nil {
+		return llm.Usage{}
+	}
+	return llm.Usage{PromptTokens: u.PromptTokenCount, CandidateTokens: u.CandidatesTokenCount, TotalTokens: u.TotalTokenCount}
 }
 
 type Candidate struct {
 	Content Content `json:"content"`
 }
+
+// text mengembalikan gabungan teks dari kandidat pertama, jika ada.
+func (r GeminiResponse) text() (string, bool) {
+	if len(r.Candidates) == 0 || len(r.Candidates[0].Content.Parts) == 0 {
+		return "", false
+	}
+	var sb strings.Builder
+	for _, part := range r.Candidates[0].Content.Parts {
+		sb.WriteString(part.Text)
+	}
+	return sb.String(), true
+}
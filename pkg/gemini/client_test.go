@@ -0,0 +1,263 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/llm"
+)
+
+// newTestClient membuat Client yang mengarah ke server uji, tanpa retry dan circuit breaker.
+func newTestClient(baseURL string) *Client {
+	return NewClientWithConfig(ClientConfig{
+		APIKey:           "test-key",
+		BaseURL:          baseURL,
+		Model:            "test-model",
+		Retry:            RetryPolicy{MaxAttempts: 1},
+		BreakerThreshold: -1,
+	})
+}
+
+// sseChunk membuat satu event SSE berisi GeminiResponse dengan teks tertentu.
+func sseChunk(text string) string {
+	return fmt.Sprintf("data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":%q}]}}]}\n\n", text)
+}
+
+func TestStreamParsesChunksAndUsage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/test-model:streamGenerateContent" || r.URL.Query().Get("alt") != "sse" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, sseChunk("Halo"))
+		fmt.Fprint(w, ": komentar SSE diabaikan\n\n")
+		fmt.Fprint(w, sseChunk(", apa"))
+		fmt.Fprint(w, "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\" kabar?\"}]}}],"+
+			"\"usageMetadata\":{\"promptTokenCount\":3,\"candidatesTokenCount\":4,\"totalTokenCount\":7}}\n\n")
+	}))
+	defer srv.Close()
+
+	var chunks []string
+	resp, err := newTestClient(srv.URL).Stream(context.Background(), llm.Request{
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: "hai"}},
+	}, func(text string) error {
+		chunks = append(chunks, text)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	if got := strings.Join(chunks, "|"); got != "Halo|, apa| kabar?" {
+		t.Errorf("chunks = %q", got)
+	}
+	if resp.Text != "Halo, apa kabar?" {
+		t.Errorf("text = %q", resp.Text)
+	}
+	if resp.Usage != (llm.Usage{PromptTokens: 3, CandidateTokens: 4, TotalTokens: 7}) {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+}
+
+func TestStreamPartialFinalFrame(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantText string
+		wantErr  bool
+	}{
+		{
+			// Frame terakhir tanpa baris kosong penutup tetap dibaca.
+			name:     "final frame without terminator",
+			body:     sseChunk("satu") + strings.TrimSuffix(sseChunk("dua"), "\n\n"),
+			wantText: "satudua",
+		},
+		{
+			// Frame terakhir yang terpotong menghasilkan error, tetapi teks yang sudah diterima dikembalikan.
+			name:     "truncated final frame",
+			body:     sseChunk("satu") + `data: {"candidates":[{"content":{"parts":[{"te`,
+			wantText: "satu",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, tt.body)
+			}))
+			defer srv.Close()
+
+			text, _, err := newTestClient(srv.URL).stream(context.Background(), GeminiRequest{}, func(string) error { return nil })
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if text != tt.wantText {
+				t.Errorf("text = %q, want %q", text, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestStreamNon200Status(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":{"message":"bad request"}}`)
+	}))
+	defer srv.Close()
+
+	called := false
+	_, err := newTestClient(srv.URL).StreamGenerateContent(context.Background(), "hai", func(string) error {
+		called = true
+		return nil
+	})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("err = %v, want *APIError with status 400", err)
+	}
+	if !strings.Contains(apiErr.Body, "bad request") {
+		t.Errorf("body = %q", apiErr.Body)
+	}
+	if errors.Is(err, llm.ErrUnavailable) {
+		t.Error("400 must not be reported as llm.ErrUnavailable")
+	}
+	if called {
+		t.Error("onChunk called for a failed request")
+	}
+}
+
+func TestStreamContextCanceledMidStream(t *testing.T) {
+	released := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, sseChunk("awal"))
+		w.(http.Flusher).Flush()
+		// Tahan stream sampai client memutus koneksi.
+		<-r.Context().Done()
+		close(released)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	var text string
+	go func() {
+		var err error
+		text, err = newTestClient(srv.URL).StreamGenerateContent(ctx, "hai", func(string) error {
+			cancel() // Batalkan setelah potongan pertama diterima.
+			return nil
+		})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("err = %v, want context.Canceled", err)
+		}
+		if text != "awal" {
+			t.Errorf("text = %q, want the chunk received before cancel", text)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream did not stop after the context was canceled")
+	}
+
+	select {
+	case <-released:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server request was not canceled")
+	}
+}
+
+func TestRequestSendsAPIKeyInHeader(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("x-goog-api-key"); got != "test-key" {
+			t.Errorf("x-goog-api-key = %q, want test-key", got)
+		}
+		if strings.Contains(r.URL.RawQuery, "test-key") {
+			t.Errorf("api key found in query %q", r.URL.RawQuery)
+		}
+		fmt.Fprint(w, sseChunk("ok"))
+	}))
+	defer srv.Close()
+
+	if _, err := newTestClient(srv.URL).StreamGenerateContent(context.Background(), "hai", func(string) error { return nil }); err != nil {
+		t.Fatalf("StreamGenerateContent: %v", err)
+	}
+}
+
+func TestNetworkErrorDoesNotLeakAPIKey(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close() // Setiap request gagal dengan *url.Error yang memuat URL.
+
+	_, err := newTestClient(srv.URL).GenerateContent(context.Background(), "hai")
+	if err == nil {
+		t.Fatal("expected an error from a closed server")
+	}
+	if strings.Contains(err.Error(), "test-key") {
+		t.Errorf("error leaks the api key: %v", err)
+	}
+}
+
+func TestGenerationConfigOverrides(t *testing.T) {
+	temperature, topK, seed := 0.7, 40, 1
+	overrideTemperature, overrideSeed, penalty := 0.2, 42, 0.5
+
+	tests := []struct {
+		name     string
+		defaults llm.GenerationOptions
+		override *llm.GenerationOptions
+		want     string // generationConfig yang dikirim; kosong berarti field tidak dikirim sama sekali.
+	}{
+		{name: "no settings", want: ""},
+		{
+			name:     "configured defaults",
+			defaults: llm.GenerationOptions{Temperature: &temperature, TopK: &topK, Seed: &seed},
+			want:     `{"temperature":0.7,"topK":40,"seed":1}`,
+		},
+		{
+			name:     "per-call override",
+			defaults: llm.GenerationOptions{Temperature: &temperature, TopK: &topK, Seed: &seed},
+			override: &llm.GenerationOptions{Temperature: &overrideTemperature, Seed: &overrideSeed, PresencePenalty: &penalty, FrequencyPenalty: &penalty},
+			want:     `{"temperature":0.2,"topK":40,"seed":42,"presencePenalty":0.5,"frequencyPenalty":0.5}`,
+		},
+		{
+			name:     "override without defaults",
+			override: &llm.GenerationOptions{StopSequences: []string{"END"}},
+			want:     `{"stopSequences":["END"]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body struct {
+					GenerationConfig json.RawMessage `json:"generationConfig"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("decode request: %v", err)
+				}
+				got = string(body.GenerationConfig)
+				fmt.Fprint(w, `{"candidates":[{"content":{"parts":[{"text":"ok"}]}}]}`)
+			}))
+			defer srv.Close()
+
+			client := newTestClient(srv.URL)
+			client.generation = tt.defaults
+			req := llm.UserPrompt("hai")
+			req.Options = tt.override
+			if _, err := client.Generate(context.Background(), req); err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("generationConfig = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
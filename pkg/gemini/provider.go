@@ -0,0 +1,74 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/llm"
+)
+
+// Memastikan Client memenuhi kontrak llm.Provider.
+var _ llm.Provider = (*Client)(nil)
+
+// Generate mengimplementasikan llm.Provider menggunakan endpoint `generateContent`.
+func (c *Client) Generate(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	text, usage, err := c.generate(ctx, c.newGeminiRequest(req.System, toContents(req.Messages), req.Options))
+	if err != nil {
+		return nil, err
+	}
+	return &llm.Response{Text: text, Model: c.model, Usage: usage}, nil
+}
+
+// Stream mengimplementasikan llm.Provider menggunakan endpoint `streamGenerateContent`.
+func (c *Client) Stream(ctx context.Context, req llm.Request, onChunk func(text string) error) (*llm.Response, error) {
+	text, usage, err := c.stream(ctx, c.newGeminiRequest(req.System, toContents(req.Messages), req.Options), onChunk)
+	if err != nil {
+		return nil, err
+	}
+	return &llm.Response{Text: text, Model: c.model, Usage: usage}, nil
+}
+
+// CountTokens mengimplementasikan llm.Provider menggunakan endpoint `countTokens`.
+func (c *Client) CountTokens(ctx context.Context, req llm.Request) (int, error) {
+	resp, err := c.do(ctx, "countTokens", nil, GeminiRequest{Contents: toContents(req.Messages)}, c.timeout)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var countResp CountTokensResponse
+	if err := json.Unmarshal(respBody, &countResp); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal response body: %w", err)
+	}
+	return countResp.TotalTokens, nil
+}
+
+// toContents mengubah percakapan llm menjadi format `contents` Gemini.
+// Role `assistant` dipetakan ke `model`, dan giliran berurutan dengan role yang sama digabung.
+func toContents(messages []llm.Message) []Content {
+	var contents []Content
+	for _, msg := range messages {
+		role := RoleUser
+		if msg.Role == llm.RoleAssistant {
+			role = RoleModel
+		}
+		if n := len(contents); n > 0 && contents[n-1].Role == role {
+			contents[n-1].Parts = append(contents[n-1].Parts, Part{Text: msg.Content})
+			continue
+		}
+		contents = append(contents, NewTextContent(role, msg.Content))
+	}
+	return contents
+}
+
+// CountTokensResponse adalah respons dari endpoint `countTokens`.
+type CountTokensResponse struct {
+	TotalTokens int `json:"totalTokens"`
+}
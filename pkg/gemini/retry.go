@@ -0,0 +1,191 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/llm"
+)
+
+// RetryPolicy mengatur pengulangan panggilan yang gagal karena error sementara
+// (error jaringan, 429, atau 5xx).
+type RetryPolicy struct {
+	MaxAttempts int           // Jumlah percobaan total, termasuk yang pertama. <= 1 berarti tanpa retry.
+	BaseDelay   time.Duration // Jeda dasar backoff, digandakan di setiap percobaan.
+	MaxDelay    time.Duration // Batas jeda backoff. Retry-After yang lebih lama dari ini tidak ditunggu.
+}
+
+// DefaultRetryPolicy dipakai jika ClientConfig.Retry tidak diisi.
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: 500 * time.Millisecond, MaxDelay: 10 * time.Second}
+
+// APIError adalah respons non-200 dari Gemini API.
+type APIError struct {
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration // Dari header Retry-After; 0 jika tidak ada.
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("gemini api returned non-200 status: %d, body: %s", e.StatusCode, e.Body)
+}
+
+// Unwrap membuat error sementara (429 dan 5xx) dikenali sebagai llm.ErrUnavailable.
+func (e *APIError) Unwrap() error {
+	if e.temporary() {
+		return llm.ErrUnavailable
+	}
+	return nil
+}
+
+// temporary menandakan request yang sama layak dicoba lagi.
+func (e *APIError) temporary() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// do mengirim request ke method Gemini dengan retry dan circuit breaker, lalu mengembalikan respons 200.
+// Setiap percobaan dibatasi oleh timeout (0 berarti tanpa batas); batas waktu tetap berlaku saat
+// membaca body, sampai body ditutup oleh pemanggil.
+func (c *Client) do(ctx context.Context, method string, query map[string]string, body GeminiRequest, timeout time.Duration) (*http.Response, error) {
+	// 1. Gagal cepat jika upstream sedang dianggap tidak sehat.
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		// 2. Kirim satu percobaan.
+		resp, err := c.attempt(ctx, method, query, body, timeout)
+		if err == nil {
+			c.breaker.success()
+			return resp, nil
+		}
+		lastErr = err
+
+		// 3. Error dari pemanggil (context dibatalkan) atau error permanen (misal: 400) tidak diulang
+		//    dan tidak dihitung sebagai kegagalan upstream.
+		if ctx.Err() != nil {
+			c.breaker.cancel()
+			return nil, err
+		}
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && !apiErr.temporary() {
+			c.breaker.success()
+			return nil, err
+		}
+
+		// 4. Tunggu sesuai backoff atau Retry-After sebelum mencoba lagi.
+		delay, ok := c.retryDelay(attempt, apiErr)
+		if !ok {
+			break
+		}
+		log.Printf("gemini %s attempt %d failed, retrying in %s: %v", method, attempt, delay, err)
+		select {
+		case <-ctx.Done():
+			c.breaker.cancel()
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+
+	c.breaker.failure()
+	if errors.Is(lastErr, llm.ErrUnavailable) {
+		return nil, lastErr
+	}
+	return nil, fmt.Errorf("%w: %v", llm.ErrUnavailable, lastErr)
+}
+
+// attempt mengirim satu request dan mengembalikan *APIError untuk respons non-200.
+func (c *Client) attempt(ctx context.Context, method string, query map[string]string, body GeminiRequest, timeout time.Duration) (*http.Response, error) {
+	cancel := context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+
+	req, err := c.newRequest(ctx, method, query, body)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to send request to gemini api: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		cancel()
+		return nil, &APIError{
+			StatusCode: resp.StatusCode,
+			Body:       string(respBody),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+
+	// Timeout dilepas bersamaan dengan penutupan body.
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// retryDelay menghitung jeda sebelum percobaan berikutnya, atau false jika tidak perlu mencoba lagi.
+// Retry-After dari server diutamakan; jika tidak ada, dipakai exponential backoff dengan full jitter.
+func (c *Client) retryDelay(attempt int, apiErr *APIError) (time.Duration, bool) {
+	if attempt >= c.retry.MaxAttempts {
+		return 0, false
+	}
+	if apiErr != nil && apiErr.RetryAfter > 0 {
+		if c.retry.MaxDelay > 0 && apiErr.RetryAfter > c.retry.MaxDelay {
+			return 0, false
+		}
+		return apiErr.RetryAfter, true
+	}
+
+	backoff := c.retry.BaseDelay << (attempt - 1)
+	if backoff <= 0 || (c.retry.MaxDelay > 0 && backoff > c.retry.MaxDelay) {
+		backoff = c.retry.MaxDelay
+	}
+	if backoff <= 0 {
+		return 0, true
+	}
+	return time.Duration(rand.Int63n(int64(backoff)) + 1), true
+}
+
+// parseRetryAfter membaca header Retry-After, baik berupa jumlah detik maupun tanggal HTTP.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// cancelOnClose membatalkan context request saat body respons ditutup.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,200 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/llm"
+)
+
+// generateOK adalah respons generateContent yang berhasil.
+const generateOK = `{"candidates":[{"content":{"parts":[{"text":"ok"}]}}]}`
+
+// scriptedServer membalas setiap request dengan status berikutnya dari statuses; status terakhir diulang.
+// Status 0 memutus koneksi tanpa respons (error jaringan). Mengembalikan server dan penghitung request.
+func scriptedServer(t *testing.T, statuses []int, header http.Header) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1)) - 1
+		if n >= len(statuses) {
+			n = len(statuses) - 1
+		}
+		switch status := statuses[n]; status {
+		case 0:
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		case http.StatusOK:
+			fmt.Fprint(w, generateOK)
+		default:
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(status)
+			fmt.Fprintf(w, `{"error":{"code":%d}}`, status)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		maxAttempts  int
+		wantAttempts int32
+		wantErr      bool
+		wantUnavail  bool
+	}{
+		{name: "429 then success", statuses: []int{429, 200}, maxAttempts: 3, wantAttempts: 2},
+		{name: "5xx then success", statuses: []int{500, 502, 200}, maxAttempts: 3, wantAttempts: 3},
+		{name: "503 then 504 then success", statuses: []int{503, 504, 200}, maxAttempts: 3, wantAttempts: 3},
+		{name: "network error then success", statuses: []int{0, 200}, maxAttempts: 3, wantAttempts: 2},
+		{name: "400 is not retried", statuses: []int{400, 200}, maxAttempts: 3, wantAttempts: 1, wantErr: true},
+		{name: "401 is not retried", statuses: []int{401, 200}, maxAttempts: 3, wantAttempts: 1, wantErr: true},
+		{name: "404 is not retried", statuses: []int{404, 200}, maxAttempts: 3, wantAttempts: 1, wantErr: true},
+		{name: "attempts are limited", statuses: []int{503}, maxAttempts: 3, wantAttempts: 3, wantErr: true, wantUnavail: true},
+		{name: "single attempt disables retry", statuses: []int{503, 200}, maxAttempts: 1, wantAttempts: 1, wantErr: true, wantUnavail: true},
+		{name: "network errors are limited", statuses: []int{0}, maxAttempts: 2, wantAttempts: 2, wantErr: true, wantUnavail: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, calls := scriptedServer(t, tt.statuses, nil)
+			client := NewClientWithConfig(ClientConfig{
+				APIKey:           "test-key",
+				BaseURL:          srv.URL,
+				Retry:            RetryPolicy{MaxAttempts: tt.maxAttempts, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond},
+				BreakerThreshold: -1,
+			})
+
+			text, err := client.GenerateContent(context.Background(), "hai")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && text != "ok" {
+				t.Errorf("text = %q", text)
+			}
+			if got := errors.Is(err, llm.ErrUnavailable); got != tt.wantUnavail {
+				t.Errorf("errors.Is(err, llm.ErrUnavailable) = %v, want %v (err: %v)", got, tt.wantUnavail, err)
+			}
+			if got := calls.Load(); got != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", got, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestRetryHonorsRetryAfter(t *testing.T) {
+	srv, calls := scriptedServer(t, []int{429, 200}, http.Header{"Retry-After": {"1"}})
+	client := NewClientWithConfig(ClientConfig{
+		BaseURL:          srv.URL,
+		Retry:            RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Second},
+		BreakerThreshold: -1,
+	})
+
+	start := time.Now()
+	if _, err := client.GenerateContent(context.Background(), "hai"); err != nil {
+		t.Fatalf("GenerateContent: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %s, want at least the 1s from Retry-After", elapsed)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("attempts = %d, want 2", got)
+	}
+}
+
+func TestRetryGivesUpWhenRetryAfterExceedsMaxDelay(t *testing.T) {
+	srv, calls := scriptedServer(t, []int{503, 200}, http.Header{"Retry-After": {"60"}})
+	client := NewClientWithConfig(ClientConfig{
+		BaseURL:          srv.URL,
+		Retry:            RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Second},
+		BreakerThreshold: -1,
+	})
+
+	start := time.Now()
+	_, err := client.GenerateContent(context.Background(), "hai")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.RetryAfter != time.Minute {
+		t.Fatalf("err = %v, want *APIError with RetryAfter 1m", err)
+	}
+	if !errors.Is(err, llm.ErrUnavailable) {
+		t.Errorf("err = %v, want llm.ErrUnavailable", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("attempts = %d, want 1", got)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("waited %s before giving up", elapsed)
+	}
+}
+
+func TestRetryStopsWhenContextCanceled(t *testing.T) {
+	srv, calls := scriptedServer(t, []int{503}, nil)
+	client := NewClientWithConfig(ClientConfig{
+		BaseURL:          srv.URL,
+		Retry:            RetryPolicy{MaxAttempts: 5, BaseDelay: time.Minute, MaxDelay: time.Minute},
+		BreakerThreshold: -1,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := client.GenerateContent(ctx, "hai")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("attempts = %d, want 1", got)
+	}
+}
+
+func TestRetryDelayBackoff(t *testing.T) {
+	c := &Client{retry: RetryPolicy{MaxAttempts: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}}
+
+	for attempt, limit := range map[int]time.Duration{
+		1: 100 * time.Millisecond,
+		2: 200 * time.Millisecond,
+		3: 400 * time.Millisecond,
+		5: time.Second, // 1.6s dibatasi MaxDelay.
+		9: time.Second,
+	} {
+		for i := 0; i < 100; i++ {
+			delay, ok := c.retryDelay(attempt, nil)
+			if !ok || delay <= 0 || delay > limit {
+				t.Fatalf("retryDelay(%d) = %s, %v; want (0, %s]", attempt, delay, ok, limit)
+			}
+		}
+	}
+
+	if _, ok := c.retryDelay(10, nil); ok {
+		t.Error("retryDelay allowed an attempt beyond MaxAttempts")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{value: "", want: 0},
+		{value: "3", want: 3 * time.Second},
+		{value: "0", want: 0},
+		{value: "-1", want: 0},
+		{value: "soon", want: 0},
+		{value: now.Add(90 * time.Second).Format(http.TimeFormat), want: 90 * time.Second},
+		{value: now.Add(-time.Minute).Format(http.TimeFormat), want: 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// JWKS adalah dokumen JSON Web Key Set (RFC 7517) berisi public key untuk verifikasi token.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK adalah satu public key dalam format JSON Web Key.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`   // Modulus RSA.
+	E         string `json:"e,omitempty"`   // Eksponen RSA.
+	Curve     string `json:"crv,omitempty"` // Kurva OKP, misal: "Ed25519".
+	X         string `json:"x,omitempty"`   // Public key OKP.
+}
+
+// JWKS mengembalikan semua public key (penandatangan dan yang masih dalam jendela rotasi).
+// Untuk fallback HS256, daftar kunci selalu kosong.
+func (s *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, k := range s.verificationKeys() {
+		jwk := JWK{KeyID: k.ID, Use: "sig", Algorithm: k.Method.Alg()}
+		switch pub := k.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// JWKSHandler mengembalikan handler Echo untuk endpoint publik `/.well-known/jwks.json`.
+// Respons boleh di-cache sebentar oleh layanan lain; kunci baru harus dipublikasikan sebelum dipakai
+// untuk menandatangani, sehingga cache yang singkat tidak mengganggu rotasi.
+func JWKSHandler(keys *KeySet) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Response().Header().Set("Cache-Control", "public, max-age=300")
+		return c.JSON(http.StatusOK, keys.JWKS())
+	}
+}
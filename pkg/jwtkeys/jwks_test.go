@@ -0,0 +1,113 @@
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// publicKeyFromJWK membangun kembali public key dari sebuah JWK, seperti yang dilakukan layanan lain
+// yang memverifikasi token kita.
+func publicKeyFromJWK(t *testing.T, jwk JWK) crypto.PublicKey {
+	t.Helper()
+	decode := func(field, s string) []byte {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			t.Fatalf("decode %s of %s: %v", field, jwk.KeyID, err)
+		}
+		return b
+	}
+	switch jwk.KeyType {
+	case "RSA":
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(decode("n", jwk.N)), E: int(new(big.Int).SetBytes(decode("e", jwk.E)).Int64())}
+	case "OKP":
+		if jwk.Curve != "Ed25519" {
+			t.Fatalf("crv = %q, want Ed25519", jwk.Curve)
+		}
+		return ed25519.PublicKey(decode("x", jwk.X))
+	default:
+		t.Fatalf("unexpected kty %q", jwk.KeyType)
+		return nil
+	}
+}
+
+func TestJWKSRoundTrip(t *testing.T) {
+	rsaKey, edKey := newRSAKey(t, "rsa-1"), newEdDSAKey(t, "ed-1")
+	retired := &Key{ID: "ed-0", Method: jwt.SigningMethodEdDSA, Public: newEdDSAKey(t, "ed-0").Public}
+
+	e := echo.New()
+	tests := []struct {
+		name    string
+		signing *Key
+	}{
+		{name: "RS256 signing key", signing: rsaKey},
+		{name: "EdDSA signing key", signing: edKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := mustKeySet(t, []*Key{rsaKey, edKey, retired}, tt.signing.ID)
+
+			rec := httptest.NewRecorder()
+			if err := JWKSHandler(set)(e.NewContext(httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil), rec)); err != nil {
+				t.Fatalf("JWKSHandler: %v", err)
+			}
+			var doc JWKS
+			if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+				t.Fatalf("decode jwks: %v", err)
+			}
+
+			// Semua kunci dipublikasikan, termasuk yang dipensiunkan, diurutkan berdasarkan kid.
+			wantAlg := map[string]string{"ed-0": "EdDSA", "ed-1": "EdDSA", "rsa-1": "RS256"}
+			if len(doc.Keys) != len(wantAlg) {
+				t.Fatalf("jwks has %d keys, want %d", len(doc.Keys), len(wantAlg))
+			}
+			published := make(map[string]crypto.PublicKey)
+			for i, jwk := range doc.Keys {
+				if i > 0 && doc.Keys[i-1].KeyID >= jwk.KeyID {
+					t.Errorf("keys not sorted by kid: %s before %s", doc.Keys[i-1].KeyID, jwk.KeyID)
+				}
+				if jwk.Algorithm != wantAlg[jwk.KeyID] || jwk.Use != "sig" {
+					t.Errorf("jwk %s = alg %q use %q, want alg %q use sig", jwk.KeyID, jwk.Algorithm, jwk.Use, wantAlg[jwk.KeyID])
+				}
+				published[jwk.KeyID] = publicKeyFromJWK(t, jwk)
+			}
+			if got := published["rsa-1"].(*rsa.PublicKey); !got.Equal(rsaKey.Public) {
+				t.Error("rsa-1 n/e do not match the public key")
+			}
+			if got := published["ed-1"].(ed25519.PublicKey); !got.Equal(edKey.Public) {
+				t.Error("ed-1 x does not match the public key")
+			}
+
+			// Token yang ditandatangani set bisa diverifikasi hanya dengan JWKS.
+			token := sign(t, set)
+			_, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+				kid, _ := token.Header["kid"].(string)
+				return published[kid], nil
+			}, jwt.WithValidMethods([]string{wantAlg[tt.signing.ID]}))
+			if err != nil {
+				t.Errorf("verify with jwks: %v", err)
+			}
+		})
+	}
+}
+
+func TestJWKSEmptyForHMAC(t *testing.T) {
+	set, err := NewHMACKeySet("secret")
+	if err != nil {
+		t.Fatalf("NewHMACKeySet: %v", err)
+	}
+	// Secret bersama tidak boleh dipublikasikan; daftar kunci tetap berupa array kosong, bukan null.
+	body, _ := json.Marshal(set.JWKS())
+	if string(body) != `{"keys":[]}` {
+		t.Errorf("jwks = %s, want {\"keys\":[]}", body)
+	}
+}
@@ -0,0 +1,123 @@
+// Package jwtkeys mengelola kunci untuk menandatangani dan memverifikasi token JWT.
+// Kunci asimetris (RS256 atau EdDSA) dipilih berdasarkan header `kid`, sehingga beberapa kunci bisa
+// aktif bersamaan selama rotasi: satu kunci dipakai untuk menandatangani, sisanya hanya untuk verifikasi.
+// Jika tidak ada kunci asimetris, HS256 dengan secret bersama dipakai sebagai fallback.
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key adalah satu kunci yang dikenali dari `kid`-nya.
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.Signer    // nil untuk kunci yang hanya bisa memverifikasi.
+	Public  crypto.PublicKey // Dipakai untuk verifikasi dan dipublikasikan di JWKS.
+}
+
+// KeySet adalah kumpulan kunci JWT beserta kunci yang dipakai untuk menandatangani.
+type KeySet struct {
+	keys    map[string]*Key
+	signing *Key
+	secret  []byte // Hanya diisi untuk fallback HS256.
+}
+
+// NewHMACKeySet membuat KeySet HS256 dengan satu secret bersama. Token tidak diberi `kid`
+// dan JWKS selalu kosong, karena secret tidak boleh dipublikasikan.
+func NewHMACKeySet(secret string) (*KeySet, error) {
+	if secret == "" {
+		return nil, errors.New("jwt secret is empty")
+	}
+	return &KeySet{secret: []byte(secret)}, nil
+}
+
+// NewKeySet membuat KeySet dari kunci-kunci asimetris. signingKID menentukan kunci untuk menandatangani
+// dan harus memiliki private key; kunci lainnya hanya dipakai untuk verifikasi (jendela rotasi).
+func NewKeySet(keys []*Key, signingKID string) (*KeySet, error) {
+	set := &KeySet{keys: make(map[string]*Key, len(keys))}
+	for _, k := range keys {
+		if _, exists := set.keys[k.ID]; exists {
+			return nil, fmt.Errorf("duplicate jwt key id %q", k.ID)
+		}
+		set.keys[k.ID] = k
+	}
+
+	signing, ok := set.keys[signingKID]
+	if !ok {
+		return nil, fmt.Errorf("signing key %q not found", signingKID)
+	}
+	if signing.Private == nil {
+		return nil, fmt.Errorf("signing key %q has no private key", signingKID)
+	}
+	set.signing = signing
+	return set, nil
+}
+
+// Sign menandatangani claims dengan kunci penandatangan dan mengisi header `kid`.
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if s.signing == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+	}
+	token := jwt.NewWithClaims(s.signing.Method, claims)
+	token.Header["kid"] = s.signing.ID
+	return token.SignedString(s.signing.Private)
+}
+
+// Keyfunc mengembalikan kunci verifikasi untuk sebuah token berdasarkan header `kid`.
+// Algoritma token harus sama dengan algoritma kunci, agar token tidak bisa memilih algoritma lain.
+func (s *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if s.signing == nil {
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected jwt signing method=%v", token.Header["alg"])
+		}
+		return s.secret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown jwt key id %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected jwt signing method=%v for key %q", token.Header["alg"], kid)
+	}
+	return key.Public, nil
+}
+
+// SigningKeyID mengembalikan `kid` kunci penandatangan, atau string kosong untuk fallback HS256.
+func (s *KeySet) SigningKeyID() string {
+	if s.signing == nil {
+		return ""
+	}
+	return s.signing.ID
+}
+
+// verificationKeys mengembalikan semua kunci asimetris, diurutkan berdasarkan `kid`.
+func (s *KeySet) verificationKeys() []*Key {
+	keys := make([]*Key, 0, len(s.keys))
+	for _, k := range s.keys {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys
+}
+
+// methodFor menentukan algoritma penandatanganan dari tipe public key.
+func methodFor(pub crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch pub.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("unsupported jwt key type %T", pub)
+	}
+}
@@ -0,0 +1,175 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// newRSAKey membuat kunci RS256 uji dengan kid tertentu.
+func newRSAKey(t *testing.T, kid string) *Key {
+	t.Helper()
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey: %v", err)
+	}
+	return &Key{ID: kid, Method: jwt.SigningMethodRS256, Private: priv, Public: &priv.PublicKey}
+}
+
+// newEdDSAKey membuat kunci EdDSA uji dengan kid tertentu.
+func newEdDSAKey(t *testing.T, kid string) *Key {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey: %v", err)
+	}
+	return &Key{ID: kid, Method: jwt.SigningMethodEdDSA, Private: priv, Public: pub}
+}
+
+// mustKeySet membuat KeySet atau menggagalkan test.
+func mustKeySet(t *testing.T, keys []*Key, signingKID string) *KeySet {
+	t.Helper()
+	set, err := NewKeySet(keys, signingKID)
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+	return set
+}
+
+// testClaims mengembalikan claims yang masih berlaku.
+func testClaims() jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{Subject: "user-1", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))}
+}
+
+// sign menandatangani testClaims dengan set atau menggagalkan test.
+func sign(t *testing.T, set *KeySet) string {
+	t.Helper()
+	token, err := set.Sign(testClaims())
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return token
+}
+
+// verify mem-parsing token dengan Keyfunc milik set dan mengembalikan `kid` di header-nya.
+func verify(set *KeySet, token string) (string, error) {
+	parsed, err := jwt.ParseWithClaims(token, &jwt.RegisteredClaims{}, set.Keyfunc)
+	if err != nil {
+		return "", err
+	}
+	kid, _ := parsed.Header["kid"].(string)
+	return kid, nil
+}
+
+func TestSignAndVerify(t *testing.T) {
+	tests := []struct {
+		name string
+		key  *Key
+	}{
+		{name: "RS256", key: newRSAKey(t, "rsa-1")},
+		{name: "EdDSA", key: newEdDSAKey(t, "ed-1")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := mustKeySet(t, []*Key{tt.key}, tt.key.ID)
+			token := sign(t, set)
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &jwt.RegisteredClaims{})
+			if err != nil {
+				t.Fatalf("ParseUnverified: %v", err)
+			}
+			if parsed.Method.Alg() != tt.key.Method.Alg() {
+				t.Errorf("alg = %s, want %s", parsed.Method.Alg(), tt.key.Method.Alg())
+			}
+			if kid, err := verify(set, token); err != nil || kid != tt.key.ID {
+				t.Errorf("verify = %q, %v; want kid %s", kid, err, tt.key.ID)
+			}
+		})
+	}
+}
+
+func TestKeyfuncRejectsUnknownOrMismatchedKeys(t *testing.T) {
+	set := mustKeySet(t, []*Key{newEdDSAKey(t, "k1")}, "k1")
+	stranger := mustKeySet(t, []*Key{newEdDSAKey(t, "k2")}, "k2")
+	// Kunci RSA dengan kid yang sama: kid dikenali, tetapi algoritmanya tidak cocok.
+	rsaSameKID := mustKeySet(t, []*Key{newRSAKey(t, "k1")}, "k1")
+
+	noKID := jwt.NewWithClaims(jwt.SigningMethodEdDSA, testClaims())
+	noKIDToken, err := noKID.SignedString(set.signing.Private)
+	if err != nil {
+		t.Fatalf("sign without kid: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "unknown kid", token: sign(t, stranger)},
+		{name: "missing kid", token: noKIDToken},
+		{name: "algorithm mismatch", token: sign(t, rsaSameKID)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := verify(set, tt.token); err == nil {
+				t.Fatal("expected the token to be rejected")
+			}
+		})
+	}
+}
+
+func TestRetiredKeyVerifiesButDoesNotSign(t *testing.T) {
+	retired, current := newRSAKey(t, "2025"), newEdDSAKey(t, "2026")
+	// Token lama ditandatangani sebelum rotasi, dengan kunci yang kini dipensiunkan.
+	oldToken := sign(t, mustKeySet(t, []*Key{retired}, "2025"))
+
+	tests := []struct {
+		name    string
+		retired *Key
+	}{
+		{name: "retired key with private key", retired: retired},
+		{name: "retired public key only", retired: &Key{ID: retired.ID, Method: retired.Method, Public: retired.Public}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := mustKeySet(t, []*Key{tt.retired, current}, "2026")
+
+			if kid, err := verify(set, oldToken); err != nil || kid != "2025" {
+				t.Errorf("verify old token = %q, %v; want it accepted with kid 2025", kid, err)
+			}
+			for i := 0; i < 3; i++ {
+				if kid, err := verify(set, sign(t, set)); err != nil || kid != "2026" {
+					t.Fatalf("new token kid = %q, %v; want 2026", kid, err)
+				}
+			}
+			if set.SigningKeyID() != "2026" {
+				t.Errorf("SigningKeyID = %q, want 2026", set.SigningKeyID())
+			}
+		})
+	}
+}
+
+func TestNewKeySetValidation(t *testing.T) {
+	key := newEdDSAKey(t, "k1")
+	publicOnly := &Key{ID: "pub", Method: jwt.SigningMethodEdDSA, Public: key.Public}
+
+	tests := []struct {
+		name       string
+		keys       []*Key
+		signingKID string
+	}{
+		{name: "duplicate kid", keys: []*Key{key, key}, signingKID: "k1"},
+		{name: "unknown signing kid", keys: []*Key{key}, signingKID: "k2"},
+		{name: "signing key without private key", keys: []*Key{key, publicOnly}, signingKID: "pub"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewKeySet(tt.keys, tt.signingKID); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
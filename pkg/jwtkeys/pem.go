@@ -0,0 +1,88 @@
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// LoadKeySet membaca kunci dari file PEM (kid -> path) dan membuat KeySet dengan signingKID sebagai
+// kunci penandatangan. Jika signingKID kosong dan hanya ada satu kunci, kunci itu yang dipakai.
+// File boleh berisi private key (PKCS#8 atau PKCS#1 untuk RSA) atau public key (PKIX atau PKCS#1);
+// kunci dengan public key saja hanya bisa memverifikasi.
+func LoadKeySet(files map[string]string, signingKID string) (*KeySet, error) {
+	kids := make([]string, 0, len(files))
+	for kid := range files {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	keys := make([]*Key, 0, len(kids))
+	for _, kid := range kids {
+		key, err := loadKey(kid, files[kid])
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if signingKID == "" && len(kids) == 1 {
+		signingKID = kids[0]
+	}
+	return NewKeySet(keys, signingKID)
+}
+
+// loadKey membaca satu file PEM menjadi Key.
+func loadKey(kid, path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jwt key %q: %w", kid, err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("jwt key %q: no PEM block found in %s", kid, path)
+	}
+
+	key := &Key{ID: kid}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", kid, err)
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("jwt key %q: unsupported private key type %T", kid, parsed)
+		}
+		key.Private, key.Public = signer, signer.Public()
+	case "RSA PRIVATE KEY":
+		parsed, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", kid, err)
+		}
+		key.Private, key.Public = parsed, parsed.Public()
+	case "PUBLIC KEY":
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", kid, err)
+		}
+		key.Public = parsed
+	case "RSA PUBLIC KEY":
+		parsed, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", kid, err)
+		}
+		key.Public = parsed
+	default:
+		return nil, fmt.Errorf("jwt key %q: unsupported PEM block type %q", kid, block.Type)
+	}
+
+	key.Method, err = methodFor(key.Public)
+	if err != nil {
+		return nil, fmt.Errorf("jwt key %q: %w", kid, err)
+	}
+	return key, nil
+}
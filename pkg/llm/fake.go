@@ -0,0 +1,152 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeModel adalah nama model yang dilaporkan FakeProvider di Response.Model.
+const FakeModel = "fake"
+
+// ErrFakeFailure adalah error yang dikembalikan FakeProvider saat kegagalan disimulasikan.
+var ErrFakeFailure = errors.New("fake llm: simulated failure")
+
+// FakeProvider adalah implementasi Provider yang deterministik untuk pengujian dan development lokal.
+// Balasan diambil bergiliran dari Replies; jika kosong, pesan terakhir pengguna dipantulkan kembali.
+type FakeProvider struct {
+	Replies   []string      // Balasan yang dikembalikan secara bergiliran.
+	Latency   time.Duration // Jeda sebelum setiap potongan teks (atau sebelum balasan pada Generate).
+	FailEvery int           // Jika > 0, setiap panggilan ke-N gagal dengan Err.
+	Err       error         // Error untuk kegagalan yang disimulasikan; default ErrFakeFailure.
+
+	mu    sync.Mutex
+	calls int
+}
+
+// NewFakeProvider membuat FakeProvider dengan balasan, jeda dan pola kegagalan tertentu.
+func NewFakeProvider(replies []string, latency time.Duration, failEvery int) *FakeProvider {
+	return &FakeProvider{
+		Replies:   replies,
+		Latency:   latency,
+		FailEvery: failEvery,
+	}
+}
+
+// Generate mengembalikan balasan berikutnya setelah menunggu Latency.
+func (p *FakeProvider) Generate(ctx context.Context, req Request) (*Response, error) {
+	reply, err := p.next(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.wait(ctx); err != nil {
+		return nil, err
+	}
+	return &Response{Text: reply, Model: FakeModel, Usage: estimateUsage(req, reply)}, nil
+}
+
+// Stream mengirimkan balasan berikutnya kata per kata, dengan jeda Latency sebelum setiap kata.
+func (p *FakeProvider) Stream(ctx context.Context, req Request, onChunk func(text string) error) (*Response, error) {
+	reply, err := p.next(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var full strings.Builder
+	for _, chunk := range splitWords(reply) {
+		if err := p.wait(ctx); err != nil {
+			return &Response{Text: full.String(), Model: FakeModel}, err
+		}
+		full.WriteString(chunk)
+		if err := onChunk(chunk); err != nil {
+			return &Response{Text: full.String(), Model: FakeModel}, err
+		}
+	}
+	return &Response{Text: full.String(), Model: FakeModel, Usage: estimateUsage(req, full.String())}, nil
+}
+
+// CountTokens memperkirakan jumlah token dari seluruh pesan di permintaan.
+func (p *FakeProvider) CountTokens(ctx context.Context, req Request) (int, error) {
+	total := 0
+	for _, msg := range req.Messages {
+		total += EstimateTokens(msg.Content)
+	}
+	return total, nil
+}
+
+// Calls mengembalikan jumlah panggilan Generate dan Stream sejauh ini.
+func (p *FakeProvider) Calls() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+// next menentukan balasan untuk panggilan berikutnya, atau error jika panggilan ini harus gagal.
+func (p *FakeProvider) next(req Request) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.calls++
+	if p.FailEvery > 0 && p.calls%p.FailEvery == 0 {
+		if p.Err != nil {
+			return "", p.Err
+		}
+		return "", ErrFakeFailure
+	}
+
+	if len(p.Replies) > 0 {
+		return p.Replies[(p.calls-1)%len(p.Replies)], nil
+	}
+
+	// Tanpa balasan yang disiapkan, pantulkan pesan terakhir pengguna.
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if req.Messages[i].Role == RoleUser {
+			return fmt.Sprintf("Echo: %s", req.Messages[i].Content), nil
+		}
+	}
+	return "Echo", nil
+}
+
+// wait menunggu selama Latency, atau berhenti lebih awal jika context dibatalkan.
+func (p *FakeProvider) wait(ctx context.Context) error {
+	if p.Latency <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(p.Latency)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// splitWords memecah teks menjadi potongan per kata, dengan spasi ikut di potongan sebelumnya.
+func splitWords(text string) []string {
+	var chunks []string
+	start := 0
+	for i, r := range text {
+		if r == ' ' {
+			chunks = append(chunks, text[start:i+1])
+			start = i + 1
+		}
+	}
+	if start < len(text) {
+		chunks = append(chunks, text[start:])
+	}
+	return chunks
+}
+
+// estimateUsage memperkirakan pemakaian token untuk penyedia yang tidak melaporkannya.
+func estimateUsage(req Request, reply string) Usage {
+	prompt := EstimateTokens(req.System)
+	for _, msg := range req.Messages {
+		prompt += EstimateTokens(msg.Content)
+	}
+	candidate := EstimateTokens(reply)
+	return Usage{PromptTokens: prompt, CandidateTokens: candidate, TotalTokens: prompt + candidate}
+}
@@ -0,0 +1,96 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// FallbackProvider mencoba daftar penyedia secara berurutan: jika penyedia pertama gagal
+// (error atau timeout), penyedia berikutnya dipakai, dan seterusnya.
+// Response.Model mencatat model yang akhirnya menghasilkan balasan.
+type FallbackProvider struct {
+	providers []Provider
+	names     []string // Nama setiap penyedia untuk log, misal: "gemini:gemini-2.5-flash".
+}
+
+// NewFallbackProvider membuat FallbackProvider dari penyedia yang diurutkan dari yang paling diutamakan.
+// names dipakai untuk log dan boleh lebih pendek dari providers.
+func NewFallbackProvider(providers []Provider, names []string) *FallbackProvider {
+	return &FallbackProvider{providers: providers, names: names}
+}
+
+// Generate mengembalikan balasan dari penyedia pertama yang berhasil.
+func (p *FallbackProvider) Generate(ctx context.Context, req Request) (*Response, error) {
+	var errs []error
+	for i, provider := range p.providers {
+		resp, err := provider.Generate(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", p.name(i), err))
+		p.logFallback(i, err)
+	}
+	return nil, p.joinErrors(errs)
+}
+
+// Stream meneruskan stream dari penyedia pertama yang berhasil. Penyedia berikutnya hanya dicoba
+// jika penyedia sebelumnya gagal sebelum mengirim potongan teks apa pun, agar client tidak menerima
+// balasan yang tercampur dari dua model.
+func (p *FallbackProvider) Stream(ctx context.Context, req Request, onChunk func(text string) error) (*Response, error) {
+	var errs []error
+	for i, provider := range p.providers {
+		started := false
+		resp, err := provider.Stream(ctx, req, func(text string) error {
+			started = true
+			return onChunk(text)
+		})
+		if err == nil || started || ctx.Err() != nil {
+			return resp, err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", p.name(i), err))
+		p.logFallback(i, err)
+	}
+	return nil, p.joinErrors(errs)
+}
+
+// CountTokens memakai penyedia pertama yang berhasil menghitung token.
+func (p *FallbackProvider) CountTokens(ctx context.Context, req Request) (int, error) {
+	var errs []error
+	for i, provider := range p.providers {
+		n, err := provider.CountTokens(ctx, req)
+		if err == nil {
+			return n, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", p.name(i), err))
+	}
+	return 0, p.joinErrors(errs)
+}
+
+// name mengembalikan nama penyedia ke-i untuk log.
+func (p *FallbackProvider) name(i int) string {
+	if i < len(p.names) {
+		return p.names[i]
+	}
+	return fmt.Sprintf("provider #%d", i+1)
+}
+
+// logFallback mencatat kegagalan penyedia ke-i dan penyedia yang akan dicoba berikutnya.
+func (p *FallbackProvider) logFallback(i int, err error) {
+	if i+1 < len(p.providers) {
+		log.Printf("llm %s failed, falling back to %s: %v", p.name(i), p.name(i+1), err)
+	}
+}
+
+// joinErrors menggabungkan error semua penyedia. Hasilnya dikenali sebagai ErrUnavailable (errors.Is)
+// jika salah satu penyedia sedang tidak tersedia.
+func (p *FallbackProvider) joinErrors(errs []error) error {
+	if len(errs) == 0 {
+		return errors.New("llm: no provider configured")
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,208 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// stubProvider adalah Provider uji yang mengirim chunks lalu mengembalikan err, dan mencatat jumlah panggilan.
+type stubProvider struct {
+	model  string
+	chunks []string
+	err    error
+	calls  int
+}
+
+func (p *stubProvider) Generate(ctx context.Context, req Request) (*Response, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &Response{Text: strings.Join(p.chunks, ""), Model: p.model}, nil
+}
+
+func (p *stubProvider) Stream(ctx context.Context, req Request, onChunk func(text string) error) (*Response, error) {
+	p.calls++
+	var full strings.Builder
+	for _, chunk := range p.chunks {
+		full.WriteString(chunk)
+		if err := onChunk(chunk); err != nil {
+			return &Response{Text: full.String(), Model: p.model}, err
+		}
+	}
+	if p.err != nil {
+		return &Response{Text: full.String(), Model: p.model}, p.err
+	}
+	return &Response{Text: full.String(), Model: p.model}, nil
+}
+
+func (p *stubProvider) CountTokens(ctx context.Context, req Request) (int, error) {
+	p.calls++
+	return len(p.chunks), p.err
+}
+
+func TestFallbackProvider(t *testing.T) {
+	unavailable := fmt.Errorf("%w: 503", ErrUnavailable)
+	rejected := errors.New("400 bad request")
+
+	tests := []struct {
+		name      string
+		providers []*stubProvider
+		// wantCalls adalah jumlah panggilan yang diharapkan untuk setiap penyedia.
+		wantCalls       []int
+		wantModel       string
+		wantText        string
+		wantChunks      string  // Potongan yang diterima pemanggil Stream, dipisah "|".
+		wantErrs        []error // Error setiap penyedia yang harus tergabung di error akhir.
+		wantUnavailable bool
+	}{
+		{
+			name: "first provider succeeds",
+			providers: []*stubProvider{
+				{model: "utama", chunks: []string{"Halo", "!"}},
+				{model: "cadangan", chunks: []string{"tidak dipakai"}},
+			},
+			wantCalls:  []int{1, 0},
+			wantModel:  "utama",
+			wantText:   "Halo!",
+			wantChunks: "Halo|!",
+		},
+		{
+			name: "falls back in order",
+			providers: []*stubProvider{
+				{model: "utama", err: unavailable},
+				{model: "kedua", err: rejected},
+				{model: "ketiga", chunks: []string{"Halo"}},
+				{model: "keempat", chunks: []string{"tidak dipakai"}},
+			},
+			wantCalls:  []int{1, 1, 1, 0},
+			wantModel:  "ketiga",
+			wantText:   "Halo",
+			wantChunks: "Halo",
+		},
+		{
+			name: "all providers fail",
+			providers: []*stubProvider{
+				{model: "utama", err: unavailable},
+				{model: "cadangan", err: rejected},
+			},
+			wantCalls:       []int{1, 1},
+			wantErrs:        []error{unavailable, rejected},
+			wantUnavailable: true,
+		},
+		{
+			name: "no provider is unavailable",
+			providers: []*stubProvider{
+				{model: "utama", err: rejected},
+			},
+			wantCalls: []int{1},
+			wantErrs:  []error{rejected},
+		},
+	}
+	for _, tt := range tests {
+		for _, method := range []string{"generate", "stream"} {
+			t.Run(tt.name+"/"+method, func(t *testing.T) {
+				providers := make([]Provider, len(tt.providers))
+				for i, stub := range tt.providers {
+					stub.calls = 0
+					providers[i] = stub
+				}
+				fallback := NewFallbackProvider(providers, []string{"utama"})
+
+				var chunks []string
+				var resp *Response
+				var err error
+				if method == "generate" {
+					resp, err = fallback.Generate(context.Background(), UserPrompt("hai"))
+				} else {
+					resp, err = fallback.Stream(context.Background(), UserPrompt("hai"), func(text string) error {
+						chunks = append(chunks, text)
+						return nil
+					})
+				}
+
+				for i, stub := range tt.providers {
+					if stub.calls != tt.wantCalls[i] {
+						t.Errorf("provider %s called %d times, want %d", stub.model, stub.calls, tt.wantCalls[i])
+					}
+				}
+				if tt.wantErrs != nil {
+					if err == nil {
+						t.Fatal("expected an error")
+					}
+					for _, want := range tt.wantErrs {
+						if !errors.Is(err, want) {
+							t.Errorf("err = %v, want it to wrap %v", err, want)
+						}
+					}
+					if got := errors.Is(err, ErrUnavailable); got != tt.wantUnavailable {
+						t.Errorf("errors.Is(err, ErrUnavailable) = %v, want %v", got, tt.wantUnavailable)
+					}
+					// Setiap error diberi nama penyedianya; penyedia tanpa nama disebut berdasarkan urutannya.
+					for i := range tt.providers {
+						if name := fallback.name(i); !strings.Contains(err.Error(), name+": ") {
+							t.Errorf("err = %q, want it to name %s", err, name)
+						}
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if resp.Model != tt.wantModel || resp.Text != tt.wantText {
+					t.Errorf("response = %+v, want %q from %s", *resp, tt.wantText, tt.wantModel)
+				}
+				if method == "stream" && strings.Join(chunks, "|") != tt.wantChunks {
+					t.Errorf("chunks = %q, want %q", strings.Join(chunks, "|"), tt.wantChunks)
+				}
+			})
+		}
+	}
+}
+
+func TestFallbackProviderStreamDoesNotFallBackAfterChunks(t *testing.T) {
+	unavailable := fmt.Errorf("%w: connection reset", ErrUnavailable)
+	primary := &stubProvider{model: "utama", chunks: []string{"Halo", " apa"}, err: unavailable}
+	backup := &stubProvider{model: "cadangan", chunks: []string{"Balasan lain"}}
+	fallback := NewFallbackProvider([]Provider{primary, backup}, []string{"utama", "cadangan"})
+
+	var chunks []string
+	resp, err := fallback.Stream(context.Background(), UserPrompt("hai"), func(text string) error {
+		chunks = append(chunks, text)
+		return nil
+	})
+	if !errors.Is(err, unavailable) {
+		t.Fatalf("err = %v, want the primary error", err)
+	}
+	if backup.calls != 0 {
+		t.Errorf("backup called %d times after chunks were streamed, want 0", backup.calls)
+	}
+	if got := strings.Join(chunks, "|"); got != "Halo| apa" {
+		t.Errorf("chunks = %q, want only the primary chunks", got)
+	}
+	// Teks parsial dikembalikan bersama model yang menghasilkannya.
+	if resp == nil || resp.Text != "Halo apa" || resp.Model != "utama" {
+		t.Errorf("response = %+v, want the partial primary reply", resp)
+	}
+}
+
+func TestFallbackProviderStopsWhenContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	primary := &stubProvider{model: "utama", err: context.Canceled}
+	backup := &stubProvider{model: "cadangan", chunks: []string{"Halo"}}
+	fallback := NewFallbackProvider([]Provider{primary, backup}, nil)
+
+	if _, err := fallback.Generate(ctx, UserPrompt("hai")); !errors.Is(err, context.Canceled) {
+		t.Errorf("Generate: err = %v, want context.Canceled", err)
+	}
+	if _, err := fallback.Stream(ctx, UserPrompt("hai"), func(string) error { return nil }); !errors.Is(err, context.Canceled) {
+		t.Errorf("Stream: err = %v, want context.Canceled", err)
+	}
+	if backup.calls != 0 {
+		t.Errorf("backup called %d times after the caller canceled, want 0", backup.calls)
+	}
+}
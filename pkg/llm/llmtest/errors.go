@@ -0,0 +1,117 @@
+// Package llmtest berisi suite uji bersama untuk klien llm.Provider berbasis HTTP, agar setiap klien
+// menggolongkan error dengan cara yang sama (kapan boleh dialihkan ke penyedia cadangan, kapan tidak).
+package llmtest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/llm"
+)
+
+// RunErrorClassification menguji bahwa error dari Generate dan Stream dibungkus llm.ErrUnavailable hanya
+// jika penyedia sedang sibuk atau tidak bisa dihubungi (429, 5xx, gagal jaringan, timeout), dan tidak
+// jika request ditolak atau dibatalkan pemanggil. newClient membuat klien yang mengarah ke baseURL
+// dengan timeout per request.
+func RunErrorClassification(t *testing.T, newClient func(baseURL string, timeout time.Duration) llm.Provider) {
+	calls := []struct {
+		name string
+		call func(ctx context.Context, p llm.Provider) error
+	}{
+		{name: "generate", call: func(ctx context.Context, p llm.Provider) error {
+			_, err := p.Generate(ctx, llm.UserPrompt("hai"))
+			return err
+		}},
+		{name: "stream", call: func(ctx context.Context, p llm.Provider) error {
+			_, err := p.Stream(ctx, llm.UserPrompt("hai"), func(string) error { return nil })
+			return err
+		}},
+	}
+
+	for _, c := range calls {
+		t.Run(c.name, func(t *testing.T) {
+			t.Run("status", func(t *testing.T) {
+				tests := []struct {
+					name        string
+					status      int
+					wantUnavail bool
+				}{
+					{name: "rate limited", status: http.StatusTooManyRequests, wantUnavail: true},
+					{name: "server error", status: http.StatusInternalServerError, wantUnavail: true},
+					{name: "bad gateway", status: http.StatusBadGateway, wantUnavail: true},
+					{name: "service unavailable", status: http.StatusServiceUnavailable, wantUnavail: true},
+					{name: "bad request", status: http.StatusBadRequest, wantUnavail: false},
+					{name: "unauthorized", status: http.StatusUnauthorized, wantUnavail: false},
+					{name: "not found", status: http.StatusNotFound, wantUnavail: false},
+				}
+				for _, tt := range tests {
+					t.Run(tt.name, func(t *testing.T) {
+						srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+							w.WriteHeader(tt.status)
+						}))
+						defer srv.Close()
+
+						err := c.call(context.Background(), newClient(srv.URL, time.Second))
+						if err == nil {
+							t.Fatal("expected an error")
+						}
+						if got := errors.Is(err, llm.ErrUnavailable); got != tt.wantUnavail {
+							t.Errorf("errors.Is(err, llm.ErrUnavailable) = %v, want %v (err: %v)", got, tt.wantUnavail, err)
+						}
+					})
+				}
+			})
+
+			t.Run("network error is unavailable", func(t *testing.T) {
+				srv := httptest.NewServer(http.NotFoundHandler())
+				srv.Close()
+
+				if err := c.call(context.Background(), newClient(srv.URL, time.Second)); !errors.Is(err, llm.ErrUnavailable) {
+					t.Fatalf("err = %v, want llm.ErrUnavailable", err)
+				}
+			})
+
+			t.Run("timeout is unavailable", func(t *testing.T) {
+				srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					io.Copy(io.Discard, r.Body) // Koneksi yang diputus client baru terdeteksi setelah body dibaca.
+					select {
+					case <-r.Context().Done():
+					case <-time.After(5 * time.Second):
+					}
+				}))
+				defer srv.Close()
+
+				start := time.Now()
+				if err := c.call(context.Background(), newClient(srv.URL, 50*time.Millisecond)); !errors.Is(err, llm.ErrUnavailable) {
+					t.Fatalf("err = %v, want llm.ErrUnavailable", err)
+				}
+				if elapsed := time.Since(start); elapsed > 2*time.Second {
+					t.Errorf("request took %s, want it bounded by the timeout", elapsed)
+				}
+			})
+
+			t.Run("canceled is not unavailable", func(t *testing.T) {
+				srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					io.Copy(io.Discard, r.Body)
+					<-r.Context().Done()
+				}))
+				defer srv.Close()
+
+				ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+				defer cancel()
+				err := c.call(ctx, newClient(srv.URL, time.Minute))
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+				}
+				if errors.Is(err, llm.ErrUnavailable) {
+					t.Error("a request canceled by the caller must not be reported as llm.ErrUnavailable")
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,129 @@
+// Package llm mendefinisikan kontrak umum untuk penyedia model bahasa (LLM) yang dipakai aplikasi.
+// Setiap penyedia (misal: Gemini, fake untuk pengujian) mengimplementasikan interface Provider,
+// sehingga lapisan usecase tidak bergantung pada API penyedia tertentu.
+package llm
+
+import (
+	"context"
+	"errors"
+	"unicode/utf8"
+)
+
+// ErrUnavailable menandakan penyedia LLM sedang tidak tersedia (misal: kena rate limit, server error
+// yang terus berulang, atau circuit breaker terbuka). Penyedia membungkus error ini agar pemanggil
+// bisa membedakannya dari kegagalan lain dengan errors.Is.
+var ErrUnavailable = errors.New("llm provider is unavailable")
+
+// Role untuk setiap giliran percakapan.
+const (
+	RoleUser      = "user"      // Giliran yang ditulis oleh pengguna.
+	RoleAssistant = "assistant" // Giliran yang dihasilkan oleh model.
+)
+
+// Message adalah satu giliran di dalam percakapan.
+type Message struct {
+	Role    string
+	Content string
+}
+
+// Request adalah permintaan ke LLM berupa percakapan yang diurutkan dari yang terlama.
+type Request struct {
+	// System adalah instruksi sistem (persona) yang berlaku untuk seluruh percakapan. Boleh kosong.
+	System   string
+	Messages []Message
+	// Options menimpa pengaturan generasi default penyedia untuk panggilan ini saja.
+	Options *GenerationOptions
+}
+
+// GenerationOptions adalah pengaturan generasi yang umum di berbagai penyedia.
+// Field nil (atau slice kosong) berarti memakai nilai default penyedia/model.
+type GenerationOptions struct {
+	Temperature      *float64
+	TopP             *float64
+	TopK             *int
+	MaxOutputTokens  *int
+	StopSequences    []string
+	CandidateCount   *int
+	Seed             *int
+	PresencePenalty  *float64
+	FrequencyPenalty *float64
+}
+
+// Merge mengembalikan salinan o yang field-nya ditimpa oleh field override yang diisi.
+func (o GenerationOptions) Merge(override *GenerationOptions) GenerationOptions {
+	if override == nil {
+		return o
+	}
+	if override.Temperature != nil {
+		o.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		o.TopP = override.TopP
+	}
+	if override.TopK != nil {
+		o.TopK = override.TopK
+	}
+	if override.MaxOutputTokens != nil {
+		o.MaxOutputTokens = override.MaxOutputTokens
+	}
+	if len(override.StopSequences) > 0 {
+		o.StopSequences = override.StopSequences
+	}
+	if override.CandidateCount != nil {
+		o.CandidateCount = override.CandidateCount
+	}
+	if override.Seed != nil {
+		o.Seed = override.Seed
+	}
+	if override.PresencePenalty != nil {
+		o.PresencePenalty = override.PresencePenalty
+	}
+	if override.FrequencyPenalty != nil {
+		o.FrequencyPenalty = override.FrequencyPenalty
+	}
+	return o
+}
+
+// IsZero menandakan tidak ada pengaturan yang diisi.
+func (o GenerationOptions) IsZero() bool {
+	return o.Temperature == nil && o.TopP == nil && o.TopK == nil &&
+		o.MaxOutputTokens == nil && len(o.StopSequences) == 0 && o.CandidateCount == nil &&
+		o.Seed == nil && o.PresencePenalty == nil && o.FrequencyPenalty == nil
+}
+
+// Response adalah hasil dari sebuah permintaan ke LLM.
+type Response struct {
+	Text  string
+	Model string // Model yang benar-benar menghasilkan balasan.
+	Usage Usage
+}
+
+// Usage adalah jumlah token yang dipakai satu panggilan, sesuai laporan penyedia.
+// Bernilai nol jika penyedia tidak melaporkannya.
+type Usage struct {
+	PromptTokens    int // Token input (instruksi sistem dan percakapan).
+	CandidateTokens int // Token balasan yang dihasilkan.
+	TotalTokens     int
+}
+
+// Provider adalah kontrak untuk setiap penyedia LLM.
+type Provider interface {
+	// Generate mengirimkan percakapan dan mengembalikan balasan lengkap.
+	Generate(ctx context.Context, req Request) (*Response, error)
+	// Stream mengirimkan percakapan dan memanggil onChunk untuk setiap potongan teks balasan.
+	// Jika onChunk mengembalikan error, streaming dihentikan. Mengembalikan balasan lengkap.
+	Stream(ctx context.Context, req Request, onChunk func(text string) error) (*Response, error)
+	// CountTokens menghitung jumlah token input dari sebuah permintaan.
+	CountTokens(ctx context.Context, req Request) (int, error)
+}
+
+// UserPrompt membuat Request yang hanya berisi satu pesan dari pengguna.
+func UserPrompt(prompt string) Request {
+	return Request{Messages: []Message{{Role: RoleUser, Content: prompt}}}
+}
+
+// EstimateTokens memperkirakan jumlah token dari sebuah teks (sekitar 4 karakter per token).
+// Dipakai jika perhitungan pasti dari penyedia tidak diperlukan atau tidak tersedia.
+func EstimateTokens(text string) int {
+	return utf8.RuneCountInString(text)/4 + 1
+}
@@ -0,0 +1,85 @@
+package llm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func TestGenerationOptionsMerge(t *testing.T) {
+	defaults := GenerationOptions{
+		Temperature:     ptr(0.7),
+		TopK:            ptr(40),
+		MaxOutputTokens: ptr(512),
+		StopSequences:   []string{"END"},
+		Seed:            ptr(1),
+	}
+
+	tests := []struct {
+		name     string
+		override *GenerationOptions
+		want     GenerationOptions
+	}{
+		{name: "nil override", override: nil, want: defaults},
+		{name: "empty override", override: &GenerationOptions{}, want: defaults},
+		{
+			name: "override replaces only set fields",
+			override: &GenerationOptions{
+				Temperature:      ptr(0.0), // Nol tetap dianggap diisi.
+				TopP:             ptr(0.9),
+				StopSequences:    []string{"STOP", "###"},
+				CandidateCount:   ptr(2),
+				Seed:             ptr(42),
+				PresencePenalty:  ptr(0.5),
+				FrequencyPenalty: ptr(-0.5),
+			},
+			want: GenerationOptions{
+				Temperature:      ptr(0.0),
+				TopP:             ptr(0.9),
+				TopK:             ptr(40),
+				MaxOutputTokens:  ptr(512),
+				StopSequences:    []string{"STOP", "###"},
+				CandidateCount:   ptr(2),
+				Seed:             ptr(42),
+				PresencePenalty:  ptr(0.5),
+				FrequencyPenalty: ptr(-0.5),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := defaults.Merge(tt.override); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Merge() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// Merge tidak boleh mengubah pengaturan default.
+	if *defaults.Temperature != 0.7 || *defaults.Seed != 1 || len(defaults.StopSequences) != 1 {
+		t.Errorf("defaults changed to %+v", defaults)
+	}
+}
+
+func TestGenerationOptionsIsZero(t *testing.T) {
+	tests := []struct {
+		name string
+		opts GenerationOptions
+		want bool
+	}{
+		{name: "empty", want: true},
+		{name: "empty stop sequences", opts: GenerationOptions{StopSequences: []string{}}, want: true},
+		{name: "temperature", opts: GenerationOptions{Temperature: ptr(0.0)}},
+		{name: "stop sequences", opts: GenerationOptions{StopSequences: []string{"END"}}},
+		{name: "seed", opts: GenerationOptions{Seed: ptr(0)}},
+		{name: "presence penalty", opts: GenerationOptions{PresencePenalty: ptr(0.1)}},
+		{name: "frequency penalty", opts: GenerationOptions{FrequencyPenalty: ptr(0.1)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.IsZero(); got != tt.want {
+				t.Errorf("IsZero() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package llm
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// maxStreamLineSize adalah batas ukuran satu baris yang dibaca dari respons streaming.
+const maxStreamLineSize = 1024 * 1024
+
+// ReadLines membaca respons streaming baris per baris (misal: NDJSON) dan memanggil fn untuk
+// setiap baris yang tidak kosong. Berhenti saat fn mengembalikan error.
+func ReadLines(r io.Reader, fn func(line string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLineSize)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// ReadSSE membaca respons Server-Sent Events dan memanggil fn untuk setiap isi field `data:`
+// yang tidak kosong. Baris pemisah event dan field SSE lainnya diabaikan.
+func ReadSSE(r io.Reader, fn func(data string) error) error {
+	return ReadLines(r, func(line string) error {
+		if !strings.HasPrefix(line, "data:") {
+			return nil
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "" {
+			return nil
+		}
+		return fn(data)
+	})
+}
@@ -2,34 +2,71 @@
 package middleware
 
 import (
-	"crypto/subtle"
+	"context"
+	"net/http"
 
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/auth"
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/jwtkeys"
 	echojwt "github.com/labstack/echo-jwt/v4"
 	"github.com/labstack/echo/v4"
-	"github.com/labstack/echo/v4/middleware"
 )
 
+// RevocationChecker memeriksa apakah sebuah access token sudah dicabut berdasarkan claim `jti`-nya.
+type RevocationChecker interface {
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+}
+
 // JWTMiddleware membuat dan mengembalikan sebuah middleware Echo untuk validasi token JWT.
-// Middleware ini menggunakan secret yang diberikan untuk memverifikasi tanda tangan token.
+// Middleware ini memverifikasi tanda tangan token dengan kunci dari keys (dipilih berdasarkan `kid`), menolak token
+// tanpa `sub`, lalu menolak token tanpa `jti` atau yang `jti`-nya ada di daftar pencabutan.
+// revocations boleh nil untuk melewati pemeriksaan pencabutan.
 // Dependensi: github.com/labstack/echo-jwt/v4
-func JWTMiddleware(secret string) echo.MiddlewareFunc {
+func JWTMiddleware(keys *jwtkeys.KeySet, revocations RevocationChecker) echo.MiddlewareFunc {
 	config := echojwt.Config{
-		SigningKey: []byte(secret),
+		KeyFunc:       keys.Keyfunc,
+		NewClaimsFunc: auth.NewClaims,
+		ContextKey:    auth.ContextKey,
+	}
+	verify := echojwt.WithConfig(config)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return verify(func(c echo.Context) error {
+			principal, err := auth.PrincipalFromContext(c)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusUnauthorized, "token tidak valid")
+			}
+			if revocations == nil {
+				return next(c)
+			}
+			if principal.TokenID == "" {
+				return echo.NewHTTPError(http.StatusUnauthorized, "token tidak valid")
+			}
+			revoked, err := revocations.IsAccessTokenRevoked(c.Request().Context(), principal.TokenID)
+			if err != nil {
+				// Gagal tertutup: token yang statusnya tidak bisa dipastikan tidak diterima.
+				c.Logger().Errorf("failed to check token revocation: %v", err)
+				return echo.NewHTTPError(http.StatusServiceUnavailable, "token tidak bisa diverifikasi")
+			}
+			if revoked {
+				return echo.NewHTTPError(http.StatusUnauthorized, "token sudah dicabut")
+			}
+			return next(c)
+		})
 	}
-	return echojwt.WithConfig(config)
 }
 
-// BasicAuthMiddleware membuat dan mengembalikan sebuah middleware Echo untuk validasi Basic Auth.
-// Middleware ini membandingkan username dan password dari request dengan yang seharusnya
-// menggunakan `subtle.ConstantTimeCompare` untuk mencegah timing attacks.
-// Dependensi: github.com/labstack/echo/v4/middleware
-func BasicAuthMiddleware(username, password string) echo.MiddlewareFunc {
-	return middleware.BasicAuth(func(user, pass string, c echo.Context) (bool, error) {
-		// Menggunakan ConstantTimeCompare untuk keamanan.
-		if subtle.ConstantTimeCompare([]byte(user), []byte(username)) == 1 &&
-			subtle.ConstantTimeCompare([]byte(pass), []byte(password)) == 1 {
-			return true, nil
+// RequirePermission membuat middleware Echo yang hanya meneruskan request dari pengguna yang role-nya
+// memiliki permission perm. Harus dipasang setelah JWTMiddleware.
+func RequirePermission(perm auth.Permission) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			principal, err := auth.PrincipalFromContext(c)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusUnauthorized, "token tidak ditemukan")
+			}
+			if !principal.Can(perm) {
+				return echo.NewHTTPError(http.StatusForbidden, "akses ditolak")
+			}
+			return next(c)
 		}
-		return false, nil
-	})
-}
\ No newline at end of file
+	}
+}
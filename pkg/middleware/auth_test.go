@@ -0,0 +1,268 @@
+package middleware
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/auth"
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/jwtkeys"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// fakeRevocations adalah RevocationChecker uji yang mencabut jti tertentu.
+type fakeRevocations struct {
+	revoked map[string]bool
+	err     error
+}
+
+func (f fakeRevocations) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	return f.revoked[jti], f.err
+}
+
+// newEdDSAKeySet membuat KeySet EdDSA dengan satu kunci penandatangan.
+func newEdDSAKeySet(t *testing.T, kid string) *jwtkeys.KeySet {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	keys, err := jwtkeys.NewKeySet([]*jwtkeys.Key{{ID: kid, Method: jwt.SigningMethodEdDSA, Private: priv, Public: pub}}, kid)
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+	return keys
+}
+
+// validClaims mengembalikan claims token akses yang masih berlaku.
+func validClaims() *auth.Claims {
+	now := time.Now()
+	return &auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "user-1",
+			ID:        "jti-1",
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		},
+		Name: "Budi",
+	}
+}
+
+// mustSign menandatangani claims dengan keys atau menggagalkan test.
+func mustSign(t *testing.T, keys *jwtkeys.KeySet, claims jwt.Claims) string {
+	t.Helper()
+	token, err := keys.Sign(claims)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return token
+}
+
+// serveWithJWT menjalankan satu request ber-token melalui JWTMiddleware dan mengembalikan responsnya.
+// Handler di belakang middleware menulis ID principal sebagai body.
+func serveWithJWT(keys *jwtkeys.KeySet, revocations RevocationChecker, token string) *httptest.ResponseRecorder {
+	e := echo.New()
+	e.GET("/me", func(c echo.Context) error {
+		principal, err := auth.PrincipalFromContext(c)
+		if err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, principal.UserID)
+	}, JWTMiddleware(keys, revocations))
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	if token != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestJWTMiddlewareAsymmetricKeys(t *testing.T) {
+	keys := newEdDSAKeySet(t, "k1")
+	otherKeys := newEdDSAKeySet(t, "k2")
+	// Kunci lain dengan kid yang sama: kid dikenali, tetapi tanda tangannya tidak cocok.
+	impostorKeys := newEdDSAKeySet(t, "k1")
+
+	expired := validClaims()
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	noSubject := validClaims()
+	noSubject.Subject = ""
+
+	// HS256 dengan kid yang valid tidak boleh diterima saat kunci asimetris dipakai.
+	hs256 := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims())
+	hs256.Header["kid"] = "k1"
+	hs256Token, err := hs256.SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("sign HS256: %v", err)
+	}
+	none := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims())
+	none.Header["kid"] = "k1"
+	noneToken, err := none.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("sign none: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+	}{
+		{name: "valid token", token: mustSign(t, keys, validClaims()), wantStatus: http.StatusOK},
+		{name: "expired token", token: mustSign(t, keys, expired), wantStatus: http.StatusUnauthorized},
+		{name: "unknown kid", token: mustSign(t, otherKeys, validClaims()), wantStatus: http.StatusUnauthorized},
+		{name: "wrong key for kid", token: mustSign(t, impostorKeys, validClaims()), wantStatus: http.StatusUnauthorized},
+		{name: "hs256 alg mismatch", token: hs256Token, wantStatus: http.StatusUnauthorized},
+		{name: "none alg", token: noneToken, wantStatus: http.StatusUnauthorized},
+		{name: "missing sub", token: mustSign(t, keys, noSubject), wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveWithJWT(keys, nil, tt.token)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %q)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusOK && rec.Body.String() != "user-1" {
+				t.Errorf("principal = %q, want user-1", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestJWTMiddlewareHMACFallback(t *testing.T) {
+	keys, err := jwtkeys.NewHMACKeySet("secret")
+	if err != nil {
+		t.Fatalf("NewHMACKeySet: %v", err)
+	}
+	otherKeys, err := jwtkeys.NewHMACKeySet("other-secret")
+	if err != nil {
+		t.Fatalf("NewHMACKeySet: %v", err)
+	}
+	none, err := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("sign none: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+	}{
+		{name: "valid token", token: mustSign(t, keys, validClaims()), wantStatus: http.StatusOK},
+		{name: "wrong secret", token: mustSign(t, otherKeys, validClaims()), wantStatus: http.StatusUnauthorized},
+		{name: "asymmetric alg", token: mustSign(t, newEdDSAKeySet(t, "k1"), validClaims()), wantStatus: http.StatusUnauthorized},
+		{name: "none alg", token: none, wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := serveWithJWT(keys, nil, tt.token); rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %q)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestJWTMiddlewareRevocation(t *testing.T) {
+	keys := newEdDSAKeySet(t, "k1")
+	noTokenID := validClaims()
+	noTokenID.ID = ""
+
+	tests := []struct {
+		name        string
+		revocations fakeRevocations
+		claims      *auth.Claims
+		wantStatus  int
+	}{
+		{name: "not revoked", claims: validClaims(), wantStatus: http.StatusOK},
+		{name: "revoked", revocations: fakeRevocations{revoked: map[string]bool{"jti-1": true}}, claims: validClaims(), wantStatus: http.StatusUnauthorized},
+		{name: "missing jti", claims: noTokenID, wantStatus: http.StatusUnauthorized},
+		{name: "checker error fails closed", revocations: fakeRevocations{err: errors.New("store down")}, claims: validClaims(), wantStatus: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := serveWithJWT(keys, tt.revocations, mustSign(t, keys, tt.claims)); rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %q)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	keys := newEdDSAKeySet(t, "k1")
+	tests := []struct {
+		name       string
+		roles      []string
+		perm       auth.Permission
+		wantStatus int
+	}{
+		{name: "user reads any user", roles: []string{auth.RoleUser}, perm: auth.PermReadAnyUser, wantStatus: http.StatusForbidden},
+		{name: "admin reads any user", roles: []string{auth.RoleAdmin}, perm: auth.PermReadAnyUser, wantStatus: http.StatusOK},
+		{name: "user creates user", roles: []string{auth.RoleUser}, perm: auth.PermCreateUser, wantStatus: http.StatusForbidden},
+		{name: "admin creates user", roles: []string{auth.RoleAdmin}, perm: auth.PermCreateUser, wantStatus: http.StatusOK},
+		{name: "user moderates rooms", roles: []string{auth.RoleUser}, perm: auth.PermModerateRooms, wantStatus: http.StatusForbidden},
+		{name: "admin moderates rooms", roles: []string{auth.RoleAdmin}, perm: auth.PermModerateRooms, wantStatus: http.StatusOK},
+		{name: "token without roles", roles: nil, perm: auth.PermReadAnyUser, wantStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims()
+			claims.Roles = tt.roles
+
+			// Handler menulis role yang dibaca dari token, untuk memastikan role tidak berubah di perjalanan.
+			e := echo.New()
+			e.GET("/protected", func(c echo.Context) error {
+				principal, err := auth.PrincipalFromContext(c)
+				if err != nil {
+					return err
+				}
+				return c.String(http.StatusOK, strings.Join(principal.Roles, ","))
+			}, JWTMiddleware(keys, nil), RequirePermission(tt.perm))
+
+			req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+mustSign(t, keys, claims))
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %q)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusOK && rec.Body.String() != strings.Join(tt.roles, ",") {
+				t.Errorf("roles = %q, want %q", rec.Body.String(), strings.Join(tt.roles, ","))
+			}
+		})
+	}
+}
+
+func TestRolesSurviveClaimsRoundTrip(t *testing.T) {
+	keys := newEdDSAKeySet(t, "k1")
+	claims := validClaims()
+	claims.Roles = []string{auth.RoleUser, auth.RoleAdmin}
+	claims.SessionID = "sesi-1"
+
+	parsed := &auth.Claims{}
+	if _, err := jwt.ParseWithClaims(mustSign(t, keys, claims), parsed, keys.Keyfunc); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	c.Set(auth.ContextKey, &jwt.Token{Claims: parsed})
+	principal, err := auth.PrincipalFromContext(c)
+	if err != nil {
+		t.Fatalf("PrincipalFromContext: %v", err)
+	}
+
+	if !reflect.DeepEqual(principal.Roles, claims.Roles) || principal.SessionID != "sesi-1" || principal.TokenID != claims.ID {
+		t.Errorf("principal = %+v, want roles %v from the token", principal, claims.Roles)
+	}
+	if !principal.Can(auth.PermModerateRooms) {
+		t.Error("admin role from the token does not grant its permissions")
+	}
+}
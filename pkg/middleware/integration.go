@@ -2,25 +2,36 @@
 package middleware
 
 import (
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/auth"
 	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/config"
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/jwtkeys"
 	"github.com/labstack/echo/v4"
 )
 
 // Middleware adalah struct yang berfungsi sebagai container untuk semua middleware yang digunakan dalam aplikasi.
 // Ini memusatkan logika pembuatan middleware dan membuatnya mudah untuk di-inject ke router.
 type Middleware struct {
-	BasicAuth    echo.MiddlewareFunc
 	JWT          echo.MiddlewareFunc
 	GeminiAPIKey echo.MiddlewareFunc
+
+	// Pemeriksaan permission berdasarkan role di token; dipasang setelah JWT.
+	CreateUser    echo.MiddlewareFunc
+	ModerateRooms echo.MiddlewareFunc
+	ViewUsage     echo.MiddlewareFunc
+	ManageUsers   echo.MiddlewareFunc
 }
 
 // NewMiddleware membuat instance baru dari struct Middleware.
-// Ia mengambil semua dependensi yang diperlukan (seperti config) untuk menginisialisasi
-// semua middleware yang dibutuhkan oleh aplikasi.
-func NewMiddleware(cfg *config.Config) *Middleware {
+// Ia mengambil semua dependensi yang diperlukan (seperti config, kunci JWT dan daftar token yang dicabut)
+// untuk menginisialisasi semua middleware yang dibutuhkan oleh aplikasi.
+func NewMiddleware(cfg *config.Config, jwtKeys *jwtkeys.KeySet, revocations RevocationChecker) *Middleware {
 	return &Middleware{
-		BasicAuth:    BasicAuthMiddleware(cfg.BasicAuthUser, cfg.BasicAuthPass),
-		JWT:          JWTMiddleware(cfg.JWTSecret),
+		JWT:          JWTMiddleware(jwtKeys, revocations),
 		GeminiAPIKey: GeminiAPIKeyMiddleware,
+
+		CreateUser:    RequirePermission(auth.PermCreateUser),
+		ModerateRooms: RequirePermission(auth.PermModerateRooms),
+		ViewUsage:     RequirePermission(auth.PermViewUsage),
+		ManageUsers:   RequirePermission(auth.PermManageUsers),
 	}
 }
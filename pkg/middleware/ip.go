@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// NewIPExtractor membuat echo.IPExtractor yang menentukan IP client untuk c.RealIP(), misalnya untuk
+// penghitung lockout login per IP. Tanpa trustedProxies, IP diambil langsung dari koneksi dan header
+// X-Forwarded-For/X-Real-IP diabaikan, karena header tersebut bisa diisi sembarang oleh client.
+// Jika server berada di belakang reverse proxy, trustedProxies berisi IP atau CIDR proxy tersebut;
+// X-Forwarded-For hanya dibaca dari koneksi yang berasal dari proxy itu.
+func NewIPExtractor(trustedProxies []string) (echo.IPExtractor, error) {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+
+	// Rentang loopback, link-local dan jaringan privat dipercaya secara default oleh echo;
+	// di sini hanya proxy yang disebutkan yang dipercaya.
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, proxy := range trustedProxies {
+		proxy = strings.TrimSpace(proxy)
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: not an IP or CIDR", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				bits = 8 * net.IPv4len
+			}
+			proxy = fmt.Sprintf("%s/%d", proxy, bits)
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		options = append(options, echo.TrustIPRange(ipNet))
+	}
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
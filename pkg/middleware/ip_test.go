@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewIPExtractor(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		remoteAddr     string
+		xff            string
+		want           string
+	}{
+		{name: "direct ignores forwarded header", remoteAddr: "203.0.113.7:1234", xff: "198.51.100.1", want: "203.0.113.7"},
+		{name: "direct ignores header from private network", remoteAddr: "10.0.0.5:1234", xff: "198.51.100.1", want: "10.0.0.5"},
+		{name: "trusted proxy ip", trustedProxies: []string{"10.0.0.5"}, remoteAddr: "10.0.0.5:1234", xff: "198.51.100.1", want: "198.51.100.1"},
+		{name: "trusted proxy cidr", trustedProxies: []string{"10.0.0.0/24"}, remoteAddr: "10.0.0.9:1234", xff: "198.51.100.1", want: "198.51.100.1"},
+		{name: "spoofed hop before the proxy", trustedProxies: []string{"10.0.0.5"}, remoteAddr: "10.0.0.5:1234", xff: "1.1.1.1, 198.51.100.1", want: "198.51.100.1"},
+		{name: "untrusted peer", trustedProxies: []string{"10.0.0.5"}, remoteAddr: "192.168.1.20:1234", xff: "198.51.100.1", want: "192.168.1.20"},
+		{name: "untrusted loopback", trustedProxies: []string{"10.0.0.5"}, remoteAddr: "127.0.0.1:1234", xff: "198.51.100.1", want: "127.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extract, err := NewIPExtractor(tt.trustedProxies)
+			if err != nil {
+				t.Fatalf("NewIPExtractor: %v", err)
+			}
+			req := httptest.NewRequest("POST", "/v1/login", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("X-Forwarded-For", tt.xff)
+			req.Header.Set("X-Real-IP", tt.xff)
+			if got := extract(req); got != tt.want {
+				t.Errorf("ip = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewIPExtractorRejectsInvalidProxy(t *testing.T) {
+	for _, proxy := range []string{"proxy.local", "10.0.0.0/33"} {
+		if _, err := NewIPExtractor([]string{proxy}); err == nil {
+			t.Errorf("NewIPExtractor(%q) accepted an invalid proxy", proxy)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+// Package ollama menyediakan klien untuk API chat Ollama (`/api/chat`),
+// sehingga chat bisa dijalankan dengan model self-hosted.
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/llm"
+)
+
+const (
+	// DefaultBaseURL adalah alamat default server Ollama lokal.
+	DefaultBaseURL = "http://localhost:11434"
+	// DefaultTimeout dipakai jika timeout tidak diatur di config.
+	DefaultTimeout = 2 * time.Minute
+	// DefaultModel dipakai jika model tidak diatur di config.
+	DefaultModel = "llama3.1"
+
+	// roleSystem adalah role untuk instruksi sistem (llm.Request.System).
+	roleSystem = "system"
+)
+
+// Memastikan Client memenuhi kontrak llm.Provider.
+var _ llm.Provider = (*Client)(nil)
+
+// Client adalah klien untuk API chat Ollama.
+type Client struct {
+	baseURL    string
+	model      string
+	httpClient *http.Client
+}
+
+// NewClient membuat instance baru dari Client.
+// timeout membatasi satu request termasuk membaca stream balasannya; 0 berarti DefaultTimeout.
+func NewClient(baseURL, model string, timeout time.Duration) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	if model == "" {
+		model = DefaultModel
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		model:      model,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// Generate mengirimkan percakapan dengan `stream: false` dan mengembalikan balasan lengkap.
+func (c *Client) Generate(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	// 1. Mengirim request tanpa streaming.
+	resp, err := c.do(ctx, c.newChatRequest(req, false))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// 2. Membaca dan mengekstrak teks dari respons.
+	var chatResp ChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response body: %w", err)
+	}
+	if chatResp.Error != "" {
+		return nil, fmt.Errorf("ollama api returned error: %s", chatResp.Error)
+	}
+	if chatResp.Message.Content == "" {
+		return nil, fmt.Errorf("no content found in ollama response")
+	}
+
+	return &llm.Response{Text: chatResp.Message.Content, Model: c.model, Usage: chatResp.usage()}, nil
+}
+
+// Stream mengirimkan percakapan dengan `stream: true`. Ollama mengirim respons sebagai NDJSON
+// (satu objek JSON per baris) sampai objek dengan `done: true`.
+func (c *Client) Stream(ctx context.Context, req llm.Request, onChunk func(text string) error) (*llm.Response, error) {
+	// 1. Mengirim request dengan streaming.
+	resp, err := c.do(ctx, c.newChatRequest(req, true))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// 2. Membaca NDJSON baris per baris sampai `done: true`.
+	var full strings.Builder
+	var usage llm.Usage
+	errDone := errors.New("stream done")
+	err = llm.ReadLines(resp.Body, func(line string) error {
+		var chunk ChatResponse
+		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+			return fmt.Errorf("failed to unmarshal stream chunk: %w", err)
+		}
+		if chunk.Error != "" {
+			return fmt.Errorf("ollama api returned error: %s", chunk.Error)
+		}
+
+		if text := chunk.Message.Content; text != "" {
+			full.WriteString(text)
+			if err := onChunk(text); err != nil {
+				return err
+			}
+		}
+		if chunk.Done {
+			// Jumlah token hanya dilaporkan di objek terakhir.
+			usage = chunk.usage()
+			return errDone
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDone) {
+		return &llm.Response{Text: full.String(), Model: c.model, Usage: usage}, err
+	}
+
+	// 3. Stream yang selesai tanpa teks sama sekali dianggap error, sama seperti Generate.
+	if full.Len() == 0 {
+		return nil, fmt.Errorf("no content found in ollama response")
+	}
+
+	return &llm.Response{Text: full.String(), Model: c.model, Usage: usage}, nil
+}
+
+// CountTokens memperkirakan jumlah token input, karena API chat Ollama tidak menyediakan
+// endpoint penghitung token.
+func (c *Client) CountTokens(ctx context.Context, req llm.Request) (int, error) {
+	total := llm.EstimateTokens(req.System)
+	for _, msg := range req.Messages {
+		total += llm.EstimateTokens(msg.Content)
+	}
+	return total, nil
+}
+
+// newChatRequest mengubah llm.Request menjadi body request `/api/chat`.
+func (c *Client) newChatRequest(req llm.Request, stream bool) ChatRequest {
+	messages := make([]ChatMessage, 0, len(req.Messages)+1)
+	if req.System != "" {
+		messages = append(messages, ChatMessage{Role: roleSystem, Content: req.System})
+	}
+	for _, msg := range req.Messages {
+		messages = append(messages, ChatMessage{Role: msg.Role, Content: msg.Content})
+	}
+	return ChatRequest{
+		Model:    c.model,
+		Messages: messages,
+		Stream:   stream,
+	}
+}
+
+// do mengirim request ke endpoint `/api/chat` dan memastikan status respons 200.
+// Pemanggil bertanggung jawab menutup body respons.
+func (c *Client) do(ctx context.Context, body ChatRequest) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/chat", bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// Request yang dibatalkan pemanggil bukan tanda penyedia tidak tersedia.
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("failed to send request to ollama api: %w", err)
+		}
+		return nil, fmt.Errorf("%w: failed to send request to ollama api: %w", llm.ErrUnavailable, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		err := fmt.Errorf("ollama api returned non-200 status: %d, body: %s", resp.StatusCode, string(respBody))
+		if temporaryStatus(resp.StatusCode) {
+			return nil, fmt.Errorf("%w: %v", llm.ErrUnavailable, err)
+		}
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// temporaryStatus menandakan status respons karena Ollama sedang sibuk atau bermasalah (429 dan 5xx),
+// sehingga request yang sama layak dicoba lagi atau dialihkan ke penyedia cadangan.
+func temporaryStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// --- Structs for JSON Marshalling/Unmarshalling ---
+
+type ChatRequest struct {
+	Model    string        `json:"model"`
+	Messages []ChatMessage `json:"messages"`
+	// Stream harus selalu dikirim, karena Ollama melakukan streaming secara default.
+	Stream bool `json:"stream"`
+}
+
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ChatResponse struct {
+	Message         ChatMessage `json:"message"`
+	Done            bool        `json:"done"`
+	Error           string      `json:"error,omitempty"`
+	PromptEvalCount int         `json:"prompt_eval_count,omitempty"` // Token input, diisi saat done.
+	EvalCount       int         `json:"eval_count,omitempty"`        // Token balasan, diisi saat done.
+}
+
+// usage mengubah jumlah token dari respons menjadi llm.Usage.
+func (r ChatResponse) usage() llm.Usage {
+	return llm.Usage{
+		PromptTokens:    r.PromptEvalCount,
+		CandidateTokens: r.EvalCount,
+		TotalTokens:     r.PromptEvalCount + r.EvalCount,
+	}
+}
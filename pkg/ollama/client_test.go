@@ -0,0 +1,144 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/llm"
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/llm/llmtest"
+)
+
+// newTestClient membuat Client yang mengarah ke server uji.
+func newTestClient(url string, timeout time.Duration) *Client {
+	return NewClient(url, "", timeout)
+}
+
+// ndjsonChunk membuat satu baris NDJSON berisi potongan teks balasan yang belum selesai.
+func ndjsonChunk(text string) string {
+	return fmt.Sprintf("{\"message\":{\"role\":\"assistant\",\"content\":%q},\"done\":false}\n", text)
+}
+
+// decodeChatRequest membaca body request `/api/chat` yang dikirim klien.
+func decodeChatRequest(t *testing.T, r *http.Request) ChatRequest {
+	t.Helper()
+	if r.URL.Path != "/api/chat" {
+		t.Errorf("path = %s, want /api/chat", r.URL.Path)
+	}
+	var body ChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		t.Errorf("decode request: %v", err)
+	}
+	return body
+}
+
+func TestErrorClassification(t *testing.T) {
+	llmtest.RunErrorClassification(t, func(baseURL string, timeout time.Duration) llm.Provider {
+		return newTestClient(baseURL, timeout)
+	})
+}
+
+func TestGenerate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := decodeChatRequest(t, r)
+		if body.Stream || body.Model != DefaultModel {
+			t.Errorf("request = %+v, want a non-streaming request for %s", body, DefaultModel)
+		}
+		// Instruksi sistem dikirim sebagai pesan pertama dengan role system.
+		want := []ChatMessage{{Role: roleSystem, Content: "persona"}, {Role: llm.RoleUser, Content: "hai"}}
+		if fmt.Sprint(body.Messages) != fmt.Sprint(want) {
+			t.Errorf("messages = %+v, want %+v", body.Messages, want)
+		}
+		fmt.Fprint(w, `{"message":{"role":"assistant","content":"Halo!"},"done":true,"prompt_eval_count":3,"eval_count":2}`)
+	}))
+	defer srv.Close()
+
+	req := llm.UserPrompt("hai")
+	req.System = "persona"
+	resp, err := newTestClient(srv.URL, time.Second).Generate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	want := llm.Response{Text: "Halo!", Model: DefaultModel, Usage: llm.Usage{PromptTokens: 3, CandidateTokens: 2, TotalTokens: 5}}
+	if *resp != want {
+		t.Errorf("response = %+v, want %+v", *resp, want)
+	}
+}
+
+func TestStream(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantChunks []string
+		wantUsage  llm.Usage
+		wantErr    bool
+	}{
+		{
+			name: "chunks until done",
+			body: ndjsonChunk("Halo") + "\n" + ndjsonChunk(", apa") +
+				`{"message":{"role":"assistant","content":" kabar?"},"done":true,"prompt_eval_count":3,"eval_count":4}` + "\n" +
+				ndjsonChunk("setelah done diabaikan"),
+			wantChunks: []string{"Halo", ", apa", " kabar?"},
+			wantUsage:  llm.Usage{PromptTokens: 3, CandidateTokens: 4, TotalTokens: 7},
+		},
+		{
+			// Objek done tanpa teks hanya membawa jumlah token.
+			name:       "empty done object",
+			body:       ndjsonChunk("Halo") + `{"message":{"role":"assistant","content":""},"done":true,"prompt_eval_count":1,"eval_count":1}`,
+			wantChunks: []string{"Halo"},
+			wantUsage:  llm.Usage{PromptTokens: 1, CandidateTokens: 1, TotalTokens: 2},
+		},
+		{
+			name:       "error object",
+			body:       ndjsonChunk("Halo") + `{"error":"model crashed"}` + "\n",
+			wantChunks: []string{"Halo"},
+			wantErr:    true,
+		},
+		{
+			name:    "no content",
+			body:    `{"message":{"role":"assistant","content":""},"done":true}` + "\n",
+			wantErr: true,
+		},
+		{
+			name:       "malformed line",
+			body:       ndjsonChunk("Halo") + `{"message":` + "\n",
+			wantChunks: []string{"Halo"},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if body := decodeChatRequest(t, r); !body.Stream {
+					t.Errorf("request = %+v, want stream", body)
+				}
+				w.Header().Set("Content-Type", "application/x-ndjson")
+				fmt.Fprint(w, tt.body)
+			}))
+			defer srv.Close()
+
+			var chunks []string
+			resp, err := newTestClient(srv.URL, time.Second).Stream(context.Background(), llm.UserPrompt("hai"), func(text string) error {
+				chunks = append(chunks, text)
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if strings.Join(chunks, "|") != strings.Join(tt.wantChunks, "|") {
+				t.Errorf("chunks = %q, want %q", chunks, tt.wantChunks)
+			}
+			if tt.wantErr {
+				return
+			}
+			if resp.Text != strings.Join(tt.wantChunks, "") || resp.Model != DefaultModel || resp.Usage != tt.wantUsage {
+				t.Errorf("response = %+v, want the joined chunks from %s with usage %+v", *resp, DefaultModel, tt.wantUsage)
+			}
+		})
+	}
+}
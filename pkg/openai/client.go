@@ -0,0 +1,251 @@
+// Package openai menyediakan klien untuk API yang kompatibel dengan OpenAI `/v1/chat/completions`.
+// Klien ini bisa dipakai untuk OpenAI sendiri maupun server self-hosted yang meniru protokolnya
+// (misal: vLLM, LM Studio, llama.cpp server).
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/llm"
+)
+
+const (
+	// DefaultBaseURL adalah base URL OpenAI, termasuk prefix versi API.
+	DefaultBaseURL = "https://api.openai.com/v1"
+	// DefaultTimeout dipakai jika timeout tidak diatur di config.
+	DefaultTimeout = 2 * time.Minute
+	// DefaultModel dipakai jika model tidak diatur di config.
+	DefaultModel = "gpt-4o-mini"
+
+	// streamDone adalah penanda akhir stream SSE dari API chat completions.
+	streamDone = "[DONE]"
+
+	// roleSystem adalah role untuk instruksi sistem (llm.Request.System).
+	roleSystem = "system"
+)
+
+// Memastikan Client memenuhi kontrak llm.Provider.
+var _ llm.Provider = (*Client)(nil)
+
+// Client adalah klien untuk API chat completions yang kompatibel dengan OpenAI.
+type Client struct {
+	baseURL    string
+	apiKey     string // Boleh kosong untuk server self-hosted tanpa autentikasi.
+	model      string
+	httpClient *http.Client
+}
+
+// NewClient membuat instance baru dari Client. baseURL harus sudah berisi prefix versi (misal: ".../v1").
+// timeout membatasi satu request termasuk membaca stream balasannya; 0 berarti DefaultTimeout.
+func NewClient(baseURL, apiKey, model string, timeout time.Duration) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	if model == "" {
+		model = DefaultModel
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		model:      model,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// Generate mengirimkan percakapan dan mengembalikan balasan lengkap.
+func (c *Client) Generate(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	// 1. Mengirim request tanpa streaming.
+	resp, err := c.do(ctx, c.newChatRequest(req, false))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// 2. Membaca dan mengekstrak teks dari pilihan pertama.
+	var chatResp ChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response body: %w", err)
+	}
+	if len(chatResp.Choices) == 0 || chatResp.Choices[0].Message.Content == "" {
+		return nil, fmt.Errorf("no content found in openai response")
+	}
+
+	return &llm.Response{Text: chatResp.Choices[0].Message.Content, Model: c.model, Usage: chatResp.Usage.toLLM()}, nil
+}
+
+// Stream mengirimkan percakapan dengan `stream: true` dan memanggil onChunk untuk setiap potongan teks
+// yang diterima melalui Server-Sent Events.
+func (c *Client) Stream(ctx context.Context, req llm.Request, onChunk func(text string) error) (*llm.Response, error) {
+	// 1. Mengirim request dengan streaming.
+	resp, err := c.do(ctx, c.newChatRequest(req, true))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// 2. Membaca event SSE sampai penanda `[DONE]`. Setiap event berisi satu ChatResponse dengan `delta`.
+	var full strings.Builder
+	var usage llm.Usage
+	errDone := errors.New("stream done")
+	err = llm.ReadSSE(resp.Body, func(data string) error {
+		if data == streamDone {
+			return errDone
+		}
+
+		var chunk ChatResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("failed to unmarshal stream chunk: %w", err)
+		}
+		// Pemakaian token dikirim di potongan terakhir (tanpa choices) karena `include_usage`.
+		if chunk.Usage != nil {
+			usage = chunk.Usage.toLLM()
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			return nil
+		}
+
+		text := chunk.Choices[0].Delta.Content
+		full.WriteString(text)
+		return onChunk(text)
+	})
+	if err != nil && !errors.Is(err, errDone) {
+		return &llm.Response{Text: full.String(), Model: c.model, Usage: usage}, err
+	}
+
+	// 3. Stream yang selesai tanpa teks sama sekali dianggap error, sama seperti Generate.
+	if full.Len() == 0 {
+		return nil, fmt.Errorf("no content found in openai response")
+	}
+
+	return &llm.Response{Text: full.String(), Model: c.model, Usage: usage}, nil
+}
+
+// CountTokens memperkirakan jumlah token input, karena API chat completions tidak menyediakan
+// endpoint penghitung token.
+func (c *Client) CountTokens(ctx context.Context, req llm.Request) (int, error) {
+	total := llm.EstimateTokens(req.System)
+	for _, msg := range req.Messages {
+		total += llm.EstimateTokens(msg.Content)
+	}
+	return total, nil
+}
+
+// newChatRequest mengubah llm.Request menjadi body request chat completions.
+func (c *Client) newChatRequest(req llm.Request, stream bool) ChatRequest {
+	messages := make([]ChatMessage, 0, len(req.Messages)+1)
+	if req.System != "" {
+		messages = append(messages, ChatMessage{Role: roleSystem, Content: req.System})
+	}
+	for _, msg := range req.Messages {
+		messages = append(messages, ChatMessage{Role: msg.Role, Content: msg.Content})
+	}
+	body := ChatRequest{
+		Model:    c.model,
+		Messages: messages,
+		Stream:   stream,
+	}
+	if stream {
+		body.StreamOptions = &StreamOptions{IncludeUsage: true}
+	}
+	return body
+}
+
+// do mengirim request ke endpoint `/chat/completions` dan memastikan status respons 200.
+// Pemanggil bertanggung jawab menutup body respons.
+func (c *Client) do(ctx context.Context, body ChatRequest) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/chat/completions", bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	if body.Stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// Request yang dibatalkan pemanggil bukan tanda penyedia tidak tersedia.
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("failed to send request to openai api: %w", err)
+		}
+		return nil, fmt.Errorf("%w: failed to send request to openai api: %w", llm.ErrUnavailable, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		err := fmt.Errorf("openai api returned non-200 status: %d, body: %s", resp.StatusCode, string(respBody))
+		if temporaryStatus(resp.StatusCode) {
+			return nil, fmt.Errorf("%w: %v", llm.ErrUnavailable, err)
+		}
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// temporaryStatus menandakan status respons karena OpenAI sedang sibuk atau bermasalah (429 dan 5xx),
+// sehingga request yang sama layak dicoba lagi atau dialihkan ke penyedia cadangan.
+func temporaryStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// --- Structs for JSON Marshalling/Unmarshalling ---
+
+type ChatRequest struct {
+	Model         string         `json:"model"`
+	Messages      []ChatMessage  `json:"messages"`
+	Stream        bool           `json:"stream,omitempty"`
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+}
+
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ChatResponse struct {
+	Choices []Choice `json:"choices"`
+	Usage   *Usage   `json:"usage,omitempty"`
+}
+
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// toLLM mengubah Usage menjadi llm.Usage. Aman dipanggil pada nil.
+func (u *Usage) toLLM() llm.Usage {
+	if u == nil {
+		return llm.Usage{}
+	}
+	return llm.Usage{PromptTokens: u.PromptTokens, CandidateTokens: u.CompletionTokens, TotalTokens: u.TotalTokens}
+}
+
+type Choice struct {
+	Message ChatMessage `json:"message"` // Diisi pada respons tanpa streaming.
+	Delta   ChatMessage `json:"delta"`   // Diisi pada setiap potongan stream.
+}
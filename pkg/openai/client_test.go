@@ -0,0 +1,142 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/llm"
+	"github.com/gemini-cli/portfolio-chat-ai-go/pkg/llm/llmtest"
+)
+
+// newTestClient membuat Client yang mengarah ke server uji.
+func newTestClient(url string, timeout time.Duration) *Client {
+	return NewClient(url, "", "", timeout)
+}
+
+// sseDelta membuat satu event SSE berisi potongan teks chat completions.
+func sseDelta(text string) string {
+	return fmt.Sprintf("data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", text)
+}
+
+// decodeChatRequest membaca body request chat completions yang dikirim klien.
+func decodeChatRequest(t *testing.T, r *http.Request) ChatRequest {
+	t.Helper()
+	if r.URL.Path != "/chat/completions" {
+		t.Errorf("path = %s, want /chat/completions", r.URL.Path)
+	}
+	var body ChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		t.Errorf("decode request: %v", err)
+	}
+	return body
+}
+
+func TestErrorClassification(t *testing.T) {
+	llmtest.RunErrorClassification(t, func(baseURL string, timeout time.Duration) llm.Provider {
+		return newTestClient(baseURL, timeout)
+	})
+}
+
+func TestGenerate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := decodeChatRequest(t, r)
+		if body.Stream || body.Model != DefaultModel {
+			t.Errorf("request = %+v, want a non-streaming request for %s", body, DefaultModel)
+		}
+		// Instruksi sistem dikirim sebagai pesan pertama dengan role system.
+		want := []ChatMessage{{Role: roleSystem, Content: "persona"}, {Role: llm.RoleUser, Content: "hai"}}
+		if fmt.Sprint(body.Messages) != fmt.Sprint(want) {
+			t.Errorf("messages = %+v, want %+v", body.Messages, want)
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"Halo!"}}],`+
+			`"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`)
+	}))
+	defer srv.Close()
+
+	req := llm.UserPrompt("hai")
+	req.System = "persona"
+	resp, err := newTestClient(srv.URL, time.Second).Generate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	want := llm.Response{Text: "Halo!", Model: DefaultModel, Usage: llm.Usage{PromptTokens: 3, CandidateTokens: 2, TotalTokens: 5}}
+	if *resp != want {
+		t.Errorf("response = %+v, want %+v", *resp, want)
+	}
+}
+
+func TestStream(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantChunks []string
+		wantUsage  llm.Usage
+		wantErr    bool
+	}{
+		{
+			name: "chunks until done",
+			body: ": komentar SSE diabaikan\n\n" + sseDelta("Halo") + sseDelta("") + sseDelta(", apa kabar?") +
+				"data: {\"choices\":[],\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":4,\"total_tokens\":7}}\n\n" +
+				"data: [DONE]\n\n" + sseDelta("setelah done diabaikan"),
+			wantChunks: []string{"Halo", ", apa kabar?"},
+			wantUsage:  llm.Usage{PromptTokens: 3, CandidateTokens: 4, TotalTokens: 7},
+		},
+		{
+			// Stream yang ditutup tanpa [DONE] tetap mengembalikan teks yang sudah diterima.
+			name:       "closed without done",
+			body:       sseDelta("Halo"),
+			wantChunks: []string{"Halo"},
+		},
+		{
+			name:    "no content",
+			body:    "data: [DONE]\n\n",
+			wantErr: true,
+		},
+		{
+			name:       "malformed chunk",
+			body:       sseDelta("Halo") + "data: {\"choices\":[\n\n",
+			wantChunks: []string{"Halo"},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body := decodeChatRequest(t, r)
+				if !body.Stream || body.StreamOptions == nil || !body.StreamOptions.IncludeUsage {
+					t.Errorf("request = %+v, want stream with include_usage", body)
+				}
+				if accept := r.Header.Get("Accept"); accept != "text/event-stream" {
+					t.Errorf("Accept = %q, want text/event-stream", accept)
+				}
+				w.Header().Set("Content-Type", "text/event-stream")
+				fmt.Fprint(w, tt.body)
+			}))
+			defer srv.Close()
+
+			var chunks []string
+			resp, err := newTestClient(srv.URL, time.Second).Stream(context.Background(), llm.UserPrompt("hai"), func(text string) error {
+				chunks = append(chunks, text)
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if strings.Join(chunks, "|") != strings.Join(tt.wantChunks, "|") {
+				t.Errorf("chunks = %q, want %q", chunks, tt.wantChunks)
+			}
+			if tt.wantErr {
+				return
+			}
+			if resp.Text != strings.Join(tt.wantChunks, "") || resp.Model != DefaultModel || resp.Usage != tt.wantUsage {
+				t.Errorf("response = %+v, want the joined chunks from %s with usage %+v", *resp, DefaultModel, tt.wantUsage)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+// Package ratelimit menyediakan pembatas berbasis fixed window yang penghitungnya disimpan
+// di Store, sehingga batas tetap berlaku setelah server di-restart jika Store-nya persisten.
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Store menyimpan penghitung per key dan per window.
+type Store interface {
+	// Increment menambahkan n ke penghitung key pada window yang dimulai di windowStart,
+	// lalu mengembalikan nilai setelah ditambah. Penghitung boleh dihapus setelah window berakhir.
+	Increment(ctx context.Context, key string, windowStart time.Time, window time.Duration, n int64) (int64, error)
+	// Get mengembalikan nilai penghitung key pada window yang dimulai di windowStart, atau 0 jika belum ada.
+	Get(ctx context.Context, key string, windowStart time.Time) (int64, error)
+}
+
+// Result adalah hasil pemeriksaan satu batas.
+type Result struct {
+	Allowed     bool
+	Count       int64         // Nilai penghitung pada window saat ini.
+	Limit       int64         // Batas yang diperiksa; <= 0 berarti tanpa batas.
+	RetryAfter  time.Duration // Sisa waktu sampai window berikutnya; hanya diisi jika Allowed false.
+	WindowStart time.Time     // Awal window yang diperiksa, agar Refund mengembalikan cost ke window yang sama.
+}
+
+// Limiter menerapkan batas fixed window di atas Store. Window diselaraskan ke kelipatan durasinya
+// sejak waktu nol (UTC), sehingga window 24 jam selalu dimulai tengah malam UTC.
+type Limiter struct {
+	store Store
+	now   func() time.Time
+}
+
+// NewLimiter membuat instance baru dari Limiter.
+func NewLimiter(store Store) *Limiter {
+	return &Limiter{store: store, now: time.Now}
+}
+
+// Allow menambahkan cost ke penghitung key lalu memeriksa apakah totalnya masih dalam limit.
+// Percobaan yang ditolak tetap dihitung. limit <= 0 berarti tanpa batas dan tidak menyentuh Store.
+func (l *Limiter) Allow(ctx context.Context, key string, limit int64, window time.Duration, cost int64) (Result, error) {
+	if limit <= 0 {
+		return Result{Allowed: true, Limit: limit}, nil
+	}
+	start := l.now().Truncate(window)
+	count, err := l.store.Increment(ctx, key, start, window, cost)
+	if err != nil {
+		return Result{}, err
+	}
+	return l.result(count, limit, start, window, count <= limit), nil
+}
+
+// Refund mengurangi kembali cost yang sudah ditambahkan Allow ke penghitung key, pada window dari res.
+// Dipakai saat beberapa batas diperiksa bersama dan salah satunya menolak, agar batas lain tidak ikut terpakai.
+func (l *Limiter) Refund(ctx context.Context, key string, window time.Duration, res Result, cost int64) error {
+	if res.Limit <= 0 {
+		return nil
+	}
+	_, err := l.store.Increment(ctx, key, res.WindowStart, window, -cost)
+	return err
+}
+
+// Peek memeriksa apakah penghitung key masih di bawah limit tanpa menambahkannya.
+// Dipakai untuk batas yang biayanya baru diketahui setelah selesai (misal: jumlah token).
+func (l *Limiter) Peek(ctx context.Context, key string, limit int64, window time.Duration) (Result, error) {
+	if limit <= 0 {
+		return Result{Allowed: true, Limit: limit}, nil
+	}
+	start := l.now().Truncate(window)
+	count, err := l.store.Get(ctx, key, start)
+	if err != nil {
+		return Result{}, err
+	}
+	return l.result(count, limit, start, window, count < limit), nil
+}
+
+// result menyusun Result untuk window yang dimulai di start.
+func (l *Limiter) result(count, limit int64, start time.Time, window time.Duration, allowed bool) Result {
+	res := Result{Allowed: allowed, Count: count, Limit: limit, WindowStart: start}
+	if !allowed {
+		res.RetryAfter = start.Add(window).Sub(l.now())
+	}
+	return res
+}
@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memorySweepInterval adalah jeda minimal antara dua pembersihan penghitung yang kedaluwarsa.
+const memorySweepInterval = time.Minute
+
+// MemoryStore adalah implementasi Store yang menyimpan penghitung di memori.
+// Berguna untuk development dan pengujian; penghitung hilang saat server di-restart.
+type MemoryStore struct {
+	mu        sync.Mutex
+	counters  map[string]memoryCounter // Kunci adalah key; hanya window terbaru dari setiap key yang disimpan.
+	lastSweep time.Time
+}
+
+// memoryCounter adalah penghitung sebuah key pada satu window beserta waktu kedaluwarsanya.
+type memoryCounter struct {
+	windowStart time.Time
+	count       int64
+	expiresAt   time.Time
+}
+
+// NewMemoryStore membuat instance baru dari MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{counters: make(map[string]memoryCounter)}
+}
+
+// Increment menambahkan n ke penghitung. Window baru menggantikan window lama dari key yang sama,
+// sedangkan key yang tidak lagi dipakai dihapus oleh sweep.
+func (s *MemoryStore) Increment(ctx context.Context, key string, windowStart time.Time, window time.Duration, n int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(time.Now())
+
+	counter, ok := s.counters[key]
+	switch {
+	case ok && windowStart.Before(counter.windowStart):
+		// Request yang terlambat untuk window yang sudah berakhir tidak boleh menimpa window terbaru.
+		return n, nil
+	case !ok || windowStart.After(counter.windowStart):
+		counter = memoryCounter{windowStart: windowStart, expiresAt: windowStart.Add(window)}
+	}
+	counter.count += n
+	s.counters[key] = counter
+	return counter.count, nil
+}
+
+// Get mengembalikan nilai penghitung, atau 0 jika belum ada.
+func (s *MemoryStore) Get(ctx context.Context, key string, windowStart time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counter, ok := s.counters[key]
+	if !ok || !counter.windowStart.Equal(windowStart) {
+		return 0, nil
+	}
+	return counter.count, nil
+}
+
+// sweep menghapus penghitung yang sudah kedaluwarsa, paling sering sekali setiap memorySweepInterval,
+// sehingga biayanya tidak ditanggung oleh setiap Increment.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < memorySweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, counter := range s.counters {
+		if now.After(counter.expiresAt) {
+			delete(s.counters, key)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreWindows(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	window := time.Minute
+	first := time.Now().Truncate(window)
+	second := first.Add(window)
+
+	increment := func(start time.Time, n, want int64) {
+		t.Helper()
+		got, err := s.Increment(ctx, "key", start, window, n)
+		if err != nil || got != want {
+			t.Fatalf("Increment(%s, %d) = %d, %v; want %d", start.Format(time.TimeOnly), n, got, err, want)
+		}
+	}
+	get := func(start time.Time, want int64) {
+		t.Helper()
+		if got, _ := s.Get(ctx, "key", start); got != want {
+			t.Fatalf("Get(%s) = %d, want %d", start.Format(time.TimeOnly), got, want)
+		}
+	}
+
+	increment(first, 1, 1)
+	increment(first, 2, 3)
+	get(first, 3)
+	get(second, 0)
+
+	// Window baru dimulai dari nol dan menggantikan window lama.
+	increment(second, 1, 1)
+	get(second, 1)
+	get(first, 0)
+
+	// Request terlambat untuk window lama tidak mengubah window terbaru.
+	increment(first, 5, 5)
+	get(second, 1)
+
+	if got, _ := s.Increment(ctx, "other", first, window, 1); got != 1 {
+		t.Fatalf("other key = %d, want 1", got)
+	}
+}
+
+func TestMemoryStoreSweepsExpiredKeys(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	now := time.Now()
+
+	for _, key := range []string{"a", "b", "c"} {
+		s.Increment(ctx, key, now.Add(-2*time.Hour), time.Hour, 1)
+	}
+	s.Increment(ctx, "live", now.Truncate(time.Hour), time.Hour, 1)
+
+	// Pembersihan hanya berjalan sekali setiap memorySweepInterval.
+	s.Increment(ctx, "live", now.Truncate(time.Hour), time.Hour, 1)
+	if len(s.counters) != 4 {
+		t.Fatalf("counters = %d before the sweep interval, want 4", len(s.counters))
+	}
+
+	s.lastSweep = now.Add(-memorySweepInterval)
+	s.Increment(ctx, "live", now.Truncate(time.Hour), time.Hour, 1)
+	if len(s.counters) != 1 {
+		t.Fatalf("counters = %d after the sweep, want 1", len(s.counters))
+	}
+	if got, _ := s.Get(ctx, "live", now.Truncate(time.Hour)); got != 3 {
+		t.Errorf("live counter = %d, want 3", got)
+	}
+}
@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoStore adalah implementasi Store yang menyimpan penghitung di MongoDB.
+// Setiap dokumen mewakili satu key pada satu window dan dihapus otomatis oleh TTL index setelah window berakhir.
+type MongoStore struct {
+	db         *mongo.Database // Koneksi ke database spesifik di MongoDB.
+	collection string          // Nama koleksi untuk menyimpan penghitung, yaitu "rate_limits".
+}
+
+// NewMongoStore membuat instance baru dari MongoStore.
+func NewMongoStore(db *mongo.Database) *MongoStore {
+	return &MongoStore{
+		db:         db,
+		collection: "rate_limits",
+	}
+}
+
+// EnsureIndexes membuat TTL index pada `expires_at` agar penghitung yang kedaluwarsa dihapus MongoDB.
+// Aman dipanggil berulang kali saat startup.
+func (s *MongoStore) EnsureIndexes(ctx context.Context) error {
+	_, err := s.db.Collection(s.collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+// Increment menambahkan n ke penghitung secara atomik dengan `$inc`, dan membuat dokumen jika belum ada.
+func (s *MongoStore) Increment(ctx context.Context, key string, windowStart time.Time, window time.Duration, n int64) (int64, error) {
+	update := bson.M{
+		"$setOnInsert": bson.M{"key": key, "window_start": windowStart, "expires_at": windowStart.Add(window)},
+		"$inc":         bson.M{"count": n},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var doc struct {
+		Count int64 `bson:"count"`
+	}
+	filter := bson.M{"_id": windowID(key, windowStart)}
+	err := s.db.Collection(s.collection).FindOneAndUpdate(ctx, filter, update, opts).Decode(&doc)
+	if mongo.IsDuplicateKeyError(err) {
+		// Dua upsert bersamaan untuk dokumen baru: salah satunya gagal, dan percobaan ulang menjadi update biasa.
+		err = s.db.Collection(s.collection).FindOneAndUpdate(ctx, filter, update, opts).Decode(&doc)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return doc.Count, nil
+}
+
+// Get mengembalikan nilai penghitung, atau 0 jika dokumennya belum ada.
+func (s *MongoStore) Get(ctx context.Context, key string, windowStart time.Time) (int64, error) {
+	var doc struct {
+		Count int64 `bson:"count"`
+	}
+	err := s.db.Collection(s.collection).FindOne(ctx, bson.M{"_id": windowID(key, windowStart)}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return doc.Count, nil
+}
+
+// windowID membuat ID dokumen yang unik untuk sebuah key pada sebuah window.
+func windowID(key string, windowStart time.Time) string {
+	return fmt.Sprintf("%s@%d", key, windowStart.Unix())
+}